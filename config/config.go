@@ -22,6 +22,109 @@ type Config struct {
 	IPv6Domain     string
 	IPv6SubDomains []string
 	IPv6CheckURL   string
+	RecordTTL      int
+	APIEndpoint    string
+	AuthEndpoint   string
+	Region         string
+
+	// IP sources per address family: check URLs or interface:<name>. With
+	// several sources, records hold every address found.
+	IPv4Sources []string
+	IPv6Sources []string
+
+	// Health-checked failover between IP sources
+	FailoverEnabled   bool
+	FailoverCheck     string
+	FailoverPort      int
+	FailoverPath      string
+	FailoverTimeout   int
+	FailoverThreshold int
+	FailoverHoldDown  int
+
+	// Flap damping and change cap
+	StabilityChecks   int
+	StabilityWindow   int
+	MaxChangesPerHour int
+
+	// Append-only history of detected IPs and record changes
+	HistoryFile string
+
+	// RFC 2136 dynamic updates
+	RFC2136Server    string
+	RFC2136Transport string
+	TSIGAlgorithm    string
+
+	// Azure DNS
+	AzureTenantID       string
+	AzureSubscriptionID string
+	AzureResourceGroup  string
+
+	// Namecheap
+	NamecheapClientIP string
+
+	// OVH
+	OVHConsumerKey string
+
+	// PowerDNS
+	PowerDNSServerID string
+	PowerDNSRectify  bool
+	PowerDNSNotify   bool
+
+	// Local file backends (hosts, zonefile)
+	RecordFile    string
+	ReloadCommand string
+
+	// Generic webhook provider
+	WebhookGetURL       string
+	WebhookGetMethod    string
+	WebhookGetBody      string
+	WebhookCreateURL    string
+	WebhookCreateMethod string
+	WebhookCreateBody   string
+	WebhookUpdateURL    string
+	WebhookUpdateMethod string
+	WebhookUpdateBody   string
+	WebhookDeleteURL    string
+	WebhookDeleteMethod string
+	WebhookDeleteBody   string
+	WebhookHeaders      string
+	WebhookIDPath       string
+	WebhookValuePath    string
+	WebhookCreateIDPath string
+
+	// Generic exec provider
+	ExecCommand string
+	ExecTimeout int
+
+	// In-memory provider fault injection
+	MemoryLatency     int
+	MemoryFailureRate float64
+	MemoryDuplicates  bool
+
+	// Concurrent record updates per provider account
+	Concurrency int
+
+	// Handling of several existing records for one name: update, delete or
+	// refuse
+	DuplicatePolicy string
+
+	// Post-update propagation verification
+	VerifyEnabled  bool
+	VerifyTimeout  int
+	VerifyInterval int
+
+	// Pre- and post-update hooks
+	PreUpdateHook  string
+	PostUpdateHook string
+	HookTimeout    int
+	HookMode       string
+
+	// Retry and rate limiting
+	RetryMaxAttempts int
+	RetryBaseDelay   int
+	RetryMaxDelay    int
+	RateLimit        float64
+	RateLimitBurst   int
 }
 
 // LoadConfig loads and validates configuration from environment variables
@@ -38,6 +141,50 @@ func LoadConfig() (*Config, error) {
 		IPv6CheckURL:   getEnv("IPV6_CHECK_URL", "https://6.iplark.com/ip"),
 		IPv4SubDomains: parseSubDomains(getEnv("IPV4_SUBDOMAINS", "")),
 		IPv6SubDomains: parseSubDomains(getEnv("IPV6_SUBDOMAINS", "")),
+
+		APIEndpoint:  getEnv("API_ENDPOINT", ""),
+		AuthEndpoint: getEnv("AUTH_ENDPOINT", ""),
+		Region:       getEnv("DNS_REGION", ""),
+
+		RFC2136Server:    getEnv("RFC2136_SERVER", ""),
+		RFC2136Transport: strings.ToLower(getEnv("RFC2136_TRANSPORT", "udp")),
+		TSIGAlgorithm:    strings.ToLower(getEnv("TSIG_ALGORITHM", "hmac-sha256")),
+
+		AzureTenantID:       getEnv("AZURE_TENANT_ID", ""),
+		AzureSubscriptionID: getEnv("AZURE_SUBSCRIPTION_ID", ""),
+		AzureResourceGroup:  getEnv("AZURE_RESOURCE_GROUP", ""),
+
+		NamecheapClientIP: getEnv("NAMECHEAP_CLIENT_IP", ""),
+
+		OVHConsumerKey: getEnv("OVH_CONSUMER_KEY", ""),
+
+		PowerDNSServerID: getEnv("POWERDNS_SERVER_ID", "localhost"),
+		PowerDNSRectify:  getEnvAsBool("POWERDNS_RECTIFY", false),
+		PowerDNSNotify:   getEnvAsBool("POWERDNS_NOTIFY", false),
+
+		RecordFile:    getEnv("RECORD_FILE", ""),
+		ReloadCommand: getEnv("RELOAD_COMMAND", ""),
+
+		WebhookGetURL:       getEnv("WEBHOOK_GET_URL", ""),
+		WebhookGetMethod:    getEnv("WEBHOOK_GET_METHOD", "GET"),
+		WebhookGetBody:      getEnv("WEBHOOK_GET_BODY", ""),
+		WebhookCreateURL:    getEnv("WEBHOOK_CREATE_URL", ""),
+		WebhookCreateMethod: getEnv("WEBHOOK_CREATE_METHOD", "POST"),
+		WebhookCreateBody:   getEnv("WEBHOOK_CREATE_BODY", ""),
+		WebhookUpdateURL:    getEnv("WEBHOOK_UPDATE_URL", ""),
+		WebhookUpdateMethod: getEnv("WEBHOOK_UPDATE_METHOD", "PUT"),
+		WebhookUpdateBody:   getEnv("WEBHOOK_UPDATE_BODY", ""),
+		WebhookDeleteURL:    getEnv("WEBHOOK_DELETE_URL", ""),
+		WebhookDeleteMethod: getEnv("WEBHOOK_DELETE_METHOD", "DELETE"),
+		WebhookDeleteBody:   getEnv("WEBHOOK_DELETE_BODY", ""),
+		WebhookHeaders:      getEnv("WEBHOOK_HEADERS", ""),
+		WebhookIDPath:       getEnv("WEBHOOK_ID_PATH", ""),
+		WebhookValuePath:    getEnv("WEBHOOK_VALUE_PATH", ""),
+		WebhookCreateIDPath: getEnv("WEBHOOK_CREATE_ID_PATH", ""),
+
+		ExecCommand: getEnv("EXEC_COMMAND", ""),
+
+		MemoryDuplicates: getEnvAsBool("MEMORY_DUPLICATES", false),
 	}
 
 	// Parse interval with validation
@@ -48,6 +195,107 @@ func LoadConfig() (*Config, error) {
 	}
 	cfg.Interval = interval
 
+	if cfg.RecordTTL, err = getEnvAsInt("RECORD_TTL", 600, 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.Concurrency, err = getEnvAsInt("UPDATE_CONCURRENCY", 4, 1); err != nil {
+		return nil, err
+	}
+
+	cfg.DuplicatePolicy = strings.ToLower(getEnv("DUPLICATE_POLICY", "update"))
+
+	// IP sources default to the check URL
+	if cfg.IPv4Sources = parseSubDomains(getEnv("IPV4_SOURCES", "")); len(cfg.IPv4Sources) == 0 {
+		cfg.IPv4Sources = []string{cfg.IPv4CheckURL}
+	}
+	if cfg.IPv6Sources = parseSubDomains(getEnv("IPV6_SOURCES", "")); len(cfg.IPv6Sources) == 0 {
+		cfg.IPv6Sources = []string{cfg.IPv6CheckURL}
+	}
+
+	// Parse failover settings
+	cfg.FailoverEnabled = getEnvAsBool("FAILOVER_ENABLED", false)
+	cfg.FailoverCheck = strings.ToLower(getEnv("FAILOVER_CHECK", "tcp"))
+	cfg.FailoverPath = getEnv("FAILOVER_PATH", "/")
+	if cfg.FailoverPort, err = getEnvAsInt("FAILOVER_PORT", 443, 1); err != nil {
+		return nil, err
+	}
+	if cfg.FailoverTimeout, err = getEnvAsInt("FAILOVER_TIMEOUT", 5, 1); err != nil {
+		return nil, err
+	}
+	if cfg.FailoverThreshold, err = getEnvAsInt("FAILOVER_THRESHOLD", 3, 1); err != nil {
+		return nil, err
+	}
+	if cfg.FailoverHoldDown, err = getEnvAsInt("FAILOVER_HOLD_DOWN", 300, 0); err != nil {
+		return nil, err
+	}
+
+	// Parse flap damping settings
+	if cfg.StabilityChecks, err = getEnvAsInt("STABILITY_CHECKS", 1, 1); err != nil {
+		return nil, err
+	}
+	if cfg.StabilityWindow, err = getEnvAsInt("STABILITY_WINDOW", 0, 0); err != nil {
+		return nil, err
+	}
+	if cfg.MaxChangesPerHour, err = getEnvAsInt("MAX_CHANGES_PER_HOUR", 0, 0); err != nil {
+		return nil, err
+	}
+
+	cfg.HistoryFile = getEnv("HISTORY_FILE", "")
+
+	// Parse propagation verification settings
+	cfg.VerifyEnabled = getEnvAsBool("VERIFY_ENABLED", false)
+	if cfg.VerifyTimeout, err = getEnvAsInt("VERIFY_TIMEOUT", 120, 1); err != nil {
+		return nil, err
+	}
+	if cfg.VerifyInterval, err = getEnvAsInt("VERIFY_INTERVAL", 5, 1); err != nil {
+		return nil, err
+	}
+
+	// Parse hook settings
+	cfg.PreUpdateHook = getEnv("PRE_UPDATE_HOOK", "")
+	cfg.PostUpdateHook = getEnv("POST_UPDATE_HOOK", "")
+	cfg.HookMode = strings.ToLower(getEnv("HOOK_MODE", "record"))
+	if cfg.HookTimeout, err = getEnvAsInt("HOOK_TIMEOUT", 30, 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.ExecTimeout, err = getEnvAsInt("EXEC_TIMEOUT", 30, 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.MemoryLatency, err = getEnvAsInt("MEMORY_LATENCY", 0, 0); err != nil {
+		return nil, err
+	}
+	if rateStr := getEnv("MEMORY_FAILURE_RATE", ""); rateStr != "" {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid MEMORY_FAILURE_RATE value: must be a number between 0 and 1")
+		}
+		cfg.MemoryFailureRate = rate
+	}
+
+	// Parse retry and rate limit settings
+	if cfg.RetryMaxAttempts, err = getEnvAsInt("RETRY_MAX_ATTEMPTS", 3, 1); err != nil {
+		return nil, err
+	}
+	if cfg.RetryBaseDelay, err = getEnvAsInt("RETRY_BASE_DELAY", 1, 0); err != nil {
+		return nil, err
+	}
+	if cfg.RetryMaxDelay, err = getEnvAsInt("RETRY_MAX_DELAY", 30, 0); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimitBurst, err = getEnvAsInt("RATE_LIMIT_BURST", 1, 1); err != nil {
+		return nil, err
+	}
+	if rateStr := getEnv("RATE_LIMIT", ""); rateStr != "" {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT value: must be a non-negative number of requests per second")
+		}
+		cfg.RateLimit = rate
+	}
+
 	// Validate configuration
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -56,9 +304,73 @@ func LoadConfig() (*Config, error) {
 	return cfg, nil
 }
 
+// singleAddressProviders hold a single address per name and family, so
+// records cannot carry several addresses
+var singleAddressProviders = map[string]bool{
+	"dyndns2": true,
+	"noip":    true,
+	"dynu":    true,
+	"duckdns": true,
+	"oray":    true,
+}
+
+// tokenProviders authenticate with a single API token passed in SECRET_KEY
+var tokenProviders = map[string]bool{
+	"digitalocean": true,
+	"linode":       true,
+	"vultr":        true,
+	"hetzner":      true,
+	"desec":        true,
+	"gandi":        true,
+	"duckdns":      true,
+	"powerdns":     true,
+	"pihole":       true,
+}
+
 // validate checks configuration for required values
 func (c *Config) validate() error {
-	if c.SecretID == "" || c.SecretKey == "" {
+	if c.Provider == "rfc2136" {
+		// TSIG is optional for RFC 2136, but the key name and secret go together
+		if (c.SecretID == "") != (c.SecretKey == "") {
+			return fmt.Errorf("SECRET_ID (TSIG key name) and SECRET_KEY (TSIG secret) must be set together")
+		}
+		if c.RFC2136Transport != "udp" && c.RFC2136Transport != "tcp" {
+			return fmt.Errorf("invalid RFC2136_TRANSPORT value: must be udp or tcp")
+		}
+	} else if c.Provider == "gcloud" {
+		// The project ID defaults to the one in the service account key
+		if c.SecretKey == "" {
+			return fmt.Errorf("SECRET_KEY (service account key file) must be set")
+		}
+	} else if c.Provider == "hosts" || c.Provider == "zonefile" {
+		// Local files need no credentials
+		if c.RecordFile == "" {
+			return fmt.Errorf("RECORD_FILE must be set for %s", c.Provider)
+		}
+	} else if c.Provider == "cloudflare" {
+		// SECRET_KEY (account email) is only needed with the Global API Key
+		if strings.Contains(c.SecretID, "@") || (c.SecretKey != "" && !strings.Contains(c.SecretKey, "@")) {
+			return fmt.Errorf("SECRET_ID and SECRET_KEY look swapped: for cloudflare, SECRET_ID is the API token or Global API Key and SECRET_KEY the account email")
+		}
+		if c.SecretID == "" {
+			return fmt.Errorf("SECRET_ID (API token or Global API Key) must be set")
+		}
+	} else if c.Provider == "memory" {
+		// Records live in memory; nothing to configure
+	} else if c.Provider == "webhook" {
+		// Credentials are optional and only used through templates
+		if c.WebhookGetURL == "" || c.WebhookCreateURL == "" || c.WebhookValuePath == "" {
+			return fmt.Errorf("WEBHOOK_GET_URL, WEBHOOK_CREATE_URL and WEBHOOK_VALUE_PATH must be set for webhook")
+		}
+	} else if c.Provider == "exec" {
+		if c.ExecCommand == "" {
+			return fmt.Errorf("EXEC_COMMAND must be set for exec")
+		}
+	} else if tokenProviders[c.Provider] {
+		if c.SecretKey == "" {
+			return fmt.Errorf("SECRET_KEY (API token) must be set")
+		}
+	} else if c.SecretID == "" || c.SecretKey == "" {
 		return fmt.Errorf("SECRET_ID and SECRET_KEY must be set")
 	}
 
@@ -72,10 +384,65 @@ func (c *Config) validate() error {
 		// Add any Aliyun-specific validation if needed
 	}
 
+	if c.HookMode != "record" && c.HookMode != "change" {
+		return fmt.Errorf("invalid HOOK_MODE value: must be record or change")
+	}
+
+	if c.DuplicatePolicy != "update" && c.DuplicatePolicy != "delete" && c.DuplicatePolicy != "refuse" {
+		return fmt.Errorf("invalid DUPLICATE_POLICY value: must be update, delete or refuse")
+	}
+
+	if c.FailoverEnabled {
+		if c.FailoverCheck != "tcp" && c.FailoverCheck != "http" {
+			return fmt.Errorf("invalid FAILOVER_CHECK value: must be tcp or http")
+		}
+		if c.FailoverPort > 65535 {
+			return fmt.Errorf("invalid FAILOVER_PORT value: must be between 1 and 65535")
+		}
+		if (c.IPv4Enabled && len(c.IPv4Sources) < 2) || (c.IPv6Enabled && len(c.IPv6Sources) < 2) {
+			return fmt.Errorf("FAILOVER_ENABLED needs at least two IPV4_SOURCES or IPV6_SOURCES for each enabled family")
+		}
+	}
+
+	if c.RetryMaxDelay < c.RetryBaseDelay {
+		return fmt.Errorf("RETRY_MAX_DELAY must not be less than RETRY_BASE_DELAY")
+	}
+
+	if c.Provider == "azure" {
+		if c.AzureTenantID == "" || c.AzureSubscriptionID == "" || c.AzureResourceGroup == "" {
+			return fmt.Errorf("AZURE_TENANT_ID, AZURE_SUBSCRIPTION_ID and AZURE_RESOURCE_GROUP must be set")
+		}
+	}
+
+	if c.Provider == "dyndns2" && c.APIEndpoint == "" {
+		return fmt.Errorf("API_ENDPOINT (update server URL) must be set for dyndns2")
+	}
+
+	if c.Provider == "powerdns" && c.APIEndpoint == "" {
+		return fmt.Errorf("API_ENDPOINT (PowerDNS API URL) must be set for powerdns")
+	}
+
+	if (c.Provider == "pihole" || c.Provider == "adguard") && c.APIEndpoint == "" {
+		return fmt.Errorf("API_ENDPOINT (web interface URL) must be set for %s", c.Provider)
+	}
+
+	if c.Provider == "ovh" && c.OVHConsumerKey == "" {
+		return fmt.Errorf("OVH_CONSUMER_KEY must be set")
+	}
+
 	if !c.IPv4Enabled && !c.IPv6Enabled {
 		return fmt.Errorf("at least one of IPv4 or IPv6 must be enabled")
 	}
 
+	for _, source := range append(append([]string(nil), c.IPv4Sources...), c.IPv6Sources...) {
+		if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "interface:") {
+			return fmt.Errorf("invalid IP source %q: must be an http(s) URL or interface:<name>", source)
+		}
+	}
+	if singleAddressProviders[c.Provider] && !c.FailoverEnabled && (len(c.IPv4Sources) > 1 || len(c.IPv6Sources) > 1) {
+		return fmt.Errorf("%s holds one address per name, so IPV4_SOURCES and IPV6_SOURCES must list a single source", c.Provider)
+	}
+
 	if c.IPv4Enabled {
 		if c.IPv4Domain == "" {
 			return fmt.Errorf("IPV4_DOMAIN must be set when IPv4 is enabled")
@@ -115,6 +482,19 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return val == "true" || val == "1" || val == "yes" || val == "on"
 }
 
+// getEnvAsInt converts environment variable to an integer no less than minValue
+func getEnvAsInt(key string, defaultValue, minValue int) (int, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < minValue {
+		return 0, fmt.Errorf("invalid %s value: must be an integer ≥%d", key, minValue)
+	}
+	return n, nil
+}
+
 // parseSubDomains converts comma-separated string to slice
 func parseSubDomains(subDomainsStr string) []string {
 	var subDomains []string
@@ -131,14 +511,47 @@ func PrintConfigSummary(cfg *Config) {
 	utils.LogInfo("\n=== Configuration Summary ===")
 	utils.LogInfo("DNS Provider: %s", cfg.Provider)
 	utils.LogInfo("Update Interval: %d seconds", cfg.Interval)
+	utils.LogInfo("Update Concurrency: %d", cfg.Concurrency)
+	if cfg.VerifyEnabled {
+		utils.LogInfo("Propagation Verification: Timeout=%ds, Interval=%ds", cfg.VerifyTimeout, cfg.VerifyInterval)
+	}
+	if cfg.PreUpdateHook != "" || cfg.PostUpdateHook != "" {
+		utils.LogInfo("Hooks: Mode=%s, Timeout=%ds, Pre=%v, Post=%v",
+			cfg.HookMode, cfg.HookTimeout, cfg.PreUpdateHook != "", cfg.PostUpdateHook != "")
+	}
+	utils.LogInfo("Retry: MaxAttempts=%d, BaseDelay=%ds, MaxDelay=%ds",
+		cfg.RetryMaxAttempts, cfg.RetryBaseDelay, cfg.RetryMaxDelay)
+	if cfg.RateLimit > 0 {
+		utils.LogInfo("Rate Limit: %.2f requests/second, Burst=%d", cfg.RateLimit, cfg.RateLimitBurst)
+	}
 
 	if cfg.IPv4Enabled {
 		utils.LogInfo("IPv4: Enabled=%v, Domain=%s, Subdomains=%v",
 			cfg.IPv4Enabled, cfg.IPv4Domain, cfg.IPv4SubDomains)
+		if len(cfg.IPv4Sources) > 1 {
+			utils.LogInfo("IPv4 Sources: %v", cfg.IPv4Sources)
+		}
 	}
 
 	if cfg.IPv6Enabled {
 		utils.LogInfo("IPv6: Enabled=%v, Domain=%s, Subdomains=%v",
 			cfg.IPv6Enabled, cfg.IPv6Domain, cfg.IPv6SubDomains)
+		if len(cfg.IPv6Sources) > 1 {
+			utils.LogInfo("IPv6 Sources: %v", cfg.IPv6Sources)
+		}
+	}
+
+	if cfg.StabilityChecks > 1 || cfg.StabilityWindow > 0 || cfg.MaxChangesPerHour > 0 {
+		utils.LogInfo("Damping: StabilityChecks=%d, StabilityWindow=%ds, MaxChangesPerHour=%d",
+			cfg.StabilityChecks, cfg.StabilityWindow, cfg.MaxChangesPerHour)
+	}
+
+	if cfg.HistoryFile != "" {
+		utils.LogInfo("History: %s", cfg.HistoryFile)
+	}
+
+	if cfg.FailoverEnabled {
+		utils.LogInfo("Failover: Check=%s, Port=%d, Threshold=%d, HoldDown=%ds",
+			cfg.FailoverCheck, cfg.FailoverPort, cfg.FailoverThreshold, cfg.FailoverHoldDown)
 	}
 }
\ No newline at end of file
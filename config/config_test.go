@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCloudflareCredentials checks that SECRET_ID holds the key and
+// SECRET_KEY the optional email, and that swapped values are refused
+func TestCloudflareCredentials(t *testing.T) {
+	tests := []struct {
+		secretID, secretKey string
+		wantErr             string
+	}{
+		{"api-token", "", ""},
+		{"global-key", "user@example.com", ""},
+		{"", "", "SECRET_ID (API token or Global API Key) must be set"},
+		{"user@example.com", "global-key", "look swapped"},
+		{"", "api-token", "look swapped"},
+		{"global-key", "api-token", "look swapped"},
+	}
+	t.Setenv("DNS_PROVIDER", "cloudflare")
+	t.Setenv("IPV4_DOMAIN", "example.com")
+	t.Setenv("IPV4_SUBDOMAINS", "www")
+	for _, test := range tests {
+		t.Setenv("SECRET_ID", test.secretID)
+		t.Setenv("SECRET_KEY", test.secretKey)
+
+		_, err := LoadConfig()
+		if test.wantErr == "" && err != nil {
+			t.Errorf("SECRET_ID=%q SECRET_KEY=%q: %v", test.secretID, test.secretKey, err)
+		}
+		if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("SECRET_ID=%q SECRET_KEY=%q: got error %v, want %q", test.secretID, test.secretKey, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"ddnsd/internal"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// runHistory prints the entries of the history file that match the filters
+// in args and returns the process exit status. Entries go to stdout and
+// everything else to stderr, so the output can be piped as it is.
+func runHistory(args []string) int {
+	godotenv.Load()
+
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	file := flags.String("file", os.Getenv("HISTORY_FILE"), "history file (default HISTORY_FILE)")
+	since := flags.String("since", "", "show entries from this time: RFC 3339, YYYY-MM-DD[ HH:MM:SS] or a duration ago such as 24h")
+	until := flags.String("until", "", "show entries before this time, in the same formats as -since")
+	record := flags.String("record", "", "show entries for this record, such as www.example.com")
+	recordType := flags.String("type", "", "show entries for this record type: A or AAAA")
+	event := flags.String("event", "", "show entries of this event: detected, created, updated, deleted, failed or suppressed")
+	asJSON := flags.Bool("json", false, "print entries as JSON Lines")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *file == "" {
+		historyMessage("ERROR", "No history file: set HISTORY_FILE or pass -file")
+		return 1
+	}
+
+	filter := internal.HistoryFilter{Record: *record, RecordType: *recordType, Event: strings.ToLower(*event)}
+	var err error
+	if filter.Since, err = parseHistoryTime(*since); err != nil {
+		historyMessage("ERROR", "Invalid -since value: %v", err)
+		return 2
+	}
+	if filter.Until, err = parseHistoryTime(*until); err != nil {
+		historyMessage("ERROR", "Invalid -until value: %v", err)
+		return 2
+	}
+
+	entries, err := internal.ReadHistory(*file, filter, func(format string, v ...interface{}) {
+		historyMessage("WARN", format, v...)
+	})
+	if err != nil {
+		historyMessage("ERROR", "%v", err)
+		return 1
+	}
+
+	for _, entry := range entries {
+		if *asJSON {
+			line, _ := json.Marshal(entry)
+			fmt.Println(string(line))
+		} else {
+			fmt.Println(formatHistoryEntry(entry))
+		}
+	}
+	return 0
+}
+
+// historyMessage prints a warning or error of the history command to stderr
+func historyMessage(level, format string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%-5s %s\n", level, fmt.Sprintf(format, v...))
+}
+
+// parseHistoryTime parses a time filter. An empty value is the zero time.
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time or duration", value)
+}
+
+// formatHistoryEntry renders an entry as one line for people
+func formatHistoryEntry(entry internal.HistoryEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %-10s ", entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Event)
+	if entry.Record != "" {
+		fmt.Fprintf(&b, "%s %s", entry.Record, entry.RecordType)
+	} else {
+		fmt.Fprintf(&b, "%s addresses", entry.RecordType)
+	}
+
+	switch {
+	case entry.OldValue != "" && entry.NewValue != "":
+		fmt.Fprintf(&b, "  %s -> %s", entry.OldValue, entry.NewValue)
+	case entry.NewValue != "":
+		fmt.Fprintf(&b, "  %s", entry.NewValue)
+	case entry.OldValue != "":
+		fmt.Fprintf(&b, "  %s", entry.OldValue)
+	}
+
+	if entry.RecordID != "" && entry.RecordID != entry.OldValue && entry.RecordID != entry.NewValue {
+		fmt.Fprintf(&b, "  (ID=%s)", entry.RecordID)
+	}
+	if entry.Provider != "" {
+		fmt.Fprintf(&b, "  [%s]", entry.Provider)
+	}
+	if entry.Error != "" {
+		fmt.Fprintf(&b, "  error: %s", entry.Error)
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AdGuardHomeProvider implements DNSProvider for AdGuard Home DNS rewrites
+type AdGuardHomeProvider struct {
+	api *restClient
+}
+
+// AdGuard Home API structures
+type adGuardRewrite struct {
+	Domain string `json:"domain"`
+	Answer string `json:"answer"`
+	// Enabled is only reported by newer versions and is sent back as read
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+type adGuardRewriteUpdate struct {
+	Target adGuardRewrite `json:"target"`
+	Update adGuardRewrite `json:"update"`
+}
+
+// newAdGuardHomeProvider creates a new AdGuard Home provider instance.
+// endpoint is the web interface URL, such as http://192.168.1.2:3000.
+func newAdGuardHomeProvider(username, password, endpoint string) (*AdGuardHomeProvider, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("API_ENDPOINT (AdGuard Home URL) must be set for adguard")
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+
+	return &AdGuardHomeProvider{
+		api: newRESTClient(strings.TrimSuffix(endpoint, "/")+"/control", header, nil),
+	}, nil
+}
+
+// GetRecords returns the addresses of the rewrites for the name in the
+// address family of recordType. Rewrites have no IDs, so the address
+// doubles as the record ID.
+func (a *AdGuardHomeProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	rewrites, err := a.findRewrites(recordFullName(domain, subdomain), recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	for _, rewrite := range rewrites {
+		records = append(records, DNSRecord{RecordID: rewrite.Answer, Value: rewrite.Answer})
+	}
+	return records, nil
+}
+
+// CreateRecord adds a rewrite for the name
+func (a *AdGuardHomeProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	rewrite := adGuardRewrite{Domain: recordFullName(domain, subdomain), Answer: value}
+	if err := a.api.do("POST", "/rewrite/add", rewrite, nil); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord changes the answer of the rewrite with answer recordID
+func (a *AdGuardHomeProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	target, err := a.findRewrite(recordFullName(domain, subdomain), recordType, recordID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return permanentError(fmt.Errorf("rewrite %s for %s not found", recordID, recordFullName(domain, subdomain)))
+	}
+
+	update := *target
+	update.Answer = value
+	return a.api.do("PUT", "/rewrite/update", adGuardRewriteUpdate{Target: *target, Update: update}, nil)
+}
+
+// DeleteRecord removes the rewrite with answer recordID
+func (a *AdGuardHomeProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	target, err := a.findRewrite(recordFullName(domain, subdomain), recordType, recordID)
+	if err != nil || target == nil {
+		return err
+	}
+	return a.api.do("POST", "/rewrite/delete", target, nil)
+}
+
+// findRewrite returns the rewrite of name to answer in the family of
+// recordType
+func (a *AdGuardHomeProvider) findRewrite(name, recordType, answer string) (*adGuardRewrite, error) {
+	rewrites, err := a.findRewrites(name, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rewrite := range rewrites {
+		if rewrite.Answer == answer {
+			return &rewrite, nil
+		}
+	}
+	return nil, nil
+}
+
+// findRewrites returns the rewrites of name to an address in the family of
+// recordType. Wildcard and CNAME-style rewrites are ignored.
+func (a *AdGuardHomeProvider) findRewrites(name, recordType string) ([]adGuardRewrite, error) {
+	var rewrites []adGuardRewrite
+	if err := a.api.do("GET", "/rewrite/list", nil, &rewrites); err != nil {
+		return nil, err
+	}
+
+	var matching []adGuardRewrite
+	for _, rewrite := range rewrites {
+		ip := net.ParseIP(rewrite.Answer)
+		if ip == nil || (ip.To4() != nil) != (recordType == "A") {
+			continue
+		}
+		if strings.EqualFold(rewrite.Domain, name) {
+			matching = append(matching, rewrite)
+		}
+	}
+	return matching, nil
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// adGuardStandIn is a minimal AdGuard Home rewrite API. Like AdGuard Home,
+// it matches update and delete targets on every field.
+type adGuardStandIn struct {
+	mu       sync.Mutex
+	rewrites []adGuardRewrite
+}
+
+func (s *adGuardStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, password, _ := r.BasicAuth(); user != "admin" || password != "password" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "Forbidden")
+		return
+	}
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /control/rewrite/list":
+		json.NewEncoder(w).Encode(s.rewrites)
+	case "POST /control/rewrite/add":
+		var rewrite adGuardRewrite
+		json.NewDecoder(r.Body).Decode(&rewrite)
+		enabled := true
+		rewrite.Enabled = &enabled
+		s.rewrites = append(s.rewrites, rewrite)
+	case "PUT /control/rewrite/update":
+		var update adGuardRewriteUpdate
+		json.NewDecoder(r.Body).Decode(&update)
+		i := s.find(update.Target)
+		if i < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "target rewrite is not found")
+			return
+		}
+		s.rewrites[i] = update.Update
+	case "POST /control/rewrite/delete":
+		var target adGuardRewrite
+		json.NewDecoder(r.Body).Decode(&target)
+		i := s.find(target)
+		if i < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "rewrite is not found")
+			return
+		}
+		s.rewrites = append(s.rewrites[:i], s.rewrites[i+1:]...)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// find returns the index of the rewrite equal to target, or -1
+func (s *adGuardStandIn) find(target adGuardRewrite) int {
+	for i, rewrite := range s.rewrites {
+		if adGuardRewriteString(rewrite) == adGuardRewriteString(target) {
+			return i
+		}
+	}
+	return -1
+}
+
+// adGuardRewriteString formats a rewrite with its enabled flag
+func adGuardRewriteString(rewrite adGuardRewrite) string {
+	if rewrite.Enabled == nil {
+		return rewrite.Domain + " " + rewrite.Answer
+	}
+	return fmt.Sprintf("%s %s enabled=%t", rewrite.Domain, rewrite.Answer, *rewrite.Enabled)
+}
+
+func newAdGuardTestProvider(t *testing.T, password string, rewrites ...adGuardRewrite) (*AdGuardHomeProvider, *adGuardStandIn) {
+	standIn := &adGuardStandIn{rewrites: rewrites}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newAdGuardHomeProvider("admin", password, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestAdGuardChanges checks that only exact address rewrites in the right
+// family are managed and that updates send the rewrite back as read
+func TestAdGuardChanges(t *testing.T) {
+	enabled, disabled := true, false
+	p, standIn := newAdGuardTestProvider(t, "password",
+		adGuardRewrite{Domain: "www.example.com", Answer: "192.0.2.1", Enabled: &disabled},
+		adGuardRewrite{Domain: "www.example.com", Answer: "2001:db8::1", Enabled: &enabled},
+		adGuardRewrite{Domain: "*.example.com", Answer: "192.0.2.5", Enabled: &enabled},
+		adGuardRewrite{Domain: "alias.example.com", Answer: "www.example.com", Enabled: &enabled},
+		adGuardRewrite{Domain: "WWW.example.com", Answer: "192.0.2.9"},
+	)
+
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	want := []DNSRecord{{RecordID: "192.0.2.1", Value: "192.0.2.1"}, {RecordID: "192.0.2.9", Value: "192.0.2.9"}}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("GetRecords returned %v, want %v", records, want)
+	}
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("192.0.2.9", "example.com", "www", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	var got []string
+	for _, rewrite := range standIn.rewrites {
+		got = append(got, adGuardRewriteString(rewrite))
+	}
+	wantRewrites := []string{
+		"www.example.com 192.0.2.2 enabled=false",
+		"www.example.com 2001:db8::1 enabled=true",
+		"*.example.com 192.0.2.5 enabled=true",
+		"alias.example.com www.example.com enabled=true",
+		"example.com 192.0.2.3 enabled=true",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(wantRewrites) {
+		t.Errorf("rewrites are\n%q\nwant\n%q", got, wantRewrites)
+	}
+}
+
+// TestAdGuardErrors checks that rejected credentials and a missing rewrite
+// are permanent errors
+func TestAdGuardErrors(t *testing.T) {
+	p, _ := newAdGuardTestProvider(t, "wrong")
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) {
+		t.Errorf("wrong password: got error %v, want a permanent error", err)
+	}
+
+	p, _ = newAdGuardTestProvider(t, "password")
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err == nil || IsRetryable(err) {
+		t.Errorf("missing rewrite: got error %v, want a permanent error", err)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -46,130 +45,68 @@ type aliyunUpdateResponse struct {
 	RequestId string `json:"RequestId"`
 }
 
-// newAliyunProvider creates a new Aliyun provider instance
-func newAliyunProvider(accessKeyID, accessKeySecret string, isChina bool) (*AliyunProvider, error) {
-	endpoint := "alidns.cn-hangzhou.aliyuncs.com" // China edition
-	if !isChina {
-		endpoint = "alidns.ap-northeast-1.aliyuncs.com" // International edition
+type aliyunErrorResponse struct {
+	RequestId string `json:"RequestId"`
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+}
+
+// newAliyunProvider creates a new Aliyun provider instance. endpoint
+// overrides the API URL.
+func newAliyunProvider(accessKeyID, accessKeySecret string, isChina bool, endpoint string) (*AliyunProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://alidns.cn-hangzhou.aliyuncs.com" // China edition
+		if !isChina {
+			endpoint = "https://alidns.ap-northeast-1.aliyuncs.com" // International edition
+		}
 	}
 
 	return &AliyunProvider{
 		accessKeyID:     accessKeyID,
 		accessKeySecret: accessKeySecret,
-		endpoint:        endpoint,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}, nil
 }
 
-// GetRecord retrieves an existing DNS record
-func (a *AliyunProvider) GetRecord(domain, subdomain, recordType string) (*DNSRecord, error) {
+// GetRecords retrieves the existing DNS records
+func (a *AliyunProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
 	params := map[string]string{
-		"Action":           "DescribeSubDomainRecords",
-		"SubDomain":        subdomain + "." + domain,
-		"Type":             recordType,
-		"Format":           "JSON",
-		"Version":          "2015-01-09",
-		"AccessKeyId":      a.accessKeyID,
-		"SignatureMethod":  "HMAC-SHA1",
-		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
-		"SignatureVersion": "1.0",
-		"SignatureNonce":   fmt.Sprintf("%d", time.Now().UnixNano()),
-	}
-
-	signature := a.generateSignature("GET", params)
-	params["Signature"] = signature
-
-	// Build URL
-	baseURL := fmt.Sprintf("http://%s/", a.endpoint)
-	var queryParts []string
-	for k, v := range params {
-		queryParts = append(queryParts, fmt.Sprintf("%s=%s", url.QueryEscape(k), url.QueryEscape(v)))
-	}
-	sort.Strings(queryParts)
-	requestURL := baseURL + "?" + strings.Join(queryParts, "&")
-
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		"Action":    "DescribeSubDomainRecords",
+		"SubDomain": recordFullName(domain, subdomain),
+		"Type":      recordType,
 	}
 
 	var aliResp aliyunDescribeResponse
-	if err := json.Unmarshal(body, &aliResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	if err := a.doRequest(params, &aliResp); err != nil {
+		return nil, err
 	}
 
-	if aliResp.TotalCount == 0 {
-		return nil, nil
+	var records []DNSRecord
+	for _, record := range aliResp.Records.Record {
+		records = append(records, DNSRecord{
+			RecordID: record.RecordId,
+			Value:    record.Value,
+		})
 	}
-
-	record := aliResp.Records.Record[0]
-	return &DNSRecord{
-		RecordID: record.RecordId,
-		Value:    record.Value,
-	}, nil
+	return records, nil
 }
 
 // CreateRecord creates a new DNS record
 func (a *AliyunProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
 	params := map[string]string{
-		"Action":           "AddDomainRecord",
-		"DomainName":       domain,
-		"RR":               subdomain,
-		"Type":             recordType,
-		"Value":            value,
-		"Format":           "JSON",
-		"Version":          "2015-01-09",
-		"AccessKeyId":      a.accessKeyID,
-		"SignatureMethod":  "HMAC-SHA1",
-		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
-		"SignatureVersion": "1.0",
-		"SignatureNonce":   fmt.Sprintf("%d", time.Now().UnixNano()),
-	}
-
-	signature := a.generateSignature("GET", params)
-	params["Signature"] = signature
-
-	// Build URL
-	baseURL := fmt.Sprintf("http://%s/", a.endpoint)
-	var queryParts []string
-	for k, v := range params {
-		queryParts = append(queryParts, fmt.Sprintf("%s=%s", url.QueryEscape(k), url.QueryEscape(v)))
-	}
-	sort.Strings(queryParts)
-	requestURL := baseURL + "?" + strings.Join(queryParts, "&")
-
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("API request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		"Action":     "AddDomainRecord",
+		"DomainName": domain,
+		"RR":         subdomain,
+		"Type":       recordType,
+		"Value":      value,
 	}
 
 	var aliResp aliyunCreateResponse
-	if err := json.Unmarshal(body, &aliResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+	if err := a.doRequest(params, &aliResp); err != nil {
+		return "", err
 	}
 
 	return aliResp.RecordId, nil
@@ -178,74 +115,94 @@ func (a *AliyunProvider) CreateRecord(domain, subdomain, recordType, value strin
 // UpdateRecord updates an existing DNS record
 func (a *AliyunProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
 	params := map[string]string{
-		"Action":           "UpdateDomainRecord",
-		"RecordId":         recordID,
-		"RR":               subdomain,
-		"Type":             recordType,
-		"Value":            value,
-		"Format":           "JSON",
-		"Version":          "2015-01-09",
-		"AccessKeyId":      a.accessKeyID,
-		"SignatureMethod":  "HMAC-SHA1",
-		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
-		"SignatureVersion": "1.0",
-		"SignatureNonce":   fmt.Sprintf("%d", time.Now().UnixNano()),
+		"Action":   "UpdateDomainRecord",
+		"RecordId": recordID,
+		"RR":       subdomain,
+		"Type":     recordType,
+		"Value":    value,
 	}
 
+	var aliResp aliyunUpdateResponse
+	return a.doRequest(params, &aliResp)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (a *AliyunProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	params := map[string]string{
+		"Action":   "DeleteDomainRecord",
+		"RecordId": recordID,
+	}
+
+	var aliResp aliyunUpdateResponse
+	return a.doRequest(params, &aliResp)
+}
+
+// doRequest signs and sends an API request with the given action parameters
+// and decodes the response into out. Error codes in the response body are
+// classified as retryable or permanent.
+func (a *AliyunProvider) doRequest(params map[string]string, out interface{}) error {
+	params["Format"] = "JSON"
+	params["Version"] = "2015-01-09"
+	params["AccessKeyId"] = a.accessKeyID
+	params["SignatureMethod"] = "HMAC-SHA1"
+	params["Timestamp"] = time.Now().UTC().Format("2006-01-02T15:04:05Z")
+	params["SignatureVersion"] = "1.0"
+	params["SignatureNonce"] = fmt.Sprintf("%d", time.Now().UnixNano())
+
 	signature := a.generateSignature("GET", params)
 	params["Signature"] = signature
 
 	// Build URL
-	baseURL := fmt.Sprintf("http://%s/", a.endpoint)
-	var queryParts []string
-	for k, v := range params {
-		queryParts = append(queryParts, fmt.Sprintf("%s=%s", url.QueryEscape(k), url.QueryEscape(v)))
-	}
-	sort.Strings(queryParts)
-	requestURL := baseURL + "?" + strings.Join(queryParts, "&")
+	requestURL := a.endpoint + "/?" + aliyunCanonicalQuery(params)
 
 	req, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
 	}
 
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("API request failed: %v", err)
+		return requestError(err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
 	}
 
-	var aliResp aliyunUpdateResponse
-	if err := json.Unmarshal(body, &aliResp); err != nil {
-		return fmt.Errorf("failed to parse response: %v", err)
+	var aliErr aliyunErrorResponse
+	if err := json.Unmarshal(body, &aliErr); err == nil && aliErr.Code != "" {
+		apiErr := fmt.Errorf("API request failed: %s - %s", aliErr.Code, aliErr.Message)
+		if isAliyunRetryableCode(aliErr.Code) || resp.StatusCode >= 500 {
+			return retryableError(apiErr, 0)
+		}
+		return permanentError(apiErr)
 	}
 
+	if err := statusError(resp, body); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
 	return nil
 }
 
+// isAliyunRetryableCode reports whether an API error code indicates a
+// transient failure such as throttling
+func isAliyunRetryableCode(code string) bool {
+	return strings.HasPrefix(code, "Throttling") ||
+		code == "ServiceUnavailable" ||
+		code == "InternalError" ||
+		code == "UnknownError"
+}
+
 // generateSignature generates the signature for Aliyun API requests
 func (a *AliyunProvider) generateSignature(method string, params map[string]string) string {
-	// Sort parameters
-	var keys []string
-	for k := range params {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	// Build canonicalized query string
-	var queryParts []string
-	for _, k := range keys {
-		queryParts = append(queryParts, fmt.Sprintf("%s=%s", url.QueryEscape(k), url.QueryEscape(params[k])))
-	}
-	canonicalizedQueryString := strings.Join(queryParts, "&")
-
 	// Build string to sign
-	stringToSign := fmt.Sprintf("%s&%s&%s", method, url.QueryEscape("/"), url.QueryEscape(canonicalizedQueryString))
+	stringToSign := fmt.Sprintf("%s&%s&%s", method, uriEncode("/"), uriEncode(aliyunCanonicalQuery(params)))
 
 	// Sign the string
 	key := a.accessKeySecret + "&"
@@ -254,4 +211,20 @@ func (a *AliyunProvider) generateSignature(method string, params map[string]stri
 	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
 
 	return signature
+}
+
+// aliyunCanonicalQuery returns the parameters sorted by name and
+// percent-encoded as RFC 3986 requires, which the signature is computed over
+func aliyunCanonicalQuery(params map[string]string) string {
+	var keys []string
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var queryParts []string
+	for _, k := range keys {
+		queryParts = append(queryParts, uriEncode(k)+"="+uriEncode(params[k]))
+	}
+	return strings.Join(queryParts, "&")
 }
\ No newline at end of file
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestAliyunSignatureKnownAnswer checks the RPC signature against a value
+// computed independently from the API documentation
+func TestAliyunSignatureKnownAnswer(t *testing.T) {
+	p, _ := newAliyunProvider("testid", "testsecret", true, "")
+	params := map[string]string{
+		"Action":           "DescribeSubDomainRecords",
+		"SubDomain":        "www.example.com",
+		"Type":             "A",
+		"Format":           "JSON",
+		"Version":          "2015-01-09",
+		"AccessKeyId":      "testid",
+		"SignatureMethod":  "HMAC-SHA1",
+		"Timestamp":        "2023-11-14T22:13:20Z",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   "1700000000000000000",
+	}
+	if got, want := p.generateSignature("GET", params), "UjUfrLHBPh2tAcswnkIjXw8Mph4="; got != want {
+		t.Errorf("signature is %s, want %s", got, want)
+	}
+}
+
+// aliyunRecord is a record as the Alibaba Cloud DNS API lists it
+type aliyunRecord struct {
+	RecordId   string
+	DomainName string
+	RR         string
+	Type       string
+	Value      string
+}
+
+// aliyunStandIn is a minimal Alibaba Cloud DNS API serving the example.com
+// domain. Requests must be signed with the access key "key-id" and secret
+// "key-secret".
+type aliyunStandIn struct {
+	mu      sync.Mutex
+	records []aliyunRecord
+	nextID  int
+}
+
+func (s *aliyunStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	if query.Get("AccessKeyId") != "key-id" {
+		aliyunFail(w, http.StatusNotFound, "InvalidAccessKeyId.NotFound", "Specified access key is not found.")
+		return
+	}
+	if query.Get("Signature") != aliyunStandInSignature(r.Method, query, "key-secret") {
+		aliyunFail(w, http.StatusBadRequest, "SignatureDoesNotMatch", "Specified signature is not matched with our calculation.")
+		return
+	}
+
+	switch query.Get("Action") {
+	case "DescribeSubDomainRecords":
+		records := []aliyunRecord{}
+		for _, record := range s.records {
+			if aliyunStandInName(record.RR, record.DomainName) == query.Get("SubDomain") && record.Type == query.Get("Type") {
+				records = append(records, record)
+			}
+		}
+		aliyunReply(w, map[string]interface{}{"TotalCount": len(records), "DomainRecords": map[string]interface{}{"Record": records}})
+
+	case "AddDomainRecord":
+		if query.Get("DomainName") != "example.com" {
+			aliyunFail(w, http.StatusBadRequest, "InvalidDomainName.NoExist", "The specified domain name does not exist.")
+			return
+		}
+		record := aliyunRecord{"", query.Get("DomainName"), query.Get("RR"), query.Get("Type"), query.Get("Value")}
+		for _, existing := range s.records {
+			if existing.RR == record.RR && existing.Type == record.Type && existing.Value == record.Value {
+				aliyunFail(w, http.StatusBadRequest, "DomainRecordDuplicate", "The DNS record already exists.")
+				return
+			}
+		}
+		s.nextID++
+		record.RecordId = fmt.Sprint(s.nextID)
+		s.records = append(s.records, record)
+		aliyunReply(w, map[string]interface{}{"RecordId": record.RecordId})
+
+	case "UpdateDomainRecord", "DeleteDomainRecord":
+		for i, record := range s.records {
+			if record.RecordId != query.Get("RecordId") {
+				continue
+			}
+			if query.Get("Action") == "DeleteDomainRecord" {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+			} else if record.Type == query.Get("Type") && record.Value == query.Get("Value") {
+				aliyunFail(w, http.StatusBadRequest, "DomainRecordDuplicate", "The DNS record already exists.")
+				return
+			} else {
+				s.records[i].RR, s.records[i].Type, s.records[i].Value = query.Get("RR"), query.Get("Type"), query.Get("Value")
+			}
+			aliyunReply(w, map[string]interface{}{"RecordId": record.RecordId})
+			return
+		}
+		aliyunFail(w, http.StatusBadRequest, "DomainRecordNotBelongToUser", "The DNS record does not belong to you.")
+
+	default:
+		aliyunFail(w, http.StatusBadRequest, "InvalidAction.NotFound", "Specified api is not found, please check your url and method.")
+	}
+}
+
+// aliyunStandInName returns the fully qualified name of host record rr
+func aliyunStandInName(rr, domain string) string {
+	if rr == "@" {
+		return domain
+	}
+	return rr + "." + domain
+}
+
+// aliyunStandInSignature computes the signature of a request as documented
+// for the RPC-style APIs, independently of the provider's implementation
+func aliyunStandInSignature(method string, query url.Values, secret string) string {
+	var pairs []string
+	for key := range query {
+		if key != "Signature" {
+			pairs = append(pairs, aliyunPercentEncode(key)+"="+aliyunPercentEncode(query.Get(key)))
+		}
+	}
+	sort.Strings(pairs)
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(strings.Join(pairs, "&"))
+
+	h := hmac.New(sha1.New, []byte(secret+"&"))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// aliyunPercentEncode encodes s the way the API documentation describes:
+// URL-encode, then fix up +, * and ~
+func aliyunPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+func aliyunReply(w http.ResponseWriter, response map[string]interface{}) {
+	response["RequestId"] = "request-1"
+	json.NewEncoder(w).Encode(response)
+}
+
+func aliyunFail(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(aliyunErrorResponse{RequestId: "request-1", Code: code, Message: message})
+}
+
+// aliyunThrottled answers like the API does when flow control denies a
+// request
+func aliyunThrottled(w http.ResponseWriter, r *http.Request) {
+	aliyunFail(w, http.StatusBadRequest, "Throttling.User", "Request was denied due to user flow control.")
+}
+
+// TestAliyunErrors checks that API error codes are reported and classified
+func TestAliyunErrors(t *testing.T) {
+	standIn := &aliyunStandIn{nextID: 1, records: []aliyunRecord{{"1", "example.com", "www", "A", "192.0.2.1"}}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, _ := newAliyunProvider("key-id", "key-secret", true, server.URL)
+	_, err := p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "DomainRecordDuplicate - The DNS record already exists.") {
+		t.Errorf("got error %v, want a permanent duplicate record error", err)
+	}
+
+	p, _ = newAliyunProvider("key-id", "wrong-secret", true, server.URL)
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("got error %v, want a permanent signature error", err)
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(aliyunThrottled))
+	t.Cleanup(server.Close)
+	p, _ = newAliyunProvider("key-id", "key-secret", true, server.URL)
+	if _, err := p.GetRecords("example.com", "www", "A"); !IsRetryable(err) {
+		t.Errorf("got error %v, want a retryable throttling error", err)
+	}
+}
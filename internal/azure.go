@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AzureProvider implements DNSProvider for Azure DNS
+type AzureProvider struct {
+	subscriptionID string
+	resourceGroup  string
+	endpoint       string
+	ttl            int
+	client         *http.Client
+	token          *cachedToken
+}
+
+// Azure API structures
+const azureAPIVersion = "2018-05-01"
+
+type azureRecordSet struct {
+	ETag       string              `json:"etag,omitempty"`
+	Properties azureRecordSetProps `json:"properties"`
+}
+
+type azureRecordSetProps struct {
+	TTL            int               `json:"TTL"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	ARecords       []azureARecord    `json:"ARecords,omitempty"`
+	AAAARecords    []azureAAAARecord `json:"AAAARecords,omitempty"`
+	TargetResource json.RawMessage   `json:"targetResource,omitempty"`
+}
+
+type azureARecord struct {
+	IPv4Address string `json:"ipv4Address"`
+}
+
+type azureAAAARecord struct {
+	IPv6Address string `json:"ipv6Address"`
+}
+
+type azureErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// values returns the addresses in the record set for recordType
+func (p *azureRecordSetProps) values(recordType string) []string {
+	var values []string
+	if recordType == "AAAA" {
+		for _, r := range p.AAAARecords {
+			values = append(values, r.IPv6Address)
+		}
+	} else {
+		for _, r := range p.ARecords {
+			values = append(values, r.IPv4Address)
+		}
+	}
+	return values
+}
+
+// setValues replaces the addresses in the record set for recordType
+func (p *azureRecordSetProps) setValues(recordType string, values []string) {
+	if recordType == "AAAA" {
+		p.AAAARecords = nil
+		for _, v := range values {
+			p.AAAARecords = append(p.AAAARecords, azureAAAARecord{IPv6Address: v})
+		}
+		return
+	}
+	p.ARecords = nil
+	for _, v := range values {
+		p.ARecords = append(p.ARecords, azureARecord{IPv4Address: v})
+	}
+}
+
+// newAzureProvider creates a new Azure DNS provider instance that
+// authenticates with the client-credentials grant. tokenEndpoint and
+// endpoint override the Entra ID token URL and the management API URL.
+func newAzureProvider(clientID, clientSecret, tenantID, subscriptionID, resourceGroup, tokenEndpoint, endpoint string, ttl int) (*AzureProvider, error) {
+	if tokenEndpoint == "" {
+		tokenEndpoint = fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(tenantID))
+	}
+	if endpoint == "" {
+		endpoint = "https://management.azure.com"
+	}
+
+	a := &AzureProvider{
+		subscriptionID: subscriptionID,
+		resourceGroup:  resourceGroup,
+		endpoint:       strings.TrimSuffix(endpoint, "/"),
+		ttl:            ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+	a.token = &cachedToken{
+		fetch: func() (string, time.Duration, error) {
+			return fetchOAuthToken(a.client, tokenEndpoint, url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {clientID},
+				"client_secret": {clientSecret},
+				"scope":         {"https://management.azure.com/.default"},
+			})
+		},
+	}
+	return a, nil
+}
+
+// GetRecords retrieves the existing DNS records. Azure DNS stores records as
+// sets, so each value doubles as its record ID.
+func (a *AzureProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	set, err := a.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+	return setRecords(set.Properties.values(recordType)), nil
+}
+
+// CreateRecord adds a value to the record set
+func (a *AzureProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := a.put(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the record set with value
+func (a *AzureProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return a.put(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the record set
+func (a *AzureProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return a.put(domain, subdomain, recordType, recordID, "")
+}
+
+// put reads the record set, replaces oldValue with newValue and writes the
+// whole set back, keeping its TTL and metadata. A set left empty is deleted.
+// The ETag guards against concurrent modification.
+func (a *AzureProvider) put(domain, subdomain, recordType, oldValue, newValue string) error {
+	if recordType != "A" && recordType != "AAAA" {
+		return permanentError(fmt.Errorf("unsupported record type: %s", recordType))
+	}
+
+	current, err := a.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{}
+	set := azureRecordSet{Properties: azureRecordSetProps{TTL: a.ttl}}
+	if current != nil {
+		set.Properties = current.Properties
+		if current.ETag != "" {
+			headers["If-Match"] = current.ETag
+		}
+	} else {
+		headers["If-None-Match"] = "*"
+	}
+	values := replaceRecordValue(set.Properties.values(recordType), oldValue, newValue)
+	if len(values) == 0 {
+		if current == nil {
+			return nil
+		}
+		return a.doRequest("DELETE", a.recordSetPath(domain, subdomain, recordType), headers, nil, nil)
+	}
+	set.Properties.setValues(recordType, values)
+
+	return a.doRequest("PUT", a.recordSetPath(domain, subdomain, recordType), headers, set, nil)
+}
+
+// getRecordSet returns the record set for the name and type, or nil
+func (a *AzureProvider) getRecordSet(domain, subdomain, recordType string) (*azureRecordSet, error) {
+	var set azureRecordSet
+	err := a.doRequest("GET", a.recordSetPath(domain, subdomain, recordType), nil, nil, &set)
+	if err == errAzureNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// recordSetPath returns the resource path of a record set
+func (a *AzureProvider) recordSetPath(domain, subdomain, recordType string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/dnsZones/%s/%s/%s",
+		url.PathEscape(a.subscriptionID), url.PathEscape(a.resourceGroup),
+		url.PathEscape(domain), recordType, url.PathEscape(subdomain))
+}
+
+// errAzureNotFound is returned by doRequest for 404 responses
+var errAzureNotFound = permanentError(fmt.Errorf("record set not found"))
+
+// doRequest sends an authenticated API request and decodes the response
+// into out when it is not nil
+func (a *AzureProvider) doRequest(method, path string, headers map[string]string, payload interface{}, out interface{}) error {
+	token, err := a.token.Get()
+	if err != nil {
+		return err
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+		}
+		reqBody = bytes.NewBuffer(body)
+	}
+
+	requestURL := a.endpoint + path + "?api-version=" + azureAPIVersion
+	req, err := http.NewRequest(method, requestURL, reqBody)
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	if resp.StatusCode == http.StatusNotFound && method == "GET" {
+		return errAzureNotFound
+	}
+
+	if statusErr := statusError(resp, respBody); statusErr != nil {
+		var errResp azureErrorResponse
+		if json.Unmarshal(respBody, &errResp) != nil || errResp.Error.Code == "" {
+			return statusErr
+		}
+		apiErr := fmt.Errorf("API request failed: %s - %s", errResp.Error.Code, errResp.Error.Message)
+		// 412 means the record set changed since it was read; a retry re-reads it
+		if IsRetryable(statusErr) || resp.StatusCode == http.StatusPreconditionFailed {
+			return retryableError(apiErr, retryAfter(statusErr))
+		}
+		return permanentError(apiErr)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return nil
+}
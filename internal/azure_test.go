@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// azureStandIn is a minimal Entra ID token endpoint and Azure DNS API for
+// the example.com zone
+type azureStandIn struct {
+	mu         sync.Mutex
+	tokens     int
+	expiresIn  int
+	sets       map[string]azureRecordSet // "type/name" -> set
+	etag       int
+	raceWrites bool // change a set between every read and write
+}
+
+const azureZonePath = "/subscriptions/sub-id/resourceGroups/dns-rg/providers/Microsoft.Network/dnsZones/example.com/"
+
+func (s *azureStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/tenant-id/oauth2/v2.0/token" {
+		s.token(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", s.tokens) {
+		s.fail(w, http.StatusUnauthorized, "ExpiredAuthenticationToken", "The access token is invalid or expired.")
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, azureZonePath)
+	if !ok || r.URL.Query().Get("api-version") != azureAPIVersion {
+		s.fail(w, http.StatusNotFound, "ResourceNotFound", "not found")
+		return
+	}
+
+	set, exists := s.sets[key]
+	switch {
+	case r.Header.Get("If-None-Match") == "*" && exists,
+		r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != set.ETag:
+		s.fail(w, http.StatusPreconditionFailed, "PreconditionFailed", "The condition specified using HTTP conditional header(s) is not met.")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if !exists {
+			s.fail(w, http.StatusNotFound, "NotFound", "The resource record set was not found.")
+			return
+		}
+		json.NewEncoder(w).Encode(set)
+		if s.raceWrites {
+			s.etag++
+			set.ETag = strconv.Itoa(s.etag)
+			s.sets[key] = set
+		}
+	case "PUT":
+		var update azureRecordSet
+		json.NewDecoder(r.Body).Decode(&update)
+		s.etag++
+		update.ETag = strconv.Itoa(s.etag)
+		s.sets[key] = update
+		json.NewEncoder(w).Encode(update)
+	case "DELETE":
+		delete(s.sets, key)
+	}
+}
+
+// token checks the client-credentials grant and issues a new access token
+func (s *azureStandIn) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	switch {
+	case r.Form.Get("grant_type") != "client_credentials", r.Form.Get("scope") != "https://management.azure.com/.default":
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request"})
+		return
+	case r.Form.Get("client_id") != "client-id" || r.Form.Get("client_secret") != "client-secret":
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":             "invalid_client",
+			"error_description": "AADSTS7000215: Invalid client secret provided.",
+		})
+		return
+	}
+
+	s.tokens++
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token_type":   "Bearer",
+		"expires_in":   s.expiresIn,
+		"access_token": fmt.Sprintf("token-%d", s.tokens),
+	})
+}
+
+func (s *azureStandIn) fail(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": code, "message": message}})
+}
+
+func newAzureTestProvider(t *testing.T, secret string, sets map[string]azureRecordSet) (*AzureProvider, *azureStandIn) {
+	standIn := &azureStandIn{expiresIn: 3599, sets: sets}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newAzureProvider("client-id", secret, "tenant-id", "sub-id", "dns-rg",
+		server.URL+"/tenant-id/oauth2/v2.0/token", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestAzureChanges checks that record sets keep their TTL, metadata and
+// other values, and that the access token is reused
+func TestAzureChanges(t *testing.T) {
+	p, standIn := newAzureTestProvider(t, "client-secret", map[string]azureRecordSet{
+		"A/www": {ETag: "a", Properties: azureRecordSetProps{
+			TTL:      600,
+			Metadata: map[string]string{"owner": "ops"},
+			ARecords: []azureARecord{{IPv4Address: "192.0.2.1"}, {IPv4Address: "192.0.2.9"}},
+		}},
+		"AAAA/www": {ETag: "b", Properties: azureRecordSetProps{TTL: 600, AAAARecords: []azureAAAARecord{{IPv6Address: "2001:db8::1"}}}},
+		"A/mail":   {ETag: "c", Properties: azureRecordSetProps{TTL: 3600, ARecords: []azureARecord{{IPv4Address: "192.0.2.50"}}}},
+	})
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := map[string]azureRecordSetProps{
+		"A/www": {
+			TTL:      600,
+			Metadata: map[string]string{"owner": "ops"},
+			ARecords: []azureARecord{{IPv4Address: "192.0.2.9"}, {IPv4Address: "192.0.2.2"}},
+		},
+		"A/@":    {TTL: 300, ARecords: []azureARecord{{IPv4Address: "192.0.2.3"}}},
+		"A/mail": {TTL: 3600, ARecords: []azureARecord{{IPv4Address: "192.0.2.50"}}},
+	}
+	got := make(map[string]azureRecordSetProps)
+	for key, set := range standIn.sets {
+		got[key] = set.Properties
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("record sets are\n%v\nwant\n%v", got, want)
+	}
+	if standIn.tokens != 1 {
+		t.Errorf("fetched %d access tokens, want 1", standIn.tokens)
+	}
+}
+
+// TestAzureTokenRefresh checks that a token about to expire is replaced
+func TestAzureTokenRefresh(t *testing.T) {
+	p, standIn := newAzureTestProvider(t, "client-secret", make(map[string]azureRecordSet))
+	standIn.expiresIn = 30
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if standIn.tokens != 2 {
+		t.Errorf("fetched %d access tokens for a token expiring within the margin, want 2", standIn.tokens)
+	}
+}
+
+// TestAzureErrors checks how authentication and concurrency errors are
+// classified
+func TestAzureErrors(t *testing.T) {
+	p, _ := newAzureTestProvider(t, "wrong-secret", make(map[string]azureRecordSet))
+	_, err := p.GetRecords("example.com", "www", "A")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("wrong client secret: got error %v, want a permanent invalid_client error", err)
+	}
+
+	p, standIn := newAzureTestProvider(t, "client-secret", map[string]azureRecordSet{
+		"A/www": {ETag: "a", Properties: azureRecordSetProps{TTL: 600, ARecords: []azureARecord{{IPv4Address: "192.0.2.1"}}}},
+	})
+	standIn.raceWrites = true
+	err = p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2")
+	if !IsRetryable(err) || !strings.Contains(err.Error(), "PreconditionFailed") {
+		t.Errorf("record set changed since read: got error %v, want a retryable PreconditionFailed error", err)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,8 +16,12 @@ import (
 type CloudflareProvider struct {
 	apiKey   string
 	apiEmail string
-	zoneID   string
+	baseURL  string
 	client   *http.Client
+
+	// zoneIDs caches zone IDs by domain
+	mu      sync.Mutex
+	zoneIDs map[string]string
 }
 
 // Cloudflare API structures
@@ -37,6 +44,13 @@ type cloudflareSingleResponse struct {
 	Result  cloudflareRecord  `json:"result"`
 }
 
+type cloudflareZonesResponse struct {
+	Result []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"result"`
+}
+
 type cloudflareError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -56,183 +70,190 @@ type cloudflareUpdateRequest struct {
 	Proxied bool   `json:"proxied"`
 }
 
-// newCloudflareProvider creates a new Cloudflare provider instance
-func newCloudflareProvider(apiKey, apiEmail string) (*CloudflareProvider, error) {
+// newCloudflareProvider creates a new Cloudflare provider instance. With an
+// account email, apiKey is the Global API Key; without one it is an API
+// token. endpoint overrides the API URL.
+func newCloudflareProvider(apiKey, apiEmail, endpoint string) (*CloudflareProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.cloudflare.com/client/v4"
+	}
+
 	return &CloudflareProvider{
 		apiKey:   apiKey,
 		apiEmail: apiEmail,
+		baseURL:  strings.TrimSuffix(endpoint, "/"),
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		zoneIDs: make(map[string]string),
 	}, nil
 }
 
-// GetRecord retrieves an existing DNS record
-func (c *CloudflareProvider) GetRecord(domain, subdomain, recordType string) (*DNSRecord, error) {
-	var fullName string
-	if subdomain == "@" {
-		fullName = domain
-	} else {
-		fullName = subdomain + "." + domain
-	}
-
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records?name=%s&type=%s", c.zoneID, fullName, recordType)
-
-	req, err := http.NewRequest("GET", url, nil)
+// GetRecords retrieves the existing DNS records
+func (c *CloudflareProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	zoneID, err := c.getZoneID(domain)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
 	}
 
-	req.Header.Set("X-Auth-Key", c.apiKey)
-	req.Header.Set("X-Auth-Email", c.apiEmail)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
+	query := url.Values{}
+	query.Set("name", recordFullName(domain, subdomain))
+	query.Set("type", recordType)
 
 	var cfResp cloudflareResponse
-	if err := json.Unmarshal(body, &cfResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	if err := c.doRequest("GET", fmt.Sprintf("%s/zones/%s/dns_records?%s", c.baseURL, zoneID, query.Encode()), nil, &cfResp); err != nil {
+		return nil, err
 	}
 
-	if !cfResp.Success {
-		if len(cfResp.Errors) > 0 {
-			return nil, fmt.Errorf("API request failed: %s", cfResp.Errors[0].Message)
-		}
-		return nil, fmt.Errorf("API request failed")
-	}
-
-	if len(cfResp.Result) == 0 {
-		return nil, nil
+	var records []DNSRecord
+	for _, record := range cfResp.Result {
+		records = append(records, DNSRecord{
+			RecordID: record.ID,
+			Value:    record.Content,
+		})
 	}
-
-	record := cfResp.Result[0]
-	return &DNSRecord{
-		RecordID: record.ID,
-		Value:    record.Content,
-	}, nil
+	return records, nil
 }
 
 // CreateRecord creates a new DNS record
 func (c *CloudflareProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
-	var fullName string
-	if subdomain == "@" {
-		fullName = domain
-	} else {
-		fullName = subdomain + "." + domain
+	zoneID, err := c.getZoneID(domain)
+	if err != nil {
+		return "", err
 	}
 
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", c.zoneID)
-
 	createReq := cloudflareCreateRequest{
 		Type:    recordType,
-		Name:    fullName,
+		Name:    recordFullName(domain, subdomain),
 		Content: value,
 		Proxied: false,
 	}
 
-	body, err := json.Marshal(createReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+	var cfResp cloudflareSingleResponse
+	if err := c.doRequest("POST", fmt.Sprintf("%s/zones/%s/dns_records", c.baseURL, zoneID), createReq, &cfResp); err != nil {
+		return "", err
 	}
 
-	req.Header.Set("X-Auth-Key", c.apiKey)
-	req.Header.Set("X-Auth-Email", c.apiEmail)
-	req.Header.Set("Content-Type", "application/json")
+	return cfResp.Result.ID, nil
+}
 
-	resp, err := c.client.Do(req)
+// UpdateRecord updates an existing DNS record
+func (c *CloudflareProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	zoneID, err := c.getZoneID(domain)
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %v", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+	updateReq := cloudflareUpdateRequest{
+		Type:    recordType,
+		Name:    recordFullName(domain, subdomain),
+		Content: value,
+		Proxied: false,
 	}
 
 	var cfResp cloudflareSingleResponse
-	if err := json.Unmarshal(respBody, &cfResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
-	}
+	return c.doRequest("PUT", fmt.Sprintf("%s/zones/%s/dns_records/%s", c.baseURL, zoneID, url.PathEscape(recordID)), updateReq, &cfResp)
+}
 
-	if !cfResp.Success {
-		if len(cfResp.Errors) > 0 {
-			return "", fmt.Errorf("API request failed: %s", cfResp.Errors[0].Message)
-		}
-		return "", fmt.Errorf("API request failed")
+// DeleteRecord deletes an existing DNS record
+func (c *CloudflareProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	zoneID, err := c.getZoneID(domain)
+	if err != nil {
+		return err
 	}
 
-	return cfResp.Result.ID, nil
+	var cfResp cloudflareSingleResponse
+	return c.doRequest("DELETE", fmt.Sprintf("%s/zones/%s/dns_records/%s", c.baseURL, zoneID, url.PathEscape(recordID)), nil, &cfResp)
 }
 
-// UpdateRecord updates an existing DNS record
-func (c *CloudflareProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
-	var fullName string
-	if subdomain == "@" {
-		fullName = domain
-	} else {
-		fullName = subdomain + "." + domain
+// getZoneID returns the ID of the zone containing domain. domain may be a
+// name below the zone apex, so parent names are tried in turn.
+func (c *CloudflareProvider) getZoneID(domain string) (string, error) {
+	c.mu.Lock()
+	zoneID, ok := c.zoneIDs[domain]
+	c.mu.Unlock()
+	if ok {
+		return zoneID, nil
 	}
 
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", c.zoneID, recordID)
+	for name := domain; strings.Contains(name, "."); name = name[strings.Index(name, ".")+1:] {
+		query := url.Values{}
+		query.Set("name", name)
 
-	updateReq := cloudflareUpdateRequest{
-		Type:    recordType,
-		Name:    fullName,
-		Content: value,
-		Proxied: false,
+		var zones cloudflareZonesResponse
+		if err := c.doRequest("GET", c.baseURL+"/zones?"+query.Encode(), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones.Result) > 0 {
+			c.mu.Lock()
+			c.zoneIDs[domain] = zones.Result[0].ID
+			c.mu.Unlock()
+			return zones.Result[0].ID, nil
+		}
 	}
+	return "", permanentError(fmt.Errorf("zone not found for %s", domain))
+}
 
-	body, err := json.Marshal(updateReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %v", err)
+// doRequest sends an authenticated API request and decodes the response into
+// out. Failures are classified as retryable or permanent.
+func (c *CloudflareProvider) doRequest(method, url string, payload interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+		}
+		reqBody = bytes.NewBuffer(body)
 	}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(body))
+	req, err := http.NewRequest(method, url, reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
 	}
 
-	req.Header.Set("X-Auth-Key", c.apiKey)
-	req.Header.Set("X-Auth-Email", c.apiEmail)
+	if c.apiEmail != "" {
+		req.Header.Set("X-Auth-Key", c.apiKey)
+		req.Header.Set("X-Auth-Email", c.apiEmail)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("API request failed: %v", err)
+		return requestError(err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
 	}
 
-	var cfResp cloudflareSingleResponse
-	if err := json.Unmarshal(respBody, &cfResp); err != nil {
-		return fmt.Errorf("failed to parse response: %v", err)
+	var envelope struct {
+		Success bool              `json:"success"`
+		Errors  []cloudflareError `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		if statusErr := statusError(resp, respBody); statusErr != nil {
+			return statusErr
+		}
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
 	}
 
-	if !cfResp.Success {
-		if len(cfResp.Errors) > 0 {
-			return fmt.Errorf("API request failed: %s", cfResp.Errors[0].Message)
+	if !envelope.Success {
+		apiErr := fmt.Errorf("API request failed")
+		if len(envelope.Errors) > 0 {
+			apiErr = fmt.Errorf("API request failed: %s", envelope.Errors[0].Message)
+		}
+		if statusErr := statusError(resp, respBody); statusErr != nil && IsRetryable(statusErr) {
+			return retryableError(apiErr, retryAfter(statusErr))
 		}
-		return fmt.Errorf("API request failed")
+		return permanentError(apiErr)
 	}
 
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// cloudflareStandIn is a minimal Cloudflare v4 API serving the example.com
+// zone. Requests must authenticate with key as the Global API Key of email,
+// or as an API token when email is empty.
+type cloudflareStandIn struct {
+	key, email string
+
+	mu      sync.Mutex
+	records []cloudflareRecord
+	nextID  int
+}
+
+func (s *cloudflareStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authorized := r.Header.Get("X-Auth-Email") == s.email && r.Header.Get("X-Auth-Key") == s.key
+	if s.email == "" {
+		authorized = r.Header.Get("Authorization") == "Bearer "+s.key
+	}
+	if !authorized {
+		cloudflareFail(w, http.StatusForbidden, 10000, "Authentication error")
+		return
+	}
+
+	if r.URL.Path == "/zones" {
+		result := []map[string]string{}
+		if r.URL.Query().Get("name") == "example.com" {
+			result = append(result, map[string]string{"id": "zone-1", "name": "example.com"})
+		}
+		cloudflareResult(w, result)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/zones/zone-1/dns_records")
+	if !ok {
+		cloudflareFail(w, http.StatusNotFound, 7003, "Could not route to "+r.URL.Path+", perhaps your object identifier is invalid?")
+		return
+	}
+	id := strings.TrimPrefix(path, "/")
+
+	switch {
+	case id == "" && r.Method == "GET":
+		result := []cloudflareRecord{}
+		for _, record := range s.records {
+			if record.Name == r.URL.Query().Get("name") && record.Type == r.URL.Query().Get("type") {
+				result = append(result, record)
+			}
+		}
+		cloudflareResult(w, result)
+
+	case id == "" && r.Method == "POST", id != "" && r.Method == "PUT":
+		var record cloudflareRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil || record.Type == "" || record.Content == "" {
+			cloudflareFail(w, http.StatusBadRequest, 9207, "Request body is invalid.")
+			return
+		}
+		if record.Name != "example.com" && !strings.HasSuffix(record.Name, ".example.com") {
+			cloudflareFail(w, http.StatusBadRequest, 9005, "Content for record is invalid. Name is outside the zone.")
+			return
+		}
+		if r.Method == "POST" {
+			s.nextID++
+			record.ID = fmt.Sprintf("record-%d", s.nextID)
+			s.records = append(s.records, record)
+			cloudflareResult(w, record)
+			return
+		}
+		for i := range s.records {
+			if s.records[i].ID == id {
+				record.ID = id
+				s.records[i] = record
+				cloudflareResult(w, record)
+				return
+			}
+		}
+		cloudflareFail(w, http.StatusNotFound, 81044, "Record does not exist.")
+
+	case id != "" && r.Method == "DELETE":
+		for i := range s.records {
+			if s.records[i].ID == id {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+				cloudflareResult(w, map[string]string{"id": id})
+				return
+			}
+		}
+		cloudflareFail(w, http.StatusNotFound, 81044, "Record does not exist.")
+
+	default:
+		cloudflareFail(w, http.StatusMethodNotAllowed, 10405, "Method not allowed")
+	}
+}
+
+// cloudflareResult writes a successful API response
+func cloudflareResult(w http.ResponseWriter, result interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "errors": []interface{}{}, "result": result})
+}
+
+// cloudflareFail writes an API error response
+func cloudflareFail(w http.ResponseWriter, status, code int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"errors":  []cloudflareError{{Code: code, Message: message}},
+		"result":  nil,
+	})
+}
+
+// cloudflareThrottled answers like the API does when the rate limit is hit
+func cloudflareThrottled(w http.ResponseWriter, r *http.Request) {
+	cloudflareFail(w, http.StatusTooManyRequests, 971, "Please wait and consider throttling your request speed")
+}
+
+// TestCloudflareCredentials checks that an API token is sent as a bearer
+// token and a Global API Key together with the account email
+func TestCloudflareCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		key, email string
+	}{
+		{"API token", "token", ""},
+		{"Global API Key", "global-key", "user@example.com"},
+	}
+	for _, test := range tests {
+		standIn := &cloudflareStandIn{key: test.key, email: test.email}
+		server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+		t.Cleanup(server.Close)
+
+		p, _ := newCloudflareProvider(test.key, test.email, server.URL)
+		if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+			t.Errorf("%s: GetRecords: %v", test.name, err)
+		}
+
+		p, _ = newCloudflareProvider("wrong", test.email, server.URL)
+		_, err := p.GetRecords("example.com", "www", "A")
+		if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Authentication error") {
+			t.Errorf("%s: got error %v, want a permanent authentication error", test.name, err)
+		}
+	}
+}
+
+// TestCloudflareErrors checks that API error messages are reported and
+// that throttling is retryable
+func TestCloudflareErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc((&cloudflareStandIn{key: "token"}).serve))
+	t.Cleanup(server.Close)
+
+	p, _ := newCloudflareProvider("token", "", server.URL)
+	_, err := p.GetRecords("example.net", "www", "A")
+	if err == nil || IsRetryable(err) {
+		t.Errorf("got error %v for a missing zone, want a permanent error", err)
+	}
+	err = p.UpdateRecord("record-9", "example.com", "www", "A", "192.0.2.1")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Record does not exist.") {
+		t.Errorf("got error %v, want a permanent missing record error", err)
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(cloudflareThrottled))
+	t.Cleanup(server.Close)
+	p, _ = newCloudflareProvider("token", "", server.URL)
+	if _, err := p.GetRecords("example.com", "www", "A"); !IsRetryable(err) || !strings.Contains(err.Error(), "throttling") {
+		t.Errorf("got error %v, want a retryable throttling error", err)
+	}
+}
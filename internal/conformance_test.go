@@ -0,0 +1,500 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// conformanceDomain is the zone the stand-ins serve
+const conformanceDomain = "example.com"
+
+// faulty is implemented by stand-ins that can make their next calls fail
+// with a transient error, such as throttling
+type faulty interface {
+	FailNext(n int)
+}
+
+// conformanceCheck is one conformance check. Checks needing several
+// records per name or DeleteRecord are skipped for single-value providers.
+type conformanceCheck struct {
+	name       string
+	multiValue bool
+	run        func(p DNSProvider, domain string, faults faulty) error
+}
+
+// conformanceChecks are run in order; later checks build on the records
+// created by earlier ones
+var conformanceChecks = []conformanceCheck{
+	{"missing record", false, checkMissing},
+	{"create", false, checkCreate},
+	{"update", false, checkUpdate},
+	{"record types", false, checkTypes},
+	{"apex", false, checkApex},
+	{"multiple records", true, checkMultiple},
+	{"delete", true, checkDelete},
+	{"transient failure", false, checkTransient},
+}
+
+// conformanceTarget is a provider checked against a stand-in of its API.
+// start returns the provider and, when the stand-in can inject failures,
+// its fault injector.
+type conformanceTarget struct {
+	name string
+	// singleValue is set for providers holding one address per name that
+	// cannot delete records, such as dyndns2 services
+	singleValue bool
+	start       func(t *testing.T) (DNSProvider, faulty)
+}
+
+// TestConformance runs the conformance checks against every provider
+func TestConformance(t *testing.T) {
+	for _, target := range conformanceTargets {
+		t.Run(target.name, func(t *testing.T) {
+			p, faults := target.start(t)
+			for _, check := range conformanceChecks {
+				if check.multiValue && target.singleValue {
+					continue
+				}
+				if err := check.run(p, conformanceDomain, faults); err != nil {
+					t.Errorf("%s: %v", check.name, err)
+				}
+			}
+		})
+	}
+}
+
+// conformanceTargets lists every provider, in the order of newProvider,
+// configured against a stand-in of its API
+var conformanceTargets = []conformanceTarget{
+	{name: "dnspod", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &dnspodStandIn{}
+		url, faults := startStandIn(t, standIn.serve, dnspodThrottled)
+		return newConformanceProvider(t, config.Config{
+			Provider: "dnspod", SecretID: "AKIDEXAMPLE", SecretKey: "dnspod-secret-key", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "cloudflare (API token)", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &cloudflareStandIn{key: "token"}
+		url, faults := startStandIn(t, standIn.serve, cloudflareThrottled)
+		return newConformanceProvider(t, config.Config{Provider: "cloudflare", SecretID: "token", APIEndpoint: url}), faults
+	}},
+	{name: "cloudflare (Global API Key)", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &cloudflareStandIn{key: "global-key", email: "user@example.com"}
+		url, faults := startStandIn(t, standIn.serve, cloudflareThrottled)
+		return newConformanceProvider(t, config.Config{
+			Provider: "cloudflare", SecretID: "global-key", SecretKey: "user@example.com", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "aliyun", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &aliyunStandIn{}
+		url, faults := startStandIn(t, standIn.serve, aliyunThrottled)
+		return newConformanceProvider(t, config.Config{Provider: "aliyun", SecretID: "key-id", SecretKey: "key-secret", APIEndpoint: url}), faults
+	}},
+	{name: "alibabacloud", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &aliyunStandIn{}
+		url, faults := startStandIn(t, standIn.serve, aliyunThrottled)
+		return newConformanceProvider(t, config.Config{Provider: "alibabacloud", SecretID: "key-id", SecretKey: "key-secret", APIEndpoint: url}), faults
+	}},
+	{name: "rfc2136", start: func(t *testing.T) (DNSProvider, faulty) {
+		server := newRFC2136StandIn(t, map[string][]string{})
+		return newConformanceProvider(t, config.Config{
+			Provider: "rfc2136", SecretID: "update-key", SecretKey: tsigTestSecret, TSIGAlgorithm: "hmac-sha256",
+			RFC2136Server: server.conn.LocalAddr().String(), RFC2136Transport: "udp",
+		}), nil
+	}},
+	{name: "route53", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &route53StandIn{sets: make(map[string]route53RecordSet)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "route53", SecretID: "AKIDEXAMPLE", SecretKey: testAWSSecretKey, APIEndpoint: url,
+		}), faults
+	}},
+	{name: "gcloud", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &gcloudStandIn{sets: make(map[string]gcloudRecordSet)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		keyFile, publicKey := writeGcloudTestKey(t, url+"/token")
+		standIn.publicKey = publicKey
+		return newConformanceProvider(t, config.Config{Provider: "gcloud", SecretKey: keyFile, APIEndpoint: url}), faults
+	}},
+	{name: "azure", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &azureStandIn{expiresIn: 3599, sets: make(map[string]azureRecordSet)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "azure", SecretID: "client-id", SecretKey: "client-secret",
+			AzureTenantID: "tenant-id", AzureSubscriptionID: "sub-id", AzureResourceGroup: "dns-rg",
+			AuthEndpoint: url + "/tenant-id/oauth2/v2.0/token", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "huaweicloud", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &huaweiStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "huaweicloud", SecretID: "HPUAEXAMPLE", SecretKey: "huawei-secret-key", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "volcengine", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &volcengineStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "volcengine", SecretID: "AKLTEXAMPLE", SecretKey: "volcengine-secret-key", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "digitalocean", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &digitalOceanStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "digitalocean", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "linode", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &linodeStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "linode", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "vultr", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &vultrStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "vultr", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "hetzner", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &hetznerStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "hetzner", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "desec", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &deSECStandIn{rrsets: make(map[string]deSECRRSet)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "desec", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "godaddy", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &goDaddyStandIn{sets: make(map[string][]goDaddyRecord)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "godaddy", SecretID: "key", SecretKey: "secret", APIEndpoint: url}), faults
+	}},
+	{name: "namecheap", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &namecheapStandIn{emailType: "MX"}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "namecheap", SecretID: "user", SecretKey: "key", NamecheapClientIP: "198.51.100.1", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "porkbun", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &porkbunStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "porkbun", SecretID: "pk1_key", SecretKey: "sk1_secret", APIEndpoint: url}), faults
+	}},
+	{name: "gandi", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &gandiStandIn{rrsets: make(map[string]gandiRRSet)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "gandi", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "ovh", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &ovhStandIn{records: make(map[int64]ovhRecord)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "ovh", SecretID: "app-key", SecretKey: "app-secret", OVHConsumerKey: "consumer-key", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "namecom", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &nameComStandIn{records: make(map[int64]nameComRecord)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "namecom", SecretID: "user", SecretKey: "token", APIEndpoint: url}), faults
+	}},
+	{name: "dyndns2", singleValue: true, start: func(t *testing.T) (DNSProvider, faulty) {
+		// Records are read from the last update sent, so injected failures
+		// would not be seen by GetRecords
+		standIn := &dynDNSStandIn{hosts: make(map[string]string)}
+		url, _ := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "dyndns2", SecretID: "user", SecretKey: "pass", APIEndpoint: url}), nil
+	}},
+	{name: "powerdns", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &powerDNSStandIn{rrsets: make(map[string]powerDNSRRSet)}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{
+			Provider: "powerdns", SecretKey: "secret", PowerDNSServerID: "localhost", APIEndpoint: url,
+		}), faults
+	}},
+	{name: "pihole", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &piholeStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "pihole", SecretKey: "password", APIEndpoint: url}), faults
+	}},
+	{name: "adguard", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &adGuardStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, config.Config{Provider: "adguard", SecretID: "admin", SecretKey: "password", APIEndpoint: url}), faults
+	}},
+	{name: "hosts", start: func(t *testing.T) (DNSProvider, faulty) {
+		file := writeConformanceFile(t, "hosts", "127.0.0.1\tlocalhost\n")
+		return newConformanceProvider(t, config.Config{Provider: "hosts", RecordFile: file}), nil
+	}},
+	{name: "zonefile", start: func(t *testing.T) (DNSProvider, faulty) {
+		file := writeConformanceFile(t, "example.com.zone",
+			"$ORIGIN example.com.\n@\t3600\tIN\tSOA\tns1 hostmaster 1 7200 3600 1209600 3600\n\tIN\tNS\tns1\n")
+		return newConformanceProvider(t, config.Config{Provider: "zonefile", RecordFile: file}), nil
+	}},
+	{name: "webhook", start: func(t *testing.T) (DNSProvider, faulty) {
+		standIn := &webhookStandIn{}
+		url, faults := startStandIn(t, standIn.serve, nil)
+		return newConformanceProvider(t, webhookTestConfig(url)), faults
+	}},
+	{name: "exec", start: func(t *testing.T) (DNSProvider, faulty) {
+		backend := execBackend{t.TempDir()}
+		return newConformanceProvider(t, execTestConfig(t, backend.dir)), backend
+	}},
+	{name: "memory", start: func(t *testing.T) (DNSProvider, faulty) {
+		p := NewMemoryProvider()
+		return p, memoryFaults{p}
+	}},
+}
+
+// memoryFaults injects transient failures into a MemoryProvider
+type memoryFaults struct {
+	provider *MemoryProvider
+}
+
+// FailNext makes the next n calls fail with a retryable error
+func (m memoryFaults) FailNext(n int) {
+	for i := 0; i < n; i++ {
+		m.provider.FailNext(&ProviderError{Err: fmt.Errorf("injected failure"), Retryable: true})
+	}
+}
+
+// writeConformanceFile writes a file for a local backend and returns its path
+func writeConformanceFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// faultyHandler serves a stand-in, failing the next requests on demand
+type faultyHandler struct {
+	handler http.HandlerFunc
+	// fail writes the transient error; without it the response is 503
+	fail http.HandlerFunc
+
+	mu      sync.Mutex
+	failing int
+}
+
+// FailNext makes the next n requests fail with a transient error
+func (f *faultyHandler) FailNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing += n
+}
+
+func (f *faultyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	fail := f.failing > 0
+	if fail {
+		f.failing--
+	}
+	f.mu.Unlock()
+
+	switch {
+	case !fail:
+		f.handler(w, r)
+	case f.fail != nil:
+		f.fail(w, r)
+	default:
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}
+}
+
+// startStandIn serves handler on a local server for the duration of the
+// test and returns its URL and fault injector. fail, if set, writes the
+// injected transient errors.
+func startStandIn(t *testing.T, handler, fail http.HandlerFunc) (string, *faultyHandler) {
+	faults := &faultyHandler{handler: handler, fail: fail}
+	server := httptest.NewServer(faults)
+	t.Cleanup(server.Close)
+	return server.URL, faults
+}
+
+// newConformanceProvider creates the provider selected by cfg the way the
+// service does, with a rate limit the checks do not notice
+func newConformanceProvider(t *testing.T, cfg config.Config) DNSProvider {
+	if cfg.RecordTTL == 0 {
+		cfg.RecordTTL = 600
+	}
+	cfg.RateLimit = 1000
+	cfg.RateLimitBurst = 1
+
+	p, err := NewDNSProvider(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// checkMissing expects a missing record to be reported as no records
+// without error
+func checkMissing(p DNSProvider, domain string, faults faulty) error {
+	records, err := p.GetRecords(domain, "conformance-missing", "A")
+	if err != nil {
+		return fmt.Errorf("GetRecords returned error %v, want none", err)
+	}
+	if len(records) != 0 {
+		return fmt.Errorf("GetRecords returned %+v, want no records", records)
+	}
+	return nil
+}
+
+// checkCreate expects a created record to be returned by GetRecords with the
+// ID CreateRecord returned
+func checkCreate(p DNSProvider, domain string, faults faulty) error {
+	id, err := p.CreateRecord(domain, "conformance", "A", "192.0.2.1")
+	if err != nil {
+		return fmt.Errorf("CreateRecord: %v", err)
+	}
+	if id == "" {
+		return fmt.Errorf("CreateRecord returned an empty record ID")
+	}
+
+	record, err := expectValue(p, domain, "conformance", "A", "192.0.2.1")
+	if err != nil {
+		return err
+	}
+	if record.RecordID != id {
+		return fmt.Errorf("GetRecords returned ID %q, CreateRecord returned %q", record.RecordID, id)
+	}
+	return nil
+}
+
+// checkUpdate expects UpdateRecord to change the value GetRecords returns
+func checkUpdate(p DNSProvider, domain string, faults faulty) error {
+	record, err := expectValue(p, domain, "conformance", "A", "192.0.2.1")
+	if err != nil {
+		return err
+	}
+	if err := p.UpdateRecord(record.RecordID, domain, "conformance", "A", "192.0.2.2"); err != nil {
+		return fmt.Errorf("UpdateRecord: %v", err)
+	}
+	_, err = expectValue(p, domain, "conformance", "A", "192.0.2.2")
+	return err
+}
+
+// checkTypes expects A and AAAA records of the same name to be independent
+func checkTypes(p DNSProvider, domain string, faults faulty) error {
+	if _, err := p.CreateRecord(domain, "conformance", "AAAA", "2001:db8::1"); err != nil {
+		return fmt.Errorf("CreateRecord: %v", err)
+	}
+	if _, err := expectValue(p, domain, "conformance", "AAAA", "2001:db8::1"); err != nil {
+		return err
+	}
+	_, err := expectValue(p, domain, "conformance", "A", "192.0.2.2")
+	return err
+}
+
+// checkApex expects "@" to address the domain itself
+func checkApex(p DNSProvider, domain string, faults faulty) error {
+	if _, err := p.CreateRecord(domain, "@", "A", "192.0.2.3"); err != nil {
+		return fmt.Errorf("CreateRecord: %v", err)
+	}
+	if _, err := expectValue(p, domain, "@", "A", "192.0.2.3"); err != nil {
+		return err
+	}
+	_, err := expectValue(p, domain, "conformance", "A", "192.0.2.2")
+	return err
+}
+
+// checkMultiple expects every record of a name to be returned
+func checkMultiple(p DNSProvider, domain string, faults faulty) error {
+	for _, value := range []string{"192.0.2.10", "192.0.2.11"} {
+		if _, err := p.CreateRecord(domain, "conformance-multi", "A", value); err != nil {
+			return fmt.Errorf("CreateRecord: %v", err)
+		}
+	}
+
+	records, err := p.GetRecords(domain, "conformance-multi", "A")
+	if err != nil {
+		return fmt.Errorf("GetRecords: %v", err)
+	}
+	values := map[string]string{}
+	for _, record := range records {
+		values[record.Value] = record.RecordID
+	}
+	if len(records) != 2 || values["192.0.2.10"] == "" || values["192.0.2.11"] == "" {
+		return fmt.Errorf("GetRecords returned %+v, want 192.0.2.10 and 192.0.2.11", records)
+	}
+	if values["192.0.2.10"] == values["192.0.2.11"] {
+		return fmt.Errorf("GetRecords returned the same ID %q for both records", values["192.0.2.10"])
+	}
+	return nil
+}
+
+// checkDelete expects DeleteRecord to remove only the record with the ID
+func checkDelete(p DNSProvider, domain string, faults faulty) error {
+	records, err := p.GetRecords(domain, "conformance-multi", "A")
+	if err != nil {
+		return fmt.Errorf("GetRecords: %v", err)
+	}
+	for _, record := range records {
+		if record.Value != "192.0.2.10" {
+			continue
+		}
+		if err := p.DeleteRecord(record.RecordID, domain, "conformance-multi", "A"); err != nil {
+			return fmt.Errorf("DeleteRecord: %v", err)
+		}
+	}
+
+	record, err := expectValue(p, domain, "conformance-multi", "A", "192.0.2.11")
+	if err != nil {
+		return err
+	}
+	if err := p.DeleteRecord(record.RecordID, domain, "conformance-multi", "A"); err != nil {
+		return fmt.Errorf("DeleteRecord: %v", err)
+	}
+	if records, err = p.GetRecords(domain, "conformance-multi", "A"); err != nil || len(records) != 0 {
+		return fmt.Errorf("GetRecords after deleting every record returned %+v, %v; want no records", records, err)
+	}
+
+	_, err = expectValue(p, domain, "conformance", "A", "192.0.2.2")
+	return err
+}
+
+// checkTransient expects transient failures to be reported as retryable
+func checkTransient(p DNSProvider, domain string, faults faulty) error {
+	if faults == nil {
+		return nil
+	}
+
+	faults.FailNext(1)
+	_, err := p.GetRecords(domain, "conformance", "A")
+	if err == nil {
+		return fmt.Errorf("GetRecords succeeded despite an injected failure")
+	}
+	if !IsRetryable(err) {
+		return fmt.Errorf("transient failure %q is not retryable", err)
+	}
+
+	_, err = expectValue(p, domain, "conformance", "A", "192.0.2.2")
+	return err
+}
+
+// expectValue returns the record for the name and type, failing unless it
+// is the only one and has value
+func expectValue(p DNSProvider, domain, subdomain, recordType, value string) (*DNSRecord, error) {
+	records, err := p.GetRecords(domain, subdomain, recordType)
+	if err != nil {
+		return nil, fmt.Errorf("GetRecords %s %s: %v", subdomain, recordType, err)
+	}
+	if len(records) != 1 {
+		return nil, fmt.Errorf("GetRecords %s %s returned %+v, want only %s", subdomain, recordType, records, value)
+	}
+	record := records[0]
+	if record.Value != value {
+		return nil, fmt.Errorf("GetRecords %s %s returned %s, want %s", subdomain, recordType, record.Value, value)
+	}
+	if record.RecordID == "" {
+		return nil, fmt.Errorf("GetRecords %s %s returned an empty record ID", subdomain, recordType)
+	}
+	return &record, nil
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"ddnsd/utils"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ipDamper holds back a newly detected address until it has been seen
+// consistently for a number of checks or for a minimum time, whichever
+// comes first, so addresses that bounce during a reconnect do not rewrite
+// records
+type ipDamper struct {
+	checks   int
+	window   time.Duration
+	families map[string]*dampedFamily
+}
+
+// dampedFamily is the damping state of one address family, kept across
+// update cycles
+type dampedFamily struct {
+	accepted  []string
+	candidate string
+	seen      int
+	since     time.Time
+}
+
+// newIPDamper returns a damper, or nil when damping is disabled
+func newIPDamper(cfg *config.Config) *ipDamper {
+	if cfg.StabilityChecks <= 1 && cfg.StabilityWindow == 0 {
+		return nil
+	}
+	return &ipDamper{
+		checks:   cfg.StabilityChecks,
+		window:   time.Duration(cfg.StabilityWindow) * time.Second,
+		families: make(map[string]*dampedFamily),
+	}
+}
+
+// Filter returns the addresses the records of the family should hold: the
+// detected ones once they are stable, and the previously accepted ones
+// until then. The first addresses detected are accepted immediately.
+// suppressed is true on the first check that holds back a new address.
+func (d *ipDamper) Filter(log *utils.Logger, ipType string, ips []string) (accepted []string, suppressed bool) {
+	family := d.families[ipType]
+	if family == nil {
+		d.families[ipType] = &dampedFamily{accepted: ips}
+		return ips, false
+	}
+
+	detected := strings.Join(ips, ",")
+	if detected == strings.Join(family.accepted, ",") {
+		if family.candidate != "" {
+			log.Info("IP address back to %s, dropping pending change to %s", detected, family.candidate)
+		}
+		family.candidate = ""
+		return family.accepted, false
+	}
+
+	now := time.Now()
+	if detected != family.candidate {
+		family.candidate, family.seen, family.since = detected, 0, now
+	}
+	family.seen++
+
+	if d.stable(family, now) {
+		log.Info("IP address %s stable for %d checks over %v, accepting", detected, family.seen, now.Sub(family.since).Round(time.Second))
+		family.accepted, family.candidate = ips, ""
+		return ips, false
+	}
+
+	log.Warning("IP change to %s suppressed until stable: %s", detected, d.progress(family, now))
+	return family.accepted, family.seen == 1
+}
+
+// stable reports whether the candidate has met either configured
+// condition. A condition left at its default never holds.
+func (d *ipDamper) stable(family *dampedFamily, now time.Time) bool {
+	return (d.checks > 1 && family.seen >= d.checks) || (d.window > 0 && now.Sub(family.since) >= d.window)
+}
+
+// progress describes how far the candidate is from being accepted
+func (d *ipDamper) progress(family *dampedFamily, now time.Time) string {
+	var parts []string
+	if d.checks > 1 {
+		parts = append(parts, fmt.Sprintf("seen %d of %d checks", family.seen, d.checks))
+	}
+	if d.window > 0 {
+		parts = append(parts, fmt.Sprintf("seen for %v of %v", now.Sub(family.since).Round(time.Second), d.window))
+	}
+	return strings.Join(parts, " or ")
+}
+
+// changeLimiter caps the number of changes to each record within an hour
+type changeLimiter struct {
+	max     int
+	mu      sync.Mutex
+	changes map[string][]time.Time
+}
+
+// errChangeSuppressed marks a record change held back by the change cap
+type errChangeSuppressed struct {
+	changes int
+}
+
+// Error implements error
+func (e *errChangeSuppressed) Error() string {
+	return fmt.Sprintf("%d changes in the last hour", e.changes)
+}
+
+// changeSuppressed reports whether the change cap held back the update
+func (r updateResult) changeSuppressed() bool {
+	_, ok := r.err.(*errChangeSuppressed)
+	return ok
+}
+
+// newChangeLimiter returns a limiter, or nil when changes are not capped
+func newChangeLimiter(cfg *config.Config) *changeLimiter {
+	if cfg.MaxChangesPerHour == 0 {
+		return nil
+	}
+	return &changeLimiter{max: cfg.MaxChangesPerHour, changes: make(map[string][]time.Time)}
+}
+
+// Allow reports an error when the record has already changed the maximum
+// number of times in the last hour
+func (l *changeLimiter) Allow(job updateJob) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := job.fullDomain() + "/" + job.recordType
+	cutoff := time.Now().Add(-time.Hour)
+	recent := l.changes[key][:0]
+	for _, t := range l.changes[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	l.changes[key] = recent
+
+	if len(recent) >= l.max {
+		return &errChangeSuppressed{changes: len(recent)}
+	}
+	return nil
+}
+
+// Record counts a change to the record
+func (l *changeLimiter) Record(job updateJob) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := job.fullDomain() + "/" + job.recordType
+	l.changes[key] = append(l.changes[key], time.Now())
+}
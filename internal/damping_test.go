@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"ddnsd/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIPDamperAcceptsOnEitherCondition checks that a new address is
+// accepted once it has been seen for the configured checks or for the
+// configured time, whichever comes first
+func TestIPDamperAcceptsOnEitherCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks int
+		window int
+		// age is how long the new address has been seen at each check
+		ages []time.Duration
+		want int // check on which the address is accepted, 0 for never
+	}{
+		{"checks only", 3, 0, []time.Duration{0, 0, 0}, 3},
+		{"window only", 1, 60, []time.Duration{0, 30 * time.Second, time.Minute}, 3},
+		{"checks first", 2, 600, []time.Duration{0, time.Second, 2 * time.Second}, 2},
+		{"window first", 10, 60, []time.Duration{0, 2 * time.Minute}, 2},
+		{"neither", 5, 600, []time.Duration{0, time.Minute, 2 * time.Minute}, 0},
+	}
+
+	log := utils.NewLogger("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newIPDamper(&config.Config{StabilityChecks: tt.checks, StabilityWindow: tt.window})
+			d.Filter(log, "ipv4", []string{"192.0.2.1"})
+
+			accepted := 0
+			for i, age := range tt.ages {
+				if family := d.families["ipv4"]; family.candidate != "" {
+					family.since = time.Now().Add(-age)
+				}
+				ips, suppressed := d.Filter(log, "ipv4", []string{"192.0.2.2"})
+				if suppressed != (i == 0 && tt.want != 1) {
+					t.Errorf("check %d: suppressed = %v", i+1, suppressed)
+				}
+				if ips[0] == "192.0.2.2" {
+					accepted = i + 1
+					break
+				}
+			}
+			if accepted != tt.want {
+				t.Errorf("accepted on check %d, want %d", accepted, tt.want)
+			}
+		})
+	}
+}
+
+// TestIPDamperBounceBack checks that an address returning to the accepted
+// one drops the pending change
+func TestIPDamperBounceBack(t *testing.T) {
+	log := utils.NewLogger("")
+	d := newIPDamper(&config.Config{StabilityChecks: 2})
+	d.Filter(log, "ipv4", []string{"192.0.2.1"})
+
+	d.Filter(log, "ipv4", []string{"192.0.2.2"})
+	d.Filter(log, "ipv4", []string{"192.0.2.1"})
+	ips, suppressed := d.Filter(log, "ipv4", []string{"192.0.2.2"})
+	if strings.Join(ips, ",") != "192.0.2.1" || !suppressed {
+		t.Errorf("got %v (suppressed %v), want the change held back again from the start", ips, suppressed)
+	}
+}
+
+// TestDampedChangeInHistory checks that a change held back by damping is
+// written to the history before it is applied
+func TestDampedChangeInHistory(t *testing.T) {
+	ip := "192.0.2.1"
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ip)
+	}))
+	defer source.Close()
+
+	cfg := &config.Config{
+		IPv4Enabled:     true,
+		IPv4Domain:      "example.com",
+		IPv4SubDomains:  []string{"www"},
+		IPv4Sources:     []string{source.URL},
+		StabilityChecks: 2,
+		HistoryFile:     filepath.Join(t.TempDir(), "history.jsonl"),
+		Concurrency:     1,
+	}
+	u := NewUpdater(NewMemoryProvider(), cfg)
+
+	u.Run()
+	ip = "192.0.2.2"
+	u.Run()
+	u.Run()
+
+	entries, err := ReadHistory(cfg.HistoryFile, HistoryFilter{Record: "www.example.com"}, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for _, entry := range entries {
+		events = append(events, entry.Event)
+	}
+	if want := "created suppressed updated"; strings.Join(events, " ") != want {
+		t.Fatalf("got events %v, want %s", events, want)
+	}
+	if entries[1].OldValue != "192.0.2.1" || entries[1].NewValue != "192.0.2.2" {
+		t.Errorf("suppressed entry has %s -> %s, want 192.0.2.1 -> 192.0.2.2", entries[1].OldValue, entries[1].NewValue)
+	}
+}
+
+// TestChangeLimiter checks that each record may change the configured
+// number of times within an hour
+func TestChangeLimiter(t *testing.T) {
+	l := newChangeLimiter(&config.Config{MaxChangesPerHour: 2})
+	www := updateJob{domain: "example.com", subDomain: "www", recordType: "A"}
+	www6 := updateJob{domain: "example.com", subDomain: "www", recordType: "AAAA"}
+
+	for i := 0; i < 2; i++ {
+		if err := l.Allow(www); err != nil {
+			t.Fatalf("change %d: %v", i+1, err)
+		}
+		l.Record(www)
+	}
+	err := l.Allow(www)
+	if err == nil || err.Error() != "2 changes in the last hour" || !(updateResult{err: err}).changeSuppressed() {
+		t.Errorf("third change: got %v, want it suppressed", err)
+	}
+	if err := l.Allow(www6); err != nil {
+		t.Errorf("AAAA record: got %v, want it counted separately", err)
+	}
+
+	// Changes older than an hour no longer count
+	l.mu.Lock()
+	l.changes["www.example.com/A"][0] = time.Now().Add(-61 * time.Minute)
+	l.mu.Unlock()
+	if err := l.Allow(www); err != nil {
+		t.Errorf("after an hour: got %v, want the change allowed", err)
+	}
+}
+
+// TestChangeLimiterCountsPartialChanges checks that a change failing after
+// some records were already changed still counts against the cap
+func TestChangeLimiterCountsPartialChanges(t *testing.T) {
+	provider := NewMemoryProvider()
+	seedRecords(t, provider, "www", "A", "192.0.2.1", "192.0.2.9", "192.0.2.1")
+	u := NewUpdater(provider, &config.Config{MaxChangesPerHour: 1, DuplicatePolicy: "delete"})
+	job := updateJob{
+		domain: "example.com", subDomain: "www", recordType: "A",
+		ip: "192.0.2.9", ips: []string{"192.0.2.9"}, log: utils.NewLogger(""),
+	}
+
+	// The first duplicate is deleted, the second fails
+	provider.FailNext(nil, nil, permanentError(fmt.Errorf("injected failure")))
+	result := u.runJob(job)
+	if result.outcome != outcomeFailed || !result.started || !result.applied {
+		t.Fatalf("got %v started=%v applied=%v, want a failure after a change", result.outcome, result.started, result.applied)
+	}
+
+	result = u.runJob(job)
+	if result.outcome != outcomeSuppressed {
+		t.Errorf("retry got %v (%v), want it suppressed by the cap", result.outcome, result.err)
+	}
+	if got := fmt.Sprint(provider.Records("example.com", "www", "A")); got != "[{2 192.0.2.9} {3 192.0.2.1}]" {
+		t.Errorf("records %s, want the remaining duplicate left alone", got)
+	}
+
+	// A failure before any change does not count
+	u = NewUpdater(provider, &config.Config{MaxChangesPerHour: 1, DuplicatePolicy: "delete"})
+	provider.FailNext(nil, permanentError(fmt.Errorf("injected failure")))
+	if result := u.runJob(job); result.outcome != outcomeFailed || result.applied {
+		t.Fatalf("got %v applied=%v, want a failure with no change", result.outcome, result.applied)
+	}
+	if result := u.runJob(job); result.outcome != outcomeUpdated {
+		t.Errorf("retry got %v (%v), want it allowed", result.outcome, result.err)
+	}
+}
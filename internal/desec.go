@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// deSECMinTTL is the lowest TTL deSEC accepts by default
+const deSECMinTTL = 3600
+
+// DeSECProvider implements DNSProvider for deSEC
+type DeSECProvider struct {
+	api *restClient
+	ttl int
+}
+
+// deSEC API structures
+type deSECRRSet struct {
+	Subname string   `json:"subname"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl,omitempty"`
+	Records []string `json:"records"`
+}
+
+// newDeSECProvider creates a new deSEC provider instance. endpoint
+// overrides the API URL. TTLs below the deSEC minimum are raised to it.
+func newDeSECProvider(token, endpoint string, ttl int) (*DeSECProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://desec.io/api/v1"
+	}
+	if ttl < deSECMinTTL {
+		ttl = deSECMinTTL
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Token "+token)
+
+	return &DeSECProvider{
+		api: newRESTClient(endpoint, header, deSECErrorMessage),
+		ttl: ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. deSEC stores records as
+// RRsets, so each value doubles as its record ID.
+func (d *DeSECProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	rrset, err := d.getRRSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if rrset == nil {
+		return nil, nil
+	}
+	return setRecords(rrset.Records), nil
+}
+
+// CreateRecord adds a value to the RRset, creating the RRset if needed
+func (d *DeSECProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := d.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the RRset with value
+func (d *DeSECProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return d.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the RRset
+func (d *DeSECProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return d.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save writes the RRset with oldValue replaced by newValue, keeping the
+// other values and the TTL. An RRset left without records is deleted.
+func (d *DeSECProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	current, err := d.getRRSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		if newValue == "" {
+			return nil
+		}
+		rrset := deSECRRSet{
+			Subname: recordRelativeName(subdomain),
+			Type:    recordType,
+			TTL:     d.ttl,
+			Records: []string{newValue},
+		}
+		return d.api.do("POST", fmt.Sprintf("/domains/%s/rrsets/", url.PathEscape(domain)), rrset, nil)
+	}
+
+	update := struct {
+		Records []string `json:"records"`
+	}{
+		Records: replaceRecordValue(current.Records, oldValue, newValue),
+	}
+	return d.api.do("PATCH", deSECRRSetPath(domain, subdomain, recordType), update, nil)
+}
+
+// getRRSet returns the RRset for the name and type, or nil
+func (d *DeSECProvider) getRRSet(domain, subdomain, recordType string) (*deSECRRSet, error) {
+	var rrset deSECRRSet
+	err := d.api.do("GET", deSECRRSetPath(domain, subdomain, recordType), nil, &rrset)
+	if err == errRESTNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rrset, nil
+}
+
+// deSECRRSetPath returns the API path of an RRset. The apex is addressed
+// as "@".
+func deSECRRSetPath(domain, subdomain, recordType string) string {
+	return fmt.Sprintf("/domains/%s/rrsets/%s/%s/", url.PathEscape(domain), url.PathEscape(subdomain), recordType)
+}
+
+// deSECErrorMessage extracts the message from an API error response. deSEC
+// returns either a detail message or a map of field errors.
+func deSECErrorMessage(body []byte) string {
+	var detail struct {
+		Detail string `json:"detail"`
+	}
+	if json.Unmarshal(body, &detail) == nil && detail.Detail != "" {
+		return detail.Detail
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	var messages []string
+	for field, value := range fields {
+		messages = append(messages, field+": "+string(value))
+	}
+	sort.Strings(messages)
+	return strings.Join(messages, "; ")
+}
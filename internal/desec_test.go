@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// deSECStandIn is a minimal deSEC API for the example.com domain
+type deSECStandIn struct {
+	mu       sync.Mutex
+	rrsets   map[string]deSECRRSet // "subname type" -> RRset
+	throttle int
+}
+
+func (s *deSECStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Token token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Invalid token."})
+		return
+	}
+	if s.throttle > 0 {
+		s.throttle--
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Request was throttled. Expected available in 30 seconds."})
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/domains/example.com/rrsets/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Not found."})
+		return
+	}
+
+	if path == "" && r.Method == "POST" {
+		var rrset deSECRRSet
+		json.NewDecoder(r.Body).Decode(&rrset)
+		if rrset.TTL < deSECMinTTL {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string][]string{"ttl": {"Ensure this value is greater than or equal to 3600."}})
+			return
+		}
+		s.rrsets[rrset.Subname+" "+rrset.Type] = rrset
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rrset)
+		return
+	}
+
+	// The apex is addressed as @ in RRset paths
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	subname := strings.TrimPrefix(parts[0], "@")
+	key := subname + " " + parts[1]
+	rrset, exists := s.rrsets[key]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"detail": "Not found."})
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(rrset)
+	case "PATCH":
+		var update struct {
+			Records []string `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&update)
+		// An empty record list deletes the RRset
+		if len(update.Records) == 0 {
+			delete(s.rrsets, key)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rrset.Records = update.Records
+		s.rrsets[key] = rrset
+		json.NewEncoder(w).Encode(rrset)
+	}
+}
+
+func newDeSECTestProvider(t *testing.T, rrsets ...deSECRRSet) (*DeSECProvider, *deSECStandIn) {
+	standIn := &deSECStandIn{rrsets: make(map[string]deSECRRSet)}
+	for _, rrset := range rrsets {
+		standIn.rrsets[rrset.Subname+" "+rrset.Type] = rrset
+	}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newDeSECProvider("token", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestDeSECChanges checks that RRsets keep their other values and TTL,
+// that new RRsets get at least the minimum TTL and that an emptied RRset
+// is removed
+func TestDeSECChanges(t *testing.T) {
+	p, standIn := newDeSECTestProvider(t,
+		deSECRRSet{Subname: "www", Type: "A", TTL: 7200, Records: []string{"192.0.2.1", "192.0.2.9"}},
+		deSECRRSet{Subname: "www", Type: "AAAA", TTL: 7200, Records: []string{"2001:db8::1"}},
+		deSECRRSet{Subname: "", Type: "MX", TTL: 3600, Records: []string{"10 mail.example.com."}},
+	)
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := map[string]deSECRRSet{
+		"www A": {Subname: "www", Type: "A", TTL: 7200, Records: []string{"192.0.2.9", "192.0.2.2"}},
+		" A":    {Subname: "", Type: "A", TTL: 3600, Records: []string{"192.0.2.3"}},
+		" MX":   {Subname: "", Type: "MX", TTL: 3600, Records: []string{"10 mail.example.com."}},
+	}
+	if fmt.Sprint(standIn.rrsets) != fmt.Sprint(want) {
+		t.Errorf("RRsets are\n%v\nwant\n%v", standIn.rrsets, want)
+	}
+}
+
+// TestDeSECThrottling checks that a throttled request is retryable after
+// the time the API asks for
+func TestDeSECThrottling(t *testing.T) {
+	p, standIn := newDeSECTestProvider(t)
+	standIn.throttle = 1
+
+	_, err := p.GetRecords("example.com", "www", "A")
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || !providerErr.Retryable || providerErr.RetryAfter != 30*time.Second {
+		t.Fatalf("throttled request: got error %v, want a retryable error after 30s", err)
+	}
+	if !strings.Contains(err.Error(), "Request was throttled") {
+		t.Errorf("error %q does not carry the API message", err)
+	}
+
+	if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+		t.Errorf("request after throttling: %v", err)
+	}
+}
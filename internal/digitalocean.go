@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// DigitalOceanProvider implements DNSProvider for DigitalOcean DNS
+type DigitalOceanProvider struct {
+	api *restClient
+	ttl int
+}
+
+// DigitalOcean API structures
+type digitalOceanRecord struct {
+	ID   int64  `json:"id,omitempty"`
+	Type string `json:"type,omitempty"`
+	Name string `json:"name,omitempty"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+type digitalOceanRecordsResponse struct {
+	Records []digitalOceanRecord `json:"domain_records"`
+	Links   struct {
+		Pages struct {
+			Next string `json:"next"`
+		} `json:"pages"`
+	} `json:"links"`
+}
+
+type digitalOceanRecordResponse struct {
+	Record digitalOceanRecord `json:"domain_record"`
+}
+
+// newDigitalOceanProvider creates a new DigitalOcean provider instance.
+// endpoint overrides the API URL.
+func newDigitalOceanProvider(token, endpoint string, ttl int) (*DigitalOceanProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.digitalocean.com/v2"
+	}
+
+	return &DigitalOceanProvider{
+		api: newRESTClient(endpoint, bearerHeader(token), digitalOceanErrorMessage),
+		ttl: ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (d *DigitalOceanProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	query := url.Values{}
+	query.Set("type", recordType)
+	query.Set("name", recordFullName(domain, subdomain))
+	query.Set("per_page", "200")
+	path := fmt.Sprintf("/domains/%s/records?%s", url.PathEscape(domain), query.Encode())
+
+	var records []DNSRecord
+	// The name filter is applied server-side, but results are still paged
+	for path != "" {
+		var resp digitalOceanRecordsResponse
+		if err := d.api.do("GET", path, nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, record := range resp.Records {
+			if record.Name == subdomain && record.Type == recordType {
+				records = append(records, DNSRecord{
+					RecordID: strconv.FormatInt(record.ID, 10),
+					Value:    record.Data,
+				})
+			}
+		}
+		path = resp.Links.Pages.Next
+	}
+	return records, nil
+}
+
+// CreateRecord creates a new DNS record
+func (d *DigitalOceanProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	createReq := digitalOceanRecord{
+		Type: recordType,
+		Name: subdomain,
+		Data: value,
+		TTL:  d.ttl,
+	}
+
+	var resp digitalOceanRecordResponse
+	if err := d.api.do("POST", fmt.Sprintf("/domains/%s/records", url.PathEscape(domain)), createReq, &resp); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(resp.Record.ID, 10), nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (d *DigitalOceanProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	updateReq := digitalOceanRecord{
+		Data: value,
+	}
+
+	path := fmt.Sprintf("/domains/%s/records/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	return d.api.do("PATCH", path, updateReq, nil)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (d *DigitalOceanProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	path := fmt.Sprintf("/domains/%s/records/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	return d.api.do("DELETE", path, nil, nil)
+}
+
+// digitalOceanErrorMessage extracts the message from an API error response
+func digitalOceanErrorMessage(body []byte) string {
+	var errResp struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &errResp) != nil || errResp.Message == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s - %s", errResp.ID, errResp.Message)
+}
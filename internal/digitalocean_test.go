@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// digitalOceanStandIn is a minimal DigitalOcean domains API for
+// example.com. It filters listings by name and type like the real API and
+// pages them two records at a time, linking to the next page by full URL.
+type digitalOceanStandIn struct {
+	mu      sync.Mutex
+	records []digitalOceanRecord
+	nextID  int64
+}
+
+func (s *digitalOceanStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"id": "Unauthorized", "message": "Unable to authenticate you"})
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/domains/example.com/records")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"id": "not_found", "message": "The resource you were accessing could not be found."})
+		return
+	}
+
+	switch {
+	case path == "" && r.Method == "GET":
+		query := r.URL.Query()
+		var matching []digitalOceanRecord
+		for _, record := range s.records {
+			if recordFullName("example.com", record.Name) == query.Get("name") && record.Type == query.Get("type") {
+				matching = append(matching, record)
+			}
+		}
+
+		page, _ := strconv.Atoi(query.Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		var resp digitalOceanRecordsResponse
+		resp.Records = []digitalOceanRecord{}
+		for i := (page - 1) * 2; i < page*2 && i < len(matching); i++ {
+			resp.Records = append(resp.Records, matching[i])
+		}
+		if page*2 < len(matching) {
+			query.Set("page", strconv.Itoa(page+1))
+			resp.Links.Pages.Next = "http://" + r.Host + r.URL.Path + "?" + query.Encode()
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case path == "" && r.Method == "POST":
+		var record digitalOceanRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		if record.Type == "" || record.Name == "" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"id": "unprocessable_entity", "message": "Name can't be blank"})
+			return
+		}
+		s.nextID++
+		record.ID = s.nextID
+		s.records = append(s.records, record)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(digitalOceanRecordResponse{Record: record})
+
+	default:
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/"), 10, 64)
+		for i := range s.records {
+			if s.records[i].ID != id {
+				continue
+			}
+			switch r.Method {
+			case "PATCH":
+				var update digitalOceanRecord
+				json.NewDecoder(r.Body).Decode(&update)
+				s.records[i].Data = update.Data
+				json.NewEncoder(w).Encode(digitalOceanRecordResponse{Record: s.records[i]})
+			case "DELETE":
+				s.records = append(s.records[:i], s.records[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"id": "not_found", "message": "The resource you were accessing could not be found."})
+	}
+}
+
+// TestDigitalOceanPaging checks that records on later pages are found by
+// following the next page links
+func TestDigitalOceanPaging(t *testing.T) {
+	standIn := &digitalOceanStandIn{nextID: 5, records: []digitalOceanRecord{
+		{ID: 1, Type: "A", Name: "www", Data: "192.0.2.1", TTL: 600},
+		{ID: 2, Type: "AAAA", Name: "www", Data: "2001:db8::1", TTL: 600},
+		{ID: 3, Type: "A", Name: "www", Data: "192.0.2.2", TTL: 600},
+		{ID: 4, Type: "A", Name: "@", Data: "192.0.2.3", TTL: 600},
+		{ID: 5, Type: "A", Name: "www", Data: "192.0.2.4", TTL: 600},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newDigitalOceanProvider("token", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	want := []DNSRecord{{RecordID: "1", Value: "192.0.2.1"}, {RecordID: "3", Value: "192.0.2.2"}, {RecordID: "5", Value: "192.0.2.4"}}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("GetRecords returned %v, want %v", records, want)
+	}
+}
+
+// TestDigitalOceanErrors checks that error IDs and messages reach the
+// caller
+func TestDigitalOceanErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc((&digitalOceanStandIn{}).serve))
+	defer server.Close()
+
+	p, err := newDigitalOceanProvider("wrong", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.GetRecords("example.com", "www", "A")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Unauthorized - Unable to authenticate you") {
+		t.Errorf("got error %v, want a permanent authentication error", err)
+	}
+}
@@ -1,11 +1,14 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	dnspod "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/dnspod/v20210323"
 )
@@ -15,12 +18,24 @@ type DNSPodProvider struct {
 	client *dnspod.Client
 }
 
-// newDNSPodProvider creates a new DNSPod provider instance
-func newDNSPodProvider(secretID, secretKey string) (*DNSPodProvider, error) {
+// newDNSPodProvider creates a new DNSPod provider instance. endpoint
+// overrides the API URL.
+func newDNSPodProvider(secretID, secretKey, endpoint string) (*DNSPodProvider, error) {
 	credential := common.NewCredential(secretID, secretKey)
 	cpf := profile.NewClientProfile()
 	cpf.HttpProfile.Endpoint = "dnspod.tencentcloudapi.com"
 	cpf.HttpProfile.ReqTimeout = 5 // 5 second timeout
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid DNSPod API endpoint %q", endpoint)
+		}
+		// The SDK takes the host and scheme separately and always posts to "/"
+		cpf.HttpProfile.Endpoint = u.Host
+		if u.Scheme == "http" {
+			cpf.HttpProfile.Scheme = "HTTP"
+		}
+	}
 
 	client, err := dnspod.NewClient(credential, "", cpf)
 	if err != nil {
@@ -32,8 +47,8 @@ func newDNSPodProvider(secretID, secretKey string) (*DNSPodProvider, error) {
 	}, nil
 }
 
-// GetRecord retrieves an existing DNS record
-func (d *DNSPodProvider) GetRecord(domain, subdomain, recordType string) (*DNSRecord, error) {
+// GetRecords retrieves the existing DNS records
+func (d *DNSPodProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
 	req := dnspod.NewDescribeRecordListRequest()
 	req.Domain = common.StringPtr(domain)
 	req.Subdomain = common.StringPtr(subdomain)
@@ -47,18 +62,17 @@ func (d *DNSPodProvider) GetRecord(domain, subdomain, recordType string) (*DNSRe
 			strings.Contains(err.Error(), "RecordListEmpty") {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("API request failed: %v", err)
+		return nil, classifyDNSPodError(err)
 	}
 
-	if len(resp.Response.RecordList) == 0 {
-		return nil, nil
+	var records []DNSRecord
+	for _, record := range resp.Response.RecordList {
+		records = append(records, DNSRecord{
+			RecordID: fmt.Sprintf("%d", *record.RecordId),
+			Value:    *record.Value,
+		})
 	}
-
-	firstRecord := resp.Response.RecordList[0]
-	return &DNSRecord{
-		RecordID: fmt.Sprintf("%d", *firstRecord.RecordId),
-		Value:    *firstRecord.Value,
-	}, nil
+	return records, nil
 }
 
 // CreateRecord creates a new DNS record
@@ -72,7 +86,7 @@ func (d *DNSPodProvider) CreateRecord(domain, subdomain, recordType, value strin
 
 	resp, err := d.client.CreateRecord(req)
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %v", err)
+		return "", classifyDNSPodError(err)
 	}
 
 	return fmt.Sprintf("%d", *resp.Response.RecordId), nil
@@ -82,7 +96,7 @@ func (d *DNSPodProvider) CreateRecord(domain, subdomain, recordType, value strin
 func (d *DNSPodProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
 	recordIDUint, err := strconv.ParseUint(recordID, 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid record ID: %v", err)
+		return permanentError(fmt.Errorf("invalid record ID: %v", err))
 	}
 
 	req := dnspod.NewModifyRecordRequest()
@@ -95,8 +109,48 @@ func (d *DNSPodProvider) UpdateRecord(recordID, domain, subdomain, recordType, v
 
 	_, err = d.client.ModifyRecord(req)
 	if err != nil {
-		return fmt.Errorf("API request failed: %v", err)
+		return classifyDNSPodError(err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// DeleteRecord deletes an existing DNS record
+func (d *DNSPodProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	recordIDUint, err := strconv.ParseUint(recordID, 10, 64)
+	if err != nil {
+		return permanentError(fmt.Errorf("invalid record ID: %v", err))
+	}
+
+	req := dnspod.NewDeleteRecordRequest()
+	req.Domain = common.StringPtr(domain)
+	req.RecordId = common.Uint64Ptr(recordIDUint)
+
+	_, err = d.client.DeleteRecord(req)
+	if err != nil {
+		return classifyDNSPodError(err)
+	}
+
+	return nil
+}
+
+// classifyDNSPodError wraps an SDK error, marking rate limiting, internal
+// and network errors as retryable
+func classifyDNSPodError(err error) error {
+	wrapped := fmt.Errorf("API request failed: %v", err)
+
+	var sdkErr *tcerr.TencentCloudSDKError
+	if !errors.As(err, &sdkErr) {
+		return requestError(err)
+	}
+
+	code := sdkErr.GetCode()
+	if strings.HasPrefix(code, "RequestLimitExceeded") ||
+		strings.HasPrefix(code, "InternalError") ||
+		code == "ClientError.NetworkError" ||
+		code == "ClientError.CircuitBreakerError" ||
+		code == "ResourceUnavailable" {
+		return retryableError(wrapped, 0)
+	}
+	return permanentError(wrapped)
+}
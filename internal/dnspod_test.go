@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dnspodRecord is a record as the DNSPod API lists it
+type dnspodRecord struct {
+	RecordId uint64
+	Name     string
+	Type     string
+	Value    string
+	Line     string
+}
+
+// dnspodStandIn is a minimal DNSPod API serving the example.com domain. It
+// checks TC3-HMAC-SHA256 signatures and reports errors with HTTP 200 like
+// the real API.
+type dnspodStandIn struct {
+	mu      sync.Mutex
+	records []dnspodRecord
+	nextID  uint64
+}
+
+func (s *dnspodStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if !s.signatureValid(r, body) {
+		s.fail(w, "AuthFailure.SignatureFailure", "The provided credentials could not be validated.")
+		return
+	}
+
+	var req struct {
+		Domain     string
+		Subdomain  string
+		SubDomain  string
+		RecordType string
+		RecordLine string
+		Value      string
+		RecordId   uint64
+	}
+	json.Unmarshal(body, &req)
+	if req.Domain != "example.com" {
+		s.fail(w, "ResourceNotFound.NoDataOfDomain", "当前域名有误，请返回重新操作。")
+		return
+	}
+
+	switch r.Header.Get("X-TC-Action") {
+	case "DescribeRecordList":
+		var list []dnspodRecord
+		for _, record := range s.records {
+			if record.Name == req.Subdomain && record.Type == req.RecordType {
+				list = append(list, record)
+			}
+		}
+		if len(list) == 0 {
+			s.fail(w, "ResourceNotFound.NoDataOfRecord", "记录列表为空。")
+			return
+		}
+		s.reply(w, map[string]interface{}{
+			"RecordCountInfo": map[string]int{"SubdomainCount": len(list), "ListCount": len(list), "TotalCount": len(list)},
+			"RecordList":      list,
+		})
+
+	case "CreateRecord":
+		for _, record := range s.records {
+			if record.Name == req.SubDomain && record.Type == req.RecordType && record.Value == req.Value {
+				s.fail(w, "InvalidParameter.DomainRecordExist", "记录已经存在，无需再次添加。")
+				return
+			}
+		}
+		s.nextID++
+		s.records = append(s.records, dnspodRecord{s.nextID, req.SubDomain, req.RecordType, req.Value, req.RecordLine})
+		s.reply(w, map[string]interface{}{"RecordId": s.nextID})
+
+	case "ModifyRecord", "DeleteRecord":
+		for i := range s.records {
+			if s.records[i].RecordId != req.RecordId {
+				continue
+			}
+			if r.Header.Get("X-TC-Action") == "DeleteRecord" {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+			} else {
+				s.records[i] = dnspodRecord{req.RecordId, req.SubDomain, req.RecordType, req.Value, req.RecordLine}
+			}
+			s.reply(w, map[string]interface{}{"RecordId": req.RecordId})
+			return
+		}
+		s.fail(w, "InvalidParameter.RecordIdInvalid", "记录编号错误。")
+
+	default:
+		s.fail(w, "InvalidAction", "The action does not exist.")
+	}
+}
+
+// signatureValid computes the TC3-HMAC-SHA256 signature of the request and
+// compares it with the one in the Authorization header
+func (s *dnspodStandIn) signatureValid(r *http.Request, body []byte) bool {
+	timestamp, err := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+	if err != nil {
+		return false
+	}
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	scope := date + "/dnspod/tc3_request"
+
+	canonical := strings.Join([]string{
+		r.Method, "/", "",
+		"content-type:" + r.Header.Get("Content-Type") + "\nhost:" + r.Host + "\n",
+		"content-type;host",
+		sha256Hex(body),
+	}, "\n")
+	stringToSign := strings.Join([]string{"TC3-HMAC-SHA256", strconv.FormatInt(timestamp, 10), scope, sha256Hex([]byte(canonical))}, "\n")
+
+	key := hmacSHA256([]byte("TC3dnspod-secret-key"), date)
+	key = hmacSHA256(key, "dnspod")
+	key = hmacSHA256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	want := fmt.Sprintf("TC3-HMAC-SHA256 Credential=AKIDEXAMPLE/%s, SignedHeaders=content-type;host, Signature=%s", scope, signature)
+	return r.Header.Get("Authorization") == want
+}
+
+func (s *dnspodStandIn) reply(w http.ResponseWriter, response map[string]interface{}) {
+	response["RequestId"] = "request-1"
+	json.NewEncoder(w).Encode(map[string]interface{}{"Response": response})
+}
+
+func (s *dnspodStandIn) fail(w http.ResponseWriter, code, message string) {
+	s.reply(w, map[string]interface{}{"Error": map[string]string{"Code": code, "Message": message}})
+}
+
+// dnspodThrottled answers like the API does when the request rate is
+// exceeded
+func dnspodThrottled(w http.ResponseWriter, r *http.Request) {
+	(&dnspodStandIn{}).fail(w, "RequestLimitExceeded", "请求的次数超过了频率限制。")
+}
+
+// TestDNSPodErrors checks that API errors are reported with their codes
+// and classified
+func TestDNSPodErrors(t *testing.T) {
+	standIn := &dnspodStandIn{nextID: 1, records: []dnspodRecord{{1, "www", "A", "192.0.2.1", "默认"}}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newDNSPodProvider("AKIDEXAMPLE", "dnspod-secret-key", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "InvalidParameter.DomainRecordExist") {
+		t.Errorf("got error %v, want a permanent duplicate record error", err)
+	}
+
+	p, _ = newDNSPodProvider("AKIDEXAMPLE", "wrong-secret", server.URL)
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "AuthFailure.SignatureFailure") {
+		t.Errorf("got error %v, want a permanent signature error", err)
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(dnspodThrottled))
+	t.Cleanup(server.Close)
+	p, _ = newDNSPodProvider("AKIDEXAMPLE", "dnspod-secret-key", server.URL)
+	if _, err := p.GetRecords("example.com", "www", "A"); !IsRetryable(err) {
+		t.Errorf("got error %v, want a retryable rate limit error", err)
+	}
+}
+
+// TestDNSPodEndpoint checks that an endpoint without a host is refused
+func TestDNSPodEndpoint(t *testing.T) {
+	if _, err := newDNSPodProvider("AKIDEXAMPLE", "dnspod-secret-key", "dnspod.example.net"); err == nil {
+		t.Error("newDNSPodProvider accepted an endpoint without a scheme")
+	}
+}
@@ -0,0 +1,567 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNS wire-format constants used by the RFC 2136 client
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypeNS   uint16 = 2
+	dnsTypeSOA  uint16 = 6
+	dnsTypeAAAA uint16 = 28
+	dnsTypeTSIG uint16 = 250
+
+	dnsClassIN   uint16 = 1
+	dnsClassNONE uint16 = 254
+	dnsClassANY  uint16 = 255
+
+	dnsOpcodeQuery  = 0
+	dnsOpcodeUpdate = 5
+
+	dnsFlagResponse  uint16 = 1 << 15
+	dnsFlagTruncated uint16 = 1 << 9
+)
+
+// DNS response codes
+const (
+	dnsRcodeSuccess  = 0
+	dnsRcodeServFail = 2
+	dnsRcodeNXDomain = 3
+)
+
+var dnsRcodeNames = map[int]string{
+	0: "NOERROR", 1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+	16: "BADSIG", 17: "BADKEY", 18: "BADTIME",
+}
+
+// dnsRcodeName returns the mnemonic for a response code
+func dnsRcodeName(rcode int) string {
+	if name, ok := dnsRcodeNames[rcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// dnsQuestion is an entry of the question (or UPDATE zone) section
+type dnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// dnsRR is a resource record. Names inside the RDATA of NS and SOA records
+// are stored uncompressed.
+type dnsRR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+// dnsMessage is a DNS message. For UPDATE messages the sections are the
+// zone, prerequisite, update and additional sections.
+type dnsMessage struct {
+	ID         uint16
+	Flags      uint16
+	Question   []dnsQuestion
+	Answer     []dnsRR
+	Authority  []dnsRR
+	Additional []dnsRR
+
+	// tsigOffset is the wire offset of a trailing TSIG record, or -1
+	tsigOffset int
+}
+
+// newDNSMessage creates a message with a random ID and the given opcode
+func newDNSMessage(opcode int) *dnsMessage {
+	return &dnsMessage{
+		ID:         uint16(rand.Intn(1 << 16)),
+		Flags:      uint16(opcode&0xF) << 11,
+		tsigOffset: -1,
+	}
+}
+
+// rcode returns the response code of the message
+func (m *dnsMessage) rcode() int {
+	return int(m.Flags & 0xF)
+}
+
+// pack encodes the message in wire format without name compression
+func (m *dnsMessage) pack() ([]byte, error) {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	binary.BigEndian.PutUint16(b[2:], m.Flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Question)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answer)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.Authority)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additional)))
+
+	var err error
+	for _, q := range m.Question {
+		if b, err = appendDNSName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, q.Class)
+	}
+	for _, section := range [][]dnsRR{m.Answer, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if b, err = appendDNSRR(b, rr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// appendDNSRR appends a resource record in wire format
+func appendDNSRR(b []byte, rr dnsRR) ([]byte, error) {
+	b, err := appendDNSName(b, rr.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(rr.Data) > 0xFFFF {
+		return nil, fmt.Errorf("record data too long")
+	}
+	b = binary.BigEndian.AppendUint16(b, rr.Type)
+	b = binary.BigEndian.AppendUint16(b, rr.Class)
+	b = binary.BigEndian.AppendUint32(b, rr.TTL)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rr.Data)))
+	return append(b, rr.Data...), nil
+}
+
+// appendDNSName appends name as an uncompressed sequence of labels
+func appendDNSName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return append(b, 0), nil
+	}
+	if len(name) > 253 {
+		return nil, fmt.Errorf("domain name too long: %s", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain name: %s", name)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+// unpackDNSMessage decodes a wire-format message
+func unpackDNSMessage(msg []byte) (*dnsMessage, error) {
+	if len(msg) < 12 {
+		return nil, fmt.Errorf("DNS message too short")
+	}
+	m := &dnsMessage{
+		ID:         binary.BigEndian.Uint16(msg[0:]),
+		Flags:      binary.BigEndian.Uint16(msg[2:]),
+		tsigOffset: -1,
+	}
+	counts := [4]int{
+		int(binary.BigEndian.Uint16(msg[4:])),
+		int(binary.BigEndian.Uint16(msg[6:])),
+		int(binary.BigEndian.Uint16(msg[8:])),
+		int(binary.BigEndian.Uint16(msg[10:])),
+	}
+
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(msg) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		m.Question = append(m.Question, dnsQuestion{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(msg[next:]),
+			Class: binary.BigEndian.Uint16(msg[next+2:]),
+		})
+		off = next + 4
+	}
+
+	sections := []*[]dnsRR{&m.Answer, &m.Authority, &m.Additional}
+	for s, section := range sections {
+		for i := 0; i < counts[s+1]; i++ {
+			start := off
+			rr, next, err := readDNSRR(msg, off)
+			if err != nil {
+				return nil, err
+			}
+			if s == 2 && i == counts[3]-1 && rr.Type == dnsTypeTSIG {
+				m.tsigOffset = start
+			}
+			*section = append(*section, rr)
+			off = next
+		}
+	}
+	return m, nil
+}
+
+// readDNSRR decodes the resource record at off
+func readDNSRR(msg []byte, off int) (dnsRR, int, error) {
+	name, off, err := readDNSName(msg, off)
+	if err != nil {
+		return dnsRR{}, 0, err
+	}
+	if off+10 > len(msg) {
+		return dnsRR{}, 0, io.ErrUnexpectedEOF
+	}
+	rr := dnsRR{
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(msg[off:]),
+		Class: binary.BigEndian.Uint16(msg[off+2:]),
+		TTL:   binary.BigEndian.Uint32(msg[off+4:]),
+	}
+	length := int(binary.BigEndian.Uint16(msg[off+8:]))
+	off += 10
+	if off+length > len(msg) {
+		return dnsRR{}, 0, io.ErrUnexpectedEOF
+	}
+	end := off + length
+
+	switch rr.Type {
+	case dnsTypeNS:
+		host, _, err := readDNSName(msg, off)
+		if err != nil {
+			return dnsRR{}, 0, err
+		}
+		rr.Data, _ = appendDNSName(nil, host)
+	case dnsTypeSOA:
+		mname, next, err := readDNSName(msg, off)
+		if err != nil {
+			return dnsRR{}, 0, err
+		}
+		rname, next, err := readDNSName(msg, next)
+		if err != nil {
+			return dnsRR{}, 0, err
+		}
+		if next+20 > end {
+			return dnsRR{}, 0, io.ErrUnexpectedEOF
+		}
+		rr.Data, _ = appendDNSName(nil, mname)
+		rr.Data, _ = appendDNSName(rr.Data, rname)
+		rr.Data = append(rr.Data, msg[next:next+20]...)
+	default:
+		rr.Data = append([]byte(nil), msg[off:end]...)
+	}
+	return rr, end, nil
+}
+
+// readDNSName decodes a possibly compressed name at off and returns it with
+// the offset following it
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, io.ErrUnexpectedEOF
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(msg) {
+				return "", 0, io.ErrUnexpectedEOF
+			}
+			if jumps++; jumps > 32 {
+				return "", 0, fmt.Errorf("too many compression pointers")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+		case length <= 63:
+			if off+1+length > len(msg) {
+				return "", 0, io.ErrUnexpectedEOF
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		default:
+			return "", 0, fmt.Errorf("invalid label length %d", length)
+		}
+	}
+}
+
+// dnsIPRData encodes an A or AAAA value as record data
+func dnsIPRData(recordType, value string) ([]byte, error) {
+	ip := net.ParseIP(value)
+	switch {
+	case ip == nil:
+		return nil, fmt.Errorf("invalid IP address: %s", value)
+	case recordType == "A" && ip.To4() != nil:
+		return ip.To4(), nil
+	case recordType == "AAAA" && ip.To4() == nil:
+		return ip.To16(), nil
+	default:
+		return nil, fmt.Errorf("%s is not a valid %s value", value, recordType)
+	}
+}
+
+// dnsRecordType returns the wire type for an A or AAAA record type
+func dnsRecordType(recordType string) (uint16, error) {
+	switch recordType {
+	case "A":
+		return dnsTypeA, nil
+	case "AAAA":
+		return dnsTypeAAAA, nil
+	default:
+		return 0, fmt.Errorf("unsupported record type: %s", recordType)
+	}
+}
+
+// soaPrimary returns the MNAME field of SOA record data
+func soaPrimary(data []byte) (string, error) {
+	name, _, err := readDNSName(data, 0)
+	return name, err
+}
+
+// tsigKey is a TSIG key used to sign requests and verify responses (RFC 8945)
+type tsigKey struct {
+	name      string
+	algorithm string
+	secret    []byte
+	newHash   func() hash.Hash
+}
+
+// tsigFudge is the permitted clock skew in seconds
+const tsigFudge = 300
+
+// newTSIGKey creates a TSIG key for hmac-sha256 or hmac-sha512
+func newTSIGKey(name, algorithm string, secret []byte) (*tsigKey, error) {
+	key := &tsigKey{
+		name:      strings.ToLower(strings.TrimSuffix(name, ".")),
+		algorithm: strings.ToLower(strings.TrimSuffix(algorithm, ".")),
+		secret:    secret,
+	}
+	switch key.algorithm {
+	case "hmac-sha256":
+		key.newHash = sha256.New
+	case "hmac-sha512":
+		key.newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported TSIG algorithm: %s", algorithm)
+	}
+	return key, nil
+}
+
+// tsigVariables encodes the TSIG variables covered by the MAC
+func (k *tsigKey) tsigVariables(timeSigned uint64, tsigError uint16, other []byte) []byte {
+	b, _ := appendDNSName(nil, k.name)
+	b = binary.BigEndian.AppendUint16(b, dnsClassANY)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b, _ = appendDNSName(b, k.algorithm)
+	b = appendUint48(b, timeSigned)
+	b = binary.BigEndian.AppendUint16(b, tsigFudge)
+	b = binary.BigEndian.AppendUint16(b, tsigError)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(other)))
+	return append(b, other...)
+}
+
+// sign packs m and appends a TSIG record. It returns the signed message and
+// the request MAC needed to verify the response.
+func (k *tsigKey) sign(m *dnsMessage, now time.Time) ([]byte, []byte, error) {
+	wire, err := m.pack()
+	if err != nil {
+		return nil, nil, err
+	}
+	timeSigned := uint64(now.Unix())
+
+	mac := hmac.New(k.newHash, k.secret)
+	mac.Write(wire)
+	mac.Write(k.tsigVariables(timeSigned, 0, nil))
+	sum := mac.Sum(nil)
+
+	data, _ := appendDNSName(nil, k.algorithm)
+	data = appendUint48(data, timeSigned)
+	data = binary.BigEndian.AppendUint16(data, tsigFudge)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(sum)))
+	data = append(data, sum...)
+	data = binary.BigEndian.AppendUint16(data, m.ID)
+	data = binary.BigEndian.AppendUint16(data, 0)
+	data = binary.BigEndian.AppendUint16(data, 0)
+
+	wire, err = appendDNSRR(wire, dnsRR{Name: k.name, Type: dnsTypeTSIG, Class: dnsClassANY, Data: data})
+	if err != nil {
+		return nil, nil, err
+	}
+	binary.BigEndian.PutUint16(wire[10:], uint16(len(m.Additional)+1))
+	return wire, sum, nil
+}
+
+// tsigRecord holds the decoded RDATA of a TSIG record
+type tsigRecord struct {
+	algorithm  string
+	timeSigned uint64
+	fudge      uint16
+	mac        []byte
+	originalID uint16
+	err        uint16
+	other      []byte
+}
+
+// parseTSIG decodes TSIG record data
+func parseTSIG(data []byte) (*tsigRecord, error) {
+	alg, off, err := readDNSName(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if off+10 > len(data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	t := &tsigRecord{algorithm: strings.ToLower(alg)}
+	t.timeSigned = uint64(binary.BigEndian.Uint16(data[off:]))<<32 | uint64(binary.BigEndian.Uint32(data[off+2:]))
+	t.fudge = binary.BigEndian.Uint16(data[off+6:])
+	macLen := int(binary.BigEndian.Uint16(data[off+8:]))
+	off += 10
+	if off+macLen+6 > len(data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	t.mac = data[off : off+macLen]
+	off += macLen
+	t.originalID = binary.BigEndian.Uint16(data[off:])
+	t.err = binary.BigEndian.Uint16(data[off+2:])
+	otherLen := int(binary.BigEndian.Uint16(data[off+4:]))
+	off += 6
+	if off+otherLen > len(data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	t.other = data[off : off+otherLen]
+	return t, nil
+}
+
+// verify checks the TSIG record of a response against the request MAC
+func (k *tsigKey) verify(wire []byte, m *dnsMessage, requestMAC []byte, now time.Time) error {
+	if m.tsigOffset < 0 {
+		return fmt.Errorf("response is not signed (%s)", dnsRcodeName(m.rcode()))
+	}
+	rr := m.Additional[len(m.Additional)-1]
+	t, err := parseTSIG(rr.Data)
+	if err != nil {
+		return fmt.Errorf("invalid TSIG record: %v", err)
+	}
+	if t.err != 0 {
+		return fmt.Errorf("TSIG error %s", dnsRcodeName(int(t.err)))
+	}
+	if !strings.EqualFold(strings.TrimSuffix(rr.Name, "."), k.name) || t.algorithm != k.algorithm {
+		return fmt.Errorf("response signed with unexpected key %s (%s)", rr.Name, t.algorithm)
+	}
+
+	unsigned := append([]byte(nil), wire[:m.tsigOffset]...)
+	binary.BigEndian.PutUint16(unsigned[0:], t.originalID)
+	binary.BigEndian.PutUint16(unsigned[10:], uint16(len(m.Additional)-1))
+
+	mac := hmac.New(k.newHash, k.secret)
+	mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC))))
+	mac.Write(requestMAC)
+	mac.Write(unsigned)
+	mac.Write(k.tsigVariables(t.timeSigned, t.err, t.other))
+	if !hmac.Equal(mac.Sum(nil), t.mac) {
+		return fmt.Errorf("TSIG signature mismatch")
+	}
+
+	skew := now.Unix() - int64(t.timeSigned)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > int64(t.fudge) {
+		return fmt.Errorf("TSIG time outside fudge window")
+	}
+	return nil
+}
+
+// appendUint48 appends the low 48 bits of v in network byte order
+func appendUint48(b []byte, v uint64) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(v>>32))
+	return binary.BigEndian.AppendUint32(b, uint32(v))
+}
+
+// dnsExchange sends wire to server and returns the raw response. UDP
+// responses with the TC bit set are retried over TCP.
+func dnsExchange(server string, wire []byte, useTCP bool, timeout time.Duration) ([]byte, error) {
+	if !useTCP {
+		resp, err := dnsExchangeUDP(server, wire, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if binary.BigEndian.Uint16(resp[2:])&dnsFlagTruncated == 0 {
+			return resp, nil
+		}
+	}
+	return dnsExchangeTCP(server, wire, timeout)
+}
+
+// dnsExchangeUDP performs a single UDP round trip
+func dnsExchangeUDP(server string, wire []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(wire); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray datagrams that do not answer this query
+		if n >= 12 && buf[0] == wire[0] && buf[1] == wire[1] {
+			return append([]byte(nil), buf[:n]...), nil
+		}
+	}
+}
+
+// dnsExchangeTCP performs a single length-prefixed TCP round trip
+func dnsExchangeTCP(server string, wire []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := binary.BigEndian.AppendUint16(nil, uint16(len(wire)))
+	if _, err := conn.Write(append(req, wire...)); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 12 {
+		return nil, errors.New("DNS response too short")
+	}
+	return resp, nil
+}
@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"context"
+	"ddnsd/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dynDNSPreset describes a service speaking the dyndns2 protocol or a
+// close variant of it
+type dynDNSPreset struct {
+	server string
+	// ipv4Param and ipv6Param are the query parameters carrying addresses
+	ipv4Param string
+	ipv6Param string
+	// duckDNS selects the DuckDNS token protocol instead of dyndns2
+	duckDNS bool
+}
+
+// dynDNSPresets holds the known dyndns2-style services
+var dynDNSPresets = map[string]dynDNSPreset{
+	"noip":    {server: "https://dynupdate.no-ip.com/nic/update", ipv4Param: "myip", ipv6Param: "myip"},
+	"dynu":    {server: "https://api.dynu.com/nic/update", ipv4Param: "myip", ipv6Param: "myipv6"},
+	"duckdns": {server: "https://www.duckdns.org/update", ipv4Param: "ip", ipv6Param: "ipv6", duckDNS: true},
+	"oray":    {server: "https://ddns.oray.com/ph/update", ipv4Param: "myip", ipv6Param: "myip"},
+}
+
+// DynDNSProvider implements DNSProvider for services speaking the dyndns2
+// update protocol. These services have no record IDs or read API, so the
+// current value comes from the last update sent or, failing that, from
+// resolving the name.
+type DynDNSProvider struct {
+	username string
+	password string
+	preset   dynDNSPreset
+	client   *http.Client
+
+	mu    sync.Mutex
+	state map[string]string
+	// rejections stop further updates, by hostname or, for the whole
+	// account, under ""
+	rejections map[string]*dynDNSRejection
+}
+
+// dynDNSRejection is a response after which the protocol forbids further
+// updates until the user has fixed the problem, since clients that keep
+// retrying get their accounts blocked
+type dynDNSRejection struct {
+	text    string
+	account bool // the problem concerns the account rather than the host
+}
+
+func (e *dynDNSRejection) Error() string {
+	return fmt.Sprintf("update rejected: %s", e.text)
+}
+
+// newDynDNSProvider creates a new dyndns2 provider instance. name selects a
+// preset, or "dyndns2" for a generic server; endpoint overrides the server
+// URL and is required for the generic provider.
+func newDynDNSProvider(name, username, password, endpoint string) (*DynDNSProvider, error) {
+	preset, ok := dynDNSPresets[name]
+	if !ok {
+		preset = dynDNSPreset{ipv4Param: "myip", ipv6Param: "myip"}
+	}
+	if endpoint != "" {
+		preset.server = endpoint
+	}
+	if preset.server == "" {
+		return nil, fmt.Errorf("API_ENDPOINT (update server URL) must be set for %s", name)
+	}
+
+	return &DynDNSProvider{
+		username: username,
+		password: password,
+		preset:   preset,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		state:      make(map[string]string),
+		rejections: make(map[string]*dynDNSRejection),
+	}, nil
+}
+
+// GetRecords returns the last value sent for the record or, before the first
+// update, the address the name currently resolves to. The services hold a
+// single address per name, so at most one record is returned. The fully
+// qualified name doubles as the record ID.
+func (d *DynDNSProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	hostname := recordFullName(domain, subdomain)
+
+	d.mu.Lock()
+	value, ok := d.state[recordType+" "+hostname]
+	d.mu.Unlock()
+	if ok {
+		return []DNSRecord{{RecordID: hostname, Value: value}}, nil
+	}
+
+	network := "ip4"
+	if recordType == "AAAA" {
+		network = "ip6"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, hostname)
+	if err != nil || len(ips) == 0 {
+		// An unresolvable name is simply updated
+		return nil, nil
+	}
+
+	return []DNSRecord{{RecordID: hostname, Value: ips[0].String()}}, nil
+}
+
+// CreateRecord sends an update for the name. The services create names on
+// first update or require them to be registered beforehand.
+func (d *DynDNSProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := d.update(domain, subdomain, recordType, value); err != nil {
+		return "", err
+	}
+	return recordFullName(domain, subdomain), nil
+}
+
+// UpdateRecord sends an update for the name
+func (d *DynDNSProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return d.update(domain, subdomain, recordType, value)
+}
+
+// DeleteRecord is not supported by the update protocol
+func (d *DynDNSProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return permanentError(fmt.Errorf("the dyndns2 protocol does not support deleting records"))
+}
+
+// update sends the update request and records the value on success. After
+// a rejection no more requests are sent for the host or account.
+func (d *DynDNSProvider) update(domain, subdomain, recordType, value string) error {
+	hostname := recordFullName(domain, subdomain)
+	if err := d.rejected(hostname); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if d.preset.duckDNS {
+		query.Set("domains", subdomain)
+		query.Set("token", d.password)
+	} else {
+		query.Set("hostname", hostname)
+	}
+	if recordType == "AAAA" {
+		query.Set(d.preset.ipv6Param, value)
+	} else {
+		query.Set(d.preset.ipv4Param, value)
+	}
+
+	req, err := http.NewRequest("GET", d.preset.server+"?"+query.Encode(), nil)
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	if !d.preset.duckDNS {
+		req.SetBasicAuth(d.username, d.password)
+	}
+	req.Header.Set("User-Agent", "ddnsd/1.0")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	if d.preset.duckDNS {
+		err = parseDuckDNSResponse(resp, body)
+	} else {
+		err = parseDynDNSResponse(resp, body)
+	}
+	if err != nil {
+		var rejection *dynDNSRejection
+		if errors.As(err, &rejection) {
+			d.reject(hostname, rejection)
+		}
+		return err
+	}
+
+	d.mu.Lock()
+	d.state[recordType+" "+hostname] = value
+	d.mu.Unlock()
+	return nil
+}
+
+// rejected returns an error when updates for hostname have been stopped by
+// an earlier rejection
+func (d *DynDNSProvider) rejected(hostname string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rejection := d.rejections[""]
+	if rejection == nil {
+		rejection = d.rejections[hostname]
+	}
+	if rejection == nil {
+		return nil
+	}
+	return permanentError(fmt.Errorf("updates stopped after the server answered %q; fix the problem and restart ddnsd", rejection.text))
+}
+
+// reject stops further updates for hostname, or for the account, and logs
+// it once
+func (d *DynDNSProvider) reject(hostname string, rejection *dynDNSRejection) {
+	key, scope := hostname, hostname
+	if rejection.account {
+		key, scope = "", "any host"
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.rejections[key] != nil {
+		return
+	}
+	d.rejections[key] = rejection
+	utils.LogError("The update server answered %q; no more updates will be sent for %s until ddnsd is restarted", rejection.text, scope)
+}
+
+// parseDynDNSResponse classifies a dyndns2 return code. good and nochg are
+// successes; 911 and server errors are retryable; everything else, such as
+// badauth or abuse, is a rejection that needs user attention.
+func parseDynDNSResponse(resp *http.Response, body []byte) error {
+	text := strings.TrimSpace(string(body))
+	code, _, _ := strings.Cut(text, " ")
+
+	switch code {
+	case "good", "nochg":
+		return nil
+	case "911", "dnserr":
+		return retryableError(fmt.Errorf("update failed: %s", truncate(text, 200)), parseRetryAfter(resp.Header.Get("Retry-After")))
+	case "badauth", "abuse", "!donator", "badagent", "badsys":
+		return permanentError(&dynDNSRejection{text: truncate(text, 200), account: true})
+	case "notfqdn", "nohost", "numhost":
+		return permanentError(&dynDNSRejection{text: truncate(text, 200)})
+	}
+
+	if statusErr := statusError(resp, body); statusErr != nil {
+		return statusErr
+	}
+	return permanentError(fmt.Errorf("unexpected response: %s", truncate(text, 200)))
+}
+
+// parseDuckDNSResponse checks a DuckDNS update response, which is OK or KO
+func parseDuckDNSResponse(resp *http.Response, body []byte) error {
+	if statusErr := statusError(resp, body); statusErr != nil {
+		return statusErr
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "OK") {
+		return nil
+	}
+	return permanentError(&dynDNSRejection{text: "KO: check the DuckDNS token and domain", account: true})
+}
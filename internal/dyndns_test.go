@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// dynDNSStandIn is a minimal dyndns2 update server for names under
+// example.com, accepting the username "user" and password "pass". It
+// keeps one address per name and type.
+type dynDNSStandIn struct {
+	mu    sync.Mutex
+	hosts map[string]string
+}
+
+func (s *dynDNSStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+		fmt.Fprint(w, "badauth")
+		return
+	}
+	hostname, myip := r.URL.Query().Get("hostname"), r.URL.Query().Get("myip")
+	if hostname != "example.com" && !strings.HasSuffix(hostname, ".example.com") {
+		fmt.Fprint(w, "nohost")
+		return
+	}
+	ip := net.ParseIP(myip)
+	if ip == nil {
+		fmt.Fprint(w, "dnserr")
+		return
+	}
+
+	key := "A " + hostname
+	if ip.To4() == nil {
+		key = "AAAA " + hostname
+	}
+	if s.hosts[key] == myip {
+		fmt.Fprint(w, "nochg "+myip)
+		return
+	}
+	s.hosts[key] = myip
+	fmt.Fprint(w, "good "+myip)
+}
+
+// TestDynDNSUpdates checks that updates for both address families reach
+// the server and are returned by GetRecords afterwards
+func TestDynDNSUpdates(t *testing.T) {
+	standIn := &dynDNSStandIn{hosts: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newDynDNSProvider("dyndns2", "user", "pass", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, update := range [][2]string{{"A", "192.0.2.1"}, {"AAAA", "2001:db8::1"}, {"A", "192.0.2.1"}} {
+		if err := p.UpdateRecord("", "example.com", "home", update[0], update[1]); err != nil {
+			t.Fatalf("UpdateRecord %s %s: %v", update[0], update[1], err)
+		}
+		records, err := p.GetRecords("example.com", "home", update[0])
+		if err != nil || len(records) != 1 || records[0].Value != update[1] {
+			t.Errorf("GetRecords %s returned %v, %v; want %s", update[0], records, err, update[1])
+		}
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := map[string]string{"A home.example.com": "192.0.2.1", "AAAA home.example.com": "2001:db8::1"}
+	if fmt.Sprint(standIn.hosts) != fmt.Sprint(want) {
+		t.Errorf("server holds %v, want %v", standIn.hosts, want)
+	}
+}
+
+// TestDynDNSRejectionStopsUpdates checks that no request is sent after a
+// response the protocol forbids retrying
+func TestDynDNSRejectionStopsUpdates(t *testing.T) {
+	tests := []struct {
+		response  string
+		otherHost bool // updates for other hosts continue
+	}{
+		{"badauth", false},
+		{"abuse", false},
+		{"!donator", false},
+		{"nohost", true},
+		{"notfqdn", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if r.URL.Query().Get("hostname") == "other.example.com" {
+					fmt.Fprint(w, "good 192.0.2.1")
+					return
+				}
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			p, err := newDynDNSProvider("dyndns2", "user", "pass", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 3; i++ {
+				err := p.UpdateRecord("", "example.com", "home", "A", "192.0.2.1")
+				if err == nil || IsRetryable(err) {
+					t.Fatalf("update %d: got error %v, want a permanent error", i, err)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("server got %d requests, want 1", requests)
+			}
+
+			err = p.UpdateRecord("", "example.com", "other", "A", "192.0.2.1")
+			if tt.otherHost && err != nil {
+				t.Errorf("update for another host: %v", err)
+			}
+			if !tt.otherHost && err == nil {
+				t.Errorf("update for another host succeeded after an account rejection")
+			}
+		})
+	}
+}
+
+// TestDynDNSRetryableResponses checks that server trouble is retried and
+// does not stop updates
+func TestDynDNSRetryableResponses(t *testing.T) {
+	responses := []string{"911", "good 192.0.2.1"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, responses[0])
+		responses = responses[1:]
+	}))
+	defer server.Close()
+
+	p, err := newDynDNSProvider("dyndns2", "user", "pass", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateRecord("", "example.com", "home", "A", "192.0.2.1"); !IsRetryable(err) {
+		t.Fatalf("911: got error %v, want a retryable error", err)
+	}
+	if err := p.UpdateRecord("", "example.com", "home", "A", "192.0.2.1"); err != nil {
+		t.Fatalf("update after 911: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"ddnsd/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execTempFail is the exit status (EX_TEMPFAIL) an exec provider program
+// uses to report a transient failure worth retrying
+const execTempFail = 75
+
+// ExecProvider implements DNSProvider by running an external program for
+// each operation. The program reads a JSON request on stdin and writes a
+// JSON response on stdout.
+type ExecProvider struct {
+	command string
+	timeout time.Duration
+	ttl     int
+	log     *utils.Logger
+}
+
+// execRequest is written to the program's stdin
+type execRequest struct {
+	Action    string `json:"action"`
+	Domain    string `json:"domain"`
+	Subdomain string `json:"subdomain"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Value     string `json:"value,omitempty"`
+	RecordID  string `json:"record_id,omitempty"`
+	TTL       int    `json:"ttl"`
+}
+
+// execRecord is a record in a program response
+type execRecord struct {
+	RecordID string `json:"record_id"`
+	Value    string `json:"value"`
+}
+
+// execResponse is read from the program's stdout. get may answer with a
+// single record or with several in records.
+type execResponse struct {
+	execRecord
+	Records []execRecord `json:"records"`
+}
+
+// newExecProvider creates a new exec provider instance. command is run
+// through the shell and killed after timeout.
+func newExecProvider(command string, timeout time.Duration, ttl int, log *utils.Logger) (*ExecProvider, error) {
+	if command == "" {
+		return nil, fmt.Errorf("EXEC_COMMAND must be set for exec")
+	}
+	return &ExecProvider{command: command, timeout: timeout, ttl: ttl, log: log}, nil
+}
+
+// GetRecords runs the get action. An empty response or value means there
+// is no record; without a record ID the value doubles as the ID.
+func (e *ExecProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	resp, err := e.run(e.request("get", domain, subdomain, recordType, "", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	for _, record := range append([]execRecord{resp.execRecord}, resp.Records...) {
+		if record.Value == "" {
+			continue
+		}
+		if record.RecordID == "" {
+			record.RecordID = record.Value
+		}
+		records = append(records, DNSRecord{RecordID: record.RecordID, Value: record.Value})
+	}
+	return records, nil
+}
+
+// CreateRecord runs the create action
+func (e *ExecProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	resp, err := e.run(e.request("create", domain, subdomain, recordType, "", value))
+	if err != nil {
+		return "", err
+	}
+
+	if resp.RecordID == "" {
+		return value, nil
+	}
+	return resp.RecordID, nil
+}
+
+// UpdateRecord runs the update action
+func (e *ExecProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	_, err := e.run(e.request("update", domain, subdomain, recordType, recordID, value))
+	return err
+}
+
+// DeleteRecord runs the delete action
+func (e *ExecProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	_, err := e.run(e.request("delete", domain, subdomain, recordType, recordID, ""))
+	return err
+}
+
+// request builds the request for an action
+func (e *ExecProvider) request(action, domain, subdomain, recordType, recordID, value string) execRequest {
+	return execRequest{
+		Action:    action,
+		Domain:    domain,
+		Subdomain: subdomain,
+		Name:      recordFullName(domain, subdomain),
+		Type:      recordType,
+		Value:     value,
+		RecordID:  recordID,
+		TTL:       e.ttl,
+	}
+}
+
+// run executes the program with the request on stdin. stderr is logged and
+// its last line included in errors. Timeouts and exit status 75 are
+// retryable; other failures are permanent.
+func (e *ExecProvider) run(request execRequest) (execResponse, error) {
+	var resp execResponse
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return resp, permanentError(fmt.Errorf("failed to marshal request: %v", err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", e.command)
+	cmd.Env = append(os.Environ(), "DDNSD_ACTION="+request.Action)
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	lastLine := ""
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			e.log.Info("[%s] %s", request.Action, line)
+			lastLine = line
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return resp, retryableError(fmt.Errorf("%s timed out after %v", request.Action, e.timeout), 0)
+	}
+	if err != nil {
+		if lastLine != "" {
+			err = fmt.Errorf("%v: %s", err, lastLine)
+		}
+		err = fmt.Errorf("%s failed: %v", request.Action, err)
+
+		if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() == execTempFail {
+			return resp, retryableError(err, 0)
+		}
+		return resp, permanentError(err)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return resp, nil
+	}
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return resp, permanentError(fmt.Errorf("%s returned invalid JSON: %v", request.Action, err))
+	}
+	return resp, nil
+}
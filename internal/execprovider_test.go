@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ddnsd/config"
+)
+
+// execStoreEnv names the directory where the exec backend, the test binary
+// run as a helper process, keeps its records
+const execStoreEnv = "DDNSD_TEST_EXEC_STORE"
+
+// execStoredRecord is a record kept by the exec backend
+type execStoredRecord struct {
+	ID    int
+	Name  string
+	Type  string
+	Value string
+}
+
+// TestExecHelperProcess is not a real test. It is the exec backend used by
+// the exec provider tests, started as EXEC_COMMAND.
+func TestExecHelperProcess(t *testing.T) {
+	dir := os.Getenv(execStoreEnv)
+	if dir == "" {
+		return
+	}
+	os.Exit(runExecBackend(dir))
+}
+
+// runExecBackend answers one exec provider request from stdin, keeping the
+// records in dir, and returns the exit status
+func runExecBackend(dir string) int {
+	if failing, _ := os.ReadFile(filepath.Join(dir, "failing")); len(failing) > 0 {
+		n, _ := strconv.Atoi(string(failing))
+		os.WriteFile(filepath.Join(dir, "failing"), []byte(strconv.Itoa(n-1)), 0644)
+		if n > 0 {
+			fmt.Fprintln(os.Stderr, "backend temporarily unavailable")
+			return execTempFail
+		}
+	}
+
+	var req execRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid request: %v\n", err)
+		return 1
+	}
+	if req.Action != os.Getenv("DDNSD_ACTION") {
+		fmt.Fprintf(os.Stderr, "action %q does not match DDNSD_ACTION %q\n", req.Action, os.Getenv("DDNSD_ACTION"))
+		return 1
+	}
+
+	var records []execStoredRecord
+	data, _ := os.ReadFile(filepath.Join(dir, "records.json"))
+	json.Unmarshal(data, &records)
+
+	var resp execResponse
+	switch req.Action {
+	case "get":
+		for _, record := range records {
+			if record.Name == req.Name && record.Type == req.Type {
+				resp.Records = append(resp.Records, execRecord{RecordID: strconv.Itoa(record.ID), Value: record.Value})
+			}
+		}
+	case "create":
+		id := 1
+		for _, record := range records {
+			if record.ID >= id {
+				id = record.ID + 1
+			}
+		}
+		records = append(records, execStoredRecord{id, req.Name, req.Type, req.Value})
+		resp.RecordID = strconv.Itoa(id)
+	case "update", "delete":
+		i := 0
+		for i < len(records) && strconv.Itoa(records[i].ID) != req.RecordID {
+			i++
+		}
+		if i == len(records) {
+			fmt.Fprintf(os.Stderr, "record %s not found\n", req.RecordID)
+			return 1
+		}
+		if req.Action == "delete" {
+			records = append(records[:i], records[i+1:]...)
+		} else {
+			records[i].Value = req.Value
+		}
+	}
+
+	data, _ = json.Marshal(records)
+	if err := os.WriteFile(filepath.Join(dir, "records.json"), data, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+	return 0
+}
+
+// execBackend is the store of the exec backend
+type execBackend struct {
+	dir string
+}
+
+// FailNext makes the next n runs of the backend exit with EX_TEMPFAIL
+func (b execBackend) FailNext(n int) {
+	os.WriteFile(filepath.Join(b.dir, "failing"), []byte(strconv.Itoa(n)), 0644)
+}
+
+// execTestConfig returns a configuration running the test binary as the
+// exec backend with its records in dir
+func execTestConfig(t *testing.T, dir string) config.Config {
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Config{
+		Provider:    "exec",
+		ExecCommand: fmt.Sprintf("%s='%s' '%s' -test.run='^TestExecHelperProcess$'", execStoreEnv, dir, binary),
+		ExecTimeout: 30,
+	}
+}
+
+// TestExecErrors checks that a failing program is a permanent error
+// carrying its last line of stderr and that EX_TEMPFAIL is retryable
+func TestExecErrors(t *testing.T) {
+	backend := execBackend{t.TempDir()}
+	cfg := execTestConfig(t, backend.dir)
+	p, err := NewDNSProvider(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.UpdateRecord("7", "example.com", "www", "A", "192.0.2.1")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "update failed: exit status 1: record 7 not found") {
+		t.Errorf("got error %v, want a permanent error naming the missing record", err)
+	}
+
+	backend.FailNext(1)
+	if _, err := p.GetRecords("example.com", "www", "A"); !IsRetryable(err) {
+		t.Errorf("got error %v, want a retryable error", err)
+	}
+}
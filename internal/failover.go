@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"ddnsd/utils"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// failover picks, for each address family, the address of the first
+// healthy IP source. Sources are candidates in order of priority: the
+// active one is replaced after threshold failed checks in a row, and a
+// higher priority candidate takes over again once it has been healthy for
+// the hold-down period.
+type failover struct {
+	check     string
+	port      int
+	path      string
+	timeout   time.Duration
+	threshold int
+	holdDown  time.Duration
+	families  map[string]*failoverFamily
+
+	// detect, health and now default to detectIP, checkHealth and time.Now
+	detect func(source, ipType string) (string, error)
+	health func(ip string) error
+	now    func() time.Time
+}
+
+// failoverFamily is the failover state of one address family, kept across
+// update cycles
+type failoverFamily struct {
+	sources    []string
+	active     int
+	candidates []failoverCandidate
+}
+
+// failoverCandidate is the health of one IP source
+type failoverCandidate struct {
+	ip           string
+	failures     int
+	healthySince time.Time
+}
+
+// newFailover returns the failover state, or nil when failover is disabled
+func newFailover(cfg *config.Config) *failover {
+	if !cfg.FailoverEnabled {
+		return nil
+	}
+	f := &failover{
+		check:     cfg.FailoverCheck,
+		port:      cfg.FailoverPort,
+		path:      cfg.FailoverPath,
+		timeout:   time.Duration(cfg.FailoverTimeout) * time.Second,
+		threshold: cfg.FailoverThreshold,
+		holdDown:  time.Duration(cfg.FailoverHoldDown) * time.Second,
+		families:  make(map[string]*failoverFamily),
+		detect:    detectIP,
+		now:       time.Now,
+	}
+	f.health = f.checkHealth
+	return f
+}
+
+// Select checks every candidate of the family and returns the address the
+// records should point at. The family's state starts over when its sources
+// change.
+func (f *failover) Select(log *utils.Logger, ipType string, sources []string) (string, error) {
+	family := f.families[ipType]
+	if family == nil || !slices.Equal(family.sources, sources) {
+		family = &failoverFamily{sources: slices.Clone(sources), candidates: make([]failoverCandidate, len(sources))}
+		f.families[ipType] = family
+	}
+
+	now := f.now()
+	for i, source := range sources {
+		candidate := &family.candidates[i]
+		err := f.checkSource(candidate, source, ipType)
+		if err != nil {
+			candidate.failures++
+			candidate.healthySince = time.Time{}
+			log.Warning("Health check failed for %s (%d in a row): %v", source, candidate.failures, err)
+			continue
+		}
+		if candidate.healthySince.IsZero() {
+			candidate.healthySince = now
+		}
+		candidate.failures = 0
+	}
+
+	active := &family.candidates[family.active]
+	if active.failures >= f.threshold {
+		next := -1
+		for i, candidate := range family.candidates {
+			if i != family.active && candidate.failures == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return "", fmt.Errorf("%s failed %d health checks and no other source is healthy", sources[family.active], active.failures)
+		}
+		log.Warning("Failing over from %s to %s after %d failed health checks", sources[family.active], sources[next], active.failures)
+		family.active = next
+	} else {
+		// Return to a higher priority candidate that has stayed healthy
+		for i := 0; i < family.active; i++ {
+			candidate := family.candidates[i]
+			if !candidate.healthySince.IsZero() && now.Sub(candidate.healthySince) >= f.holdDown {
+				log.Info("Returning from %s to %s, healthy for %v", sources[family.active], sources[i], now.Sub(candidate.healthySince).Round(time.Second))
+				family.active = i
+				break
+			}
+		}
+	}
+
+	active = &family.candidates[family.active]
+	if active.ip == "" {
+		return "", fmt.Errorf("no address known for %s", sources[family.active])
+	}
+	return active.ip, nil
+}
+
+// checkSource detects the candidate's current address and checks its
+// health. The last known address is kept when detection fails.
+func (f *failover) checkSource(candidate *failoverCandidate, source, ipType string) error {
+	ip, err := f.detect(source, ipType)
+	if err != nil {
+		return fmt.Errorf("failed to get address: %v", err)
+	}
+	candidate.ip = ip
+	return f.health(ip)
+}
+
+// checkHealth runs the configured health check against ip
+func (f *failover) checkHealth(ip string) error {
+	address := net.JoinHostPort(ip, strconv.Itoa(f.port))
+	if f.check == "http" {
+		client := &http.Client{Timeout: f.timeout}
+		resp, err := client.Get("http://" + address + f.path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("HTTP response error: status code=%d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", address, f.timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
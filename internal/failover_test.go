@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"ddnsd/utils"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failoverStep is one update cycle of a failover test
+type failoverStep struct {
+	sources []string
+	down    string        // sources failing their health check
+	lost    string        // sources whose address cannot be detected
+	advance time.Duration // clock movement before the cycle
+	want    string
+	err     string
+}
+
+// TestFailoverSelect runs Select over several cycles with fake health
+// checks and a fake clock
+func TestFailoverSelect(t *testing.T) {
+	ips := map[string]string{"a": "192.0.2.1", "b": "192.0.2.2", "c": "192.0.2.3"}
+	abc := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name      string
+		threshold int
+		holdDown  time.Duration
+		steps     []failoverStep
+	}{
+		{
+			name: "fail over at the threshold", threshold: 3,
+			steps: []failoverStep{
+				{sources: abc, want: "192.0.2.1"},
+				{sources: abc, down: "a", want: "192.0.2.1"},
+				{sources: abc, down: "a", want: "192.0.2.1"},
+				{sources: abc, down: "a", want: "192.0.2.2"},
+			},
+		},
+		{
+			name: "a success resets the failure count", threshold: 2,
+			steps: []failoverStep{
+				{sources: abc, down: "a", want: "192.0.2.1"},
+				{sources: abc, want: "192.0.2.1"},
+				{sources: abc, down: "a", want: "192.0.2.1"},
+				{sources: abc, down: "a", want: "192.0.2.2"},
+			},
+		},
+		{
+			name: "pick the next healthy candidate", threshold: 1,
+			steps: []failoverStep{
+				{sources: abc, down: "a,b", want: "192.0.2.3"},
+				{sources: abc, down: "a,c", want: "192.0.2.2"},
+			},
+		},
+		{
+			name: "return after the hold-down", threshold: 1, holdDown: 5 * time.Minute,
+			steps: []failoverStep{
+				{sources: abc, down: "a", want: "192.0.2.2"},
+				{sources: abc, want: "192.0.2.2"},
+				{sources: abc, advance: 4 * time.Minute, want: "192.0.2.2"},
+				{sources: abc, down: "a", advance: 2 * time.Minute, want: "192.0.2.2"},
+				{sources: abc, advance: 4 * time.Minute, want: "192.0.2.2"},
+				{sources: abc, advance: 5 * time.Minute, want: "192.0.2.1"},
+			},
+		},
+		{
+			name: "nothing healthy", threshold: 1,
+			steps: []failoverStep{
+				{sources: abc, down: "a,b,c", err: "a failed 1 health checks and no other source is healthy"},
+				{sources: abc, down: "a,c", want: "192.0.2.2"},
+				{sources: abc, down: "a,b,c", err: "b failed 1 health checks and no other source is healthy"},
+			},
+		},
+		{
+			name: "no address detected yet", threshold: 2,
+			steps: []failoverStep{
+				{sources: abc, lost: "a", err: "no address known for a"},
+				{sources: abc, lost: "a", want: "192.0.2.2"},
+			},
+		},
+		{
+			name: "state resets when the sources change", threshold: 1,
+			steps: []failoverStep{
+				{sources: abc, down: "a", want: "192.0.2.2"},
+				{sources: []string{"c", "b", "a"}, want: "192.0.2.3"},
+				{sources: []string{"c", "b", "a"}, down: "c", want: "192.0.2.2"},
+				{sources: []string{"a", "b"}, want: "192.0.2.1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step failoverStep
+			now := time.Unix(1700000000, 0)
+			f := &failover{
+				threshold: tt.threshold,
+				holdDown:  tt.holdDown,
+				families:  make(map[string]*failoverFamily),
+				detect: func(source, ipType string) (string, error) {
+					if strings.Contains(step.lost, source) {
+						return "", fmt.Errorf("no route")
+					}
+					return ips[source], nil
+				},
+				health: func(ip string) error {
+					for _, source := range strings.Split(step.down, ",") {
+						if ips[source] == ip {
+							return fmt.Errorf("connection refused")
+						}
+					}
+					return nil
+				},
+				now: func() time.Time { return now },
+			}
+
+			for i, s := range tt.steps {
+				step = s
+				now = now.Add(step.advance)
+				got, err := f.Select(utils.NewLogger(""), "IPv4", step.sources)
+				switch {
+				case step.err != "" && (err == nil || err.Error() != step.err):
+					t.Errorf("cycle %d: got %q, %v, want error %q", i+1, got, err, step.err)
+				case step.err == "" && (err != nil || got != step.want):
+					t.Errorf("cycle %d: got %q, %v, want %s", i+1, got, err, step.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GandiProvider implements DNSProvider for Gandi LiveDNS
+type GandiProvider struct {
+	api *restClient
+	ttl int
+}
+
+// Gandi LiveDNS API structures
+type gandiRRSet struct {
+	TTL    int      `json:"rrset_ttl,omitempty"`
+	Values []string `json:"rrset_values"`
+}
+
+// newGandiProvider creates a new Gandi LiveDNS provider instance. token is
+// a personal access token; endpoint overrides the API URL.
+func newGandiProvider(token, endpoint string, ttl int) (*GandiProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.gandi.net/v5/livedns"
+	}
+
+	return &GandiProvider{
+		api: newRESTClient(endpoint, bearerHeader(token), gandiErrorMessage),
+		ttl: ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. LiveDNS stores records as
+// rrsets, so each value doubles as its record ID.
+func (g *GandiProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	rrset, err := g.getRRSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if rrset == nil {
+		return nil, nil
+	}
+	return setRecords(rrset.Values), nil
+}
+
+// CreateRecord adds a value to the rrset, creating the rrset if needed
+func (g *GandiProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := g.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the rrset with value
+func (g *GandiProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return g.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the rrset
+func (g *GandiProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return g.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save writes the rrset with oldValue replaced by newValue, keeping the
+// other values and the TTL. An rrset left without values is deleted.
+func (g *GandiProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	current, err := g.getRRSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	rrset := gandiRRSet{
+		TTL:    g.ttl,
+		Values: replaceRecordValue(nil, oldValue, newValue),
+	}
+	if current != nil {
+		rrset.TTL = current.TTL
+		rrset.Values = replaceRecordValue(current.Values, oldValue, newValue)
+	}
+	if len(rrset.Values) == 0 {
+		if current == nil {
+			return nil
+		}
+		return g.api.do("DELETE", gandiRRSetPath(domain, subdomain, recordType), nil, nil)
+	}
+	return g.api.do("PUT", gandiRRSetPath(domain, subdomain, recordType), rrset, nil)
+}
+
+// getRRSet returns the rrset for the name and type, or nil
+func (g *GandiProvider) getRRSet(domain, subdomain, recordType string) (*gandiRRSet, error) {
+	var rrset gandiRRSet
+	err := g.api.do("GET", gandiRRSetPath(domain, subdomain, recordType), nil, &rrset)
+	if err == errRESTNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rrset, nil
+}
+
+// gandiRRSetPath returns the API path of an rrset
+func gandiRRSetPath(domain, subdomain, recordType string) string {
+	return fmt.Sprintf("/domains/%s/records/%s/%s", url.PathEscape(domain), url.PathEscape(subdomain), recordType)
+}
+
+// gandiErrorMessage extracts the message from an API error response, which
+// is either a single message or a list of field errors
+func gandiErrorMessage(body []byte) string {
+	var errResp struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+
+	var messages []string
+	for _, e := range errResp.Errors {
+		messages = append(messages, e.Name+": "+e.Description)
+	}
+	if len(messages) > 0 {
+		return strings.Join(messages, "; ")
+	}
+	return errResp.Message
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gandiStandIn is a minimal LiveDNS API for example.com, holding rrsets by
+// "name/type"
+type gandiStandIn struct {
+	mu     sync.Mutex
+	rrsets map[string]gandiRRSet
+}
+
+func (s *gandiStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Access was denied to this resource."})
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/domains/example.com/records/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "The resource could not be found."})
+		return
+	}
+
+	rrset, exists := s.rrsets[key]
+	switch r.Method {
+	case "GET":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "The resource could not be found."})
+			return
+		}
+		json.NewEncoder(w).Encode(rrset)
+	case "PUT":
+		json.NewDecoder(r.Body).Decode(&rrset)
+		if len(rrset.Values) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"errors": []map[string]string{{"name": "rrset_values", "description": "Shorter than minimum length 1."}},
+			})
+			return
+		}
+		s.rrsets[key] = rrset
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		delete(s.rrsets, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newGandiTestProvider(t *testing.T, token string, rrsets map[string]gandiRRSet) (*GandiProvider, *gandiStandIn) {
+	standIn := &gandiStandIn{rrsets: rrsets}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newGandiProvider(token, server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestGandiChanges checks that rrsets keep their other values and TTL and
+// that an emptied rrset is deleted rather than written empty
+func TestGandiChanges(t *testing.T) {
+	p, standIn := newGandiTestProvider(t, "token", map[string]gandiRRSet{
+		"www/A":    {TTL: 1800, Values: []string{"192.0.2.1", "192.0.2.9"}},
+		"www/AAAA": {TTL: 1800, Values: []string{"2001:db8::1"}},
+		"@/MX":     {TTL: 3600, Values: []string{"10 mail.example.com."}},
+	})
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := map[string]gandiRRSet{
+		"www/A": {TTL: 1800, Values: []string{"192.0.2.9", "192.0.2.2"}},
+		"@/A":   {TTL: 300, Values: []string{"192.0.2.3"}},
+		"@/MX":  {TTL: 3600, Values: []string{"10 mail.example.com."}},
+	}
+	if fmt.Sprint(standIn.rrsets) != fmt.Sprint(want) {
+		t.Errorf("rrsets are\n%v\nwant\n%v", standIn.rrsets, want)
+	}
+}
+
+// TestGandiErrors checks that API error messages, including field errors,
+// reach the caller
+func TestGandiErrors(t *testing.T) {
+	p, _ := newGandiTestProvider(t, "wrong", map[string]gandiRRSet{})
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Access was denied") {
+		t.Errorf("wrong token: got error %v, want a permanent access denied error", err)
+	}
+
+	p, _ = newGandiTestProvider(t, "token", map[string]gandiRRSet{})
+	if err := p.api.do("PUT", gandiRRSetPath("example.com", "www", "A"), gandiRRSet{}, nil); err == nil || !strings.Contains(err.Error(), "rrset_values: Shorter than minimum length 1.") {
+		t.Errorf("empty rrset: got error %v, want the field error", err)
+	}
+}
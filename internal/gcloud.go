@@ -0,0 +1,368 @@
+package internal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GoogleCloudProvider implements DNSProvider for Google Cloud DNS
+type GoogleCloudProvider struct {
+	project  string
+	endpoint string
+	ttl      int
+	client   *http.Client
+	token    *cachedToken
+
+	mu    sync.Mutex
+	zones map[string]string
+}
+
+// Google Cloud API structures
+type gcloudServiceAccountKey struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+type gcloudManagedZonesResponse struct {
+	ManagedZones []struct {
+		Name    string `json:"name"`
+		DNSName string `json:"dnsName"`
+	} `json:"managedZones"`
+}
+
+type gcloudRecordSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	RRDatas []string `json:"rrdatas"`
+}
+
+type gcloudRecordSetsResponse struct {
+	RRSets []gcloudRecordSet `json:"rrsets"`
+}
+
+type gcloudChange struct {
+	Additions []gcloudRecordSet `json:"additions,omitempty"`
+	Deletions []gcloudRecordSet `json:"deletions,omitempty"`
+}
+
+type gcloudErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// gcloudScope is the OAuth scope needed to read and write record sets
+const gcloudScope = "https://www.googleapis.com/auth/ndev.clouddns.readwrite"
+
+// newGoogleCloudProvider creates a new Google Cloud DNS provider instance from
+// a service-account JSON key file. project may be empty to use the project of
+// the key. endpoint overrides the API URL.
+func newGoogleCloudProvider(project, keyFile, endpoint string, ttl int) (*GoogleCloudProvider, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %v", err)
+	}
+
+	var key gcloudServiceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %v", err)
+	}
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("service account key is missing client_email or private_key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	privateKey, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %v", err)
+	}
+
+	if project == "" {
+		project = key.ProjectID
+	}
+	if project == "" {
+		return nil, fmt.Errorf("project ID is not set and not present in the service account key")
+	}
+	if endpoint == "" {
+		endpoint = "https://dns.googleapis.com"
+	}
+
+	g := &GoogleCloudProvider{
+		project:  project,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		ttl:      ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		zones: make(map[string]string),
+	}
+	g.token = &cachedToken{
+		fetch: func() (string, time.Duration, error) {
+			assertion, err := gcloudAssertion(key, privateKey, time.Now())
+			if err != nil {
+				return "", 0, permanentError(err)
+			}
+			return fetchOAuthToken(g.client, key.TokenURI, url.Values{
+				"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+				"assertion":  {assertion},
+			})
+		},
+	}
+	return g, nil
+}
+
+// GetRecords retrieves the existing DNS records. Cloud DNS stores records as
+// sets, so each value doubles as its record ID.
+func (g *GoogleCloudProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	set, err := g.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+	return setRecords(set.RRDatas), nil
+}
+
+// CreateRecord adds a value to the record set
+func (g *GoogleCloudProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := g.change(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the record set with value
+func (g *GoogleCloudProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return g.change(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the record set
+func (g *GoogleCloudProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return g.change(domain, subdomain, recordType, recordID, "")
+}
+
+// change submits a Changes API request that deletes the current record set
+// and adds the new one atomically. A set left empty is only deleted.
+func (g *GoogleCloudProvider) change(domain, subdomain, recordType, oldValue, newValue string) error {
+	zone, err := g.managedZone(domain)
+	if err != nil {
+		return err
+	}
+
+	current, err := g.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	addition := gcloudRecordSet{
+		Name: recordFQDN(domain, subdomain),
+		Type: recordType,
+		TTL:  g.ttl,
+	}
+	var change gcloudChange
+	var values []string
+	if current != nil {
+		change.Deletions = []gcloudRecordSet{*current}
+		values = current.RRDatas
+		addition.TTL = current.TTL
+	}
+	addition.RRDatas = replaceRecordValue(values, oldValue, newValue)
+	if len(addition.RRDatas) > 0 {
+		change.Additions = []gcloudRecordSet{addition}
+	} else if current == nil {
+		return nil
+	}
+
+	path := fmt.Sprintf("/dns/v1/projects/%s/managedZones/%s/changes", url.PathEscape(g.project), url.PathEscape(zone))
+	return g.doRequest("POST", path, nil, change, nil)
+}
+
+// getRecordSet returns the record set for the name and type, or nil
+func (g *GoogleCloudProvider) getRecordSet(domain, subdomain, recordType string) (*gcloudRecordSet, error) {
+	zone, err := g.managedZone(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	name := recordFQDN(domain, subdomain)
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("type", recordType)
+
+	var resp gcloudRecordSetsResponse
+	path := fmt.Sprintf("/dns/v1/projects/%s/managedZones/%s/rrsets", url.PathEscape(g.project), url.PathEscape(zone))
+	if err := g.doRequest("GET", path, query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	for _, set := range resp.RRSets {
+		if strings.EqualFold(set.Name, name) && set.Type == recordType {
+			return &set, nil
+		}
+	}
+	return nil, nil
+}
+
+// managedZone resolves the managed zone name for domain and caches it
+func (g *GoogleCloudProvider) managedZone(domain string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if zone, ok := g.zones[domain]; ok {
+		return zone, nil
+	}
+
+	query := url.Values{}
+	query.Set("dnsName", domain+".")
+
+	var resp gcloudManagedZonesResponse
+	path := fmt.Sprintf("/dns/v1/projects/%s/managedZones", url.PathEscape(g.project))
+	if err := g.doRequest("GET", path, query, nil, &resp); err != nil {
+		return "", err
+	}
+
+	for _, zone := range resp.ManagedZones {
+		if strings.EqualFold(strings.TrimSuffix(zone.DNSName, "."), domain) {
+			g.zones[domain] = zone.Name
+			return zone.Name, nil
+		}
+	}
+	return "", permanentError(fmt.Errorf("managed zone not found: %s", domain))
+}
+
+// doRequest sends an authenticated API request and decodes the response
+// into out when it is not nil
+func (g *GoogleCloudProvider) doRequest(method, path string, query url.Values, payload interface{}, out interface{}) error {
+	token, err := g.token.Get()
+	if err != nil {
+		return err
+	}
+
+	requestURL := g.endpoint + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+		}
+		reqBody = bytes.NewBuffer(body)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reqBody)
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	if statusErr := statusError(resp, respBody); statusErr != nil {
+		var errResp gcloudErrorResponse
+		if json.Unmarshal(respBody, &errResp) != nil || errResp.Error.Message == "" {
+			return statusErr
+		}
+		apiErr := fmt.Errorf("API request failed: %s", errResp.Error.Message)
+		// 412 means the record set changed between read and write; a retry re-reads it
+		if IsRetryable(statusErr) || resp.StatusCode == http.StatusPreconditionFailed {
+			return retryableError(apiErr, retryAfter(statusErr))
+		}
+		return permanentError(apiErr)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return nil
+}
+
+// gcloudAssertion builds the signed JWT used in the JWT bearer grant
+func gcloudAssertion(key gcloudServiceAccountKey, privateKey *rsa.PrivateKey, now time.Time) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if key.PrivateKeyID != "" {
+		header["kid"] = key.PrivateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   key.ClientEmail,
+		"scope": gcloudScope,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign assertion: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses a PEM-encoded PKCS#8 or PKCS#1 RSA private key
+func parseRSAPrivateKey(pemData string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
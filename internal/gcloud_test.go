@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readGcloudTestKey returns the service account key in testdata, whose
+// private key exists only for these tests
+func readGcloudTestKey(t *testing.T) gcloudServiceAccountKey {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "gcloud-key.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key gcloudServiceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// TestGcloudAssertionKnownAnswer checks the JWT against one signed with
+// openssl from the same key and claims
+func TestGcloudAssertionKnownAnswer(t *testing.T) {
+	key := readGcloudTestKey(t)
+	privateKey, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "gcloud-assertion.jwt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := gcloudAssertion(key, privateKey, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strings.TrimSpace(string(want)) {
+		t.Errorf("assertion is\n%s\nwant\n%s", got, want)
+	}
+}
+
+// gcloudStandIn is a minimal Google token endpoint and Cloud DNS API
+// serving the example.com managed zone
+type gcloudStandIn struct {
+	mu        sync.Mutex
+	publicKey *rsa.PublicKey
+	tokens    int
+	sets      map[string]gcloudRecordSet // "name type" -> set
+}
+
+func (s *gcloudStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		s.token(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", s.tokens) {
+		s.fail(w, http.StatusUnauthorized, "Request had invalid authentication credentials.")
+		return
+	}
+
+	const zonePath = "/dns/v1/projects/ddnsd-test/managedZones"
+	switch {
+	case r.Method == "GET" && r.URL.Path == zonePath:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"managedZones": []map[string]string{{"name": "example-zone", "dnsName": "example.com."}},
+		})
+	case r.Method == "GET" && r.URL.Path == zonePath+"/example-zone/rrsets":
+		resp := gcloudRecordSetsResponse{RRSets: []gcloudRecordSet{}}
+		if set, ok := s.sets[r.URL.Query().Get("name")+" "+r.URL.Query().Get("type")]; ok {
+			resp.RRSets = append(resp.RRSets, set)
+		}
+		json.NewEncoder(w).Encode(resp)
+	case r.Method == "POST" && r.URL.Path == zonePath+"/example-zone/changes":
+		var change gcloudChange
+		json.NewDecoder(r.Body).Decode(&change)
+		for _, deletion := range change.Deletions {
+			key := deletion.Name + " " + deletion.Type
+			if fmt.Sprint(s.sets[key]) != fmt.Sprint(deletion) {
+				s.fail(w, http.StatusPreconditionFailed, "The resource record set to delete does not match")
+				return
+			}
+			delete(s.sets, key)
+		}
+		for _, addition := range change.Additions {
+			s.sets[addition.Name+" "+addition.Type] = addition
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "pending"})
+	default:
+		s.fail(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// token checks the JWT bearer grant and issues a new access token
+func (s *gcloudStandIn) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	parts := strings.Split(r.Form.Get("assertion"), ".")
+	if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, digest[:], signature) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Invalid JWT Signature."})
+		return
+	}
+
+	s.tokens++
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": fmt.Sprintf("token-%d", s.tokens),
+		"expires_in":   3599,
+		"token_type":   "Bearer",
+	})
+}
+
+func (s *gcloudStandIn) fail(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": status, "message": message}})
+}
+
+// writeGcloudTestKey writes a copy of the test key pointing at tokenURI and
+// returns its path and public key
+func writeGcloudTestKey(t *testing.T, tokenURI string) (string, *rsa.PublicKey) {
+	key := readGcloudTestKey(t)
+	privateKey, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key.TokenURI = tokenURI
+	data, _ := json.Marshal(key)
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(keyFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return keyFile, &privateKey.PublicKey
+}
+
+// newGcloudTestProvider starts a stand-in and returns a provider using it,
+// with a copy of the test key pointing at the stand-in's token endpoint
+func newGcloudTestProvider(t *testing.T, sets ...gcloudRecordSet) (*GoogleCloudProvider, *gcloudStandIn) {
+	standIn := &gcloudStandIn{sets: make(map[string]gcloudRecordSet)}
+	for _, set := range sets {
+		standIn.sets[set.Name+" "+set.Type] = set
+	}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	keyFile, publicKey := writeGcloudTestKey(t, server.URL+"/token")
+	standIn.publicKey = publicKey
+
+	p, err := newGoogleCloudProvider("", keyFile, server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestGcloudChanges checks that changes replace record sets atomically,
+// keep their TTL and other values, and reuse the access token
+func TestGcloudChanges(t *testing.T) {
+	p, standIn := newGcloudTestProvider(t,
+		gcloudRecordSet{Name: "www.example.com.", Type: "A", TTL: 600, RRDatas: []string{"192.0.2.1", "192.0.2.9"}},
+		gcloudRecordSet{Name: "www.example.com.", Type: "AAAA", TTL: 600, RRDatas: []string{"2001:db8::1"}},
+		gcloudRecordSet{Name: "mail.example.com.", Type: "A", TTL: 3600, RRDatas: []string{"192.0.2.50"}},
+	)
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := map[string]gcloudRecordSet{
+		"www.example.com. A":  {Name: "www.example.com.", Type: "A", TTL: 600, RRDatas: []string{"192.0.2.9", "192.0.2.2"}},
+		"example.com. A":      {Name: "example.com.", Type: "A", TTL: 300, RRDatas: []string{"192.0.2.3"}},
+		"mail.example.com. A": {Name: "mail.example.com.", Type: "A", TTL: 3600, RRDatas: []string{"192.0.2.50"}},
+	}
+	if fmt.Sprint(standIn.sets) != fmt.Sprint(want) {
+		t.Errorf("record sets are\n%v\nwant\n%v", standIn.sets, want)
+	}
+	if standIn.tokens != 1 {
+		t.Errorf("fetched %d access tokens, want 1", standIn.tokens)
+	}
+}
+
+// TestGcloudRejectedKey checks that a key the token endpoint rejects gives
+// a permanent error with the endpoint's reason
+func TestGcloudRejectedKey(t *testing.T) {
+	p, standIn := newGcloudTestProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	standIn.publicKey = &otherKey.PublicKey
+
+	_, err = p.GetRecords("example.com", "www", "A")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("got error %v, want a permanent invalid_grant error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GoDaddyProvider implements DNSProvider for GoDaddy DNS
+type GoDaddyProvider struct {
+	api *restClient
+	ttl int
+}
+
+// GoDaddy API structures
+type goDaddyRecord struct {
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+// newGoDaddyProvider creates a new GoDaddy provider instance. endpoint
+// overrides the API URL.
+func newGoDaddyProvider(apiKey, apiSecret, endpoint string, ttl int) (*GoDaddyProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.godaddy.com"
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", fmt.Sprintf("sso-key %s:%s", apiKey, apiSecret))
+
+	return &GoDaddyProvider{
+		api: newRESTClient(endpoint, header, goDaddyErrorMessage),
+		ttl: ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. GoDaddy replaces records by
+// type and name as a set, so each value doubles as its record ID.
+func (g *GoDaddyProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	records, err := g.getRecords(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		values = append(values, record.Data)
+	}
+	return setRecords(values), nil
+}
+
+// CreateRecord adds a value to the records of the type and name
+func (g *GoDaddyProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := g.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID with value
+func (g *GoDaddyProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return g.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the records of the type and
+// name
+func (g *GoDaddyProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return g.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save replaces the records of the type and name with oldValue swapped for
+// newValue. Records with other types or names are not touched.
+func (g *GoDaddyProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	current, err := g.getRecords(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	ttl := g.ttl
+	values := make([]string, 0, len(current))
+	for _, record := range current {
+		values = append(values, record.Data)
+		ttl = record.TTL
+	}
+
+	var records []goDaddyRecord
+	for _, value := range replaceRecordValue(values, oldValue, newValue) {
+		records = append(records, goDaddyRecord{Data: value, TTL: ttl})
+	}
+	if len(records) == 0 {
+		if len(current) == 0 {
+			return nil
+		}
+		// PUT rejects an empty list, so the last record is deleted instead
+		return g.api.do("DELETE", goDaddyRecordsPath(domain, subdomain, recordType), nil, nil)
+	}
+	return g.api.do("PUT", goDaddyRecordsPath(domain, subdomain, recordType), records, nil)
+}
+
+// getRecords returns the records with the type and name
+func (g *GoDaddyProvider) getRecords(domain, subdomain, recordType string) ([]goDaddyRecord, error) {
+	var records []goDaddyRecord
+	if err := g.api.do("GET", goDaddyRecordsPath(domain, subdomain, recordType), nil, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// goDaddyRecordsPath returns the API path of the records with a type and name
+func goDaddyRecordsPath(domain, subdomain, recordType string) string {
+	return fmt.Sprintf("/v1/domains/%s/records/%s/%s", url.PathEscape(domain), recordType, url.PathEscape(subdomain))
+}
+
+// goDaddyErrorMessage extracts the message from an API error response
+func goDaddyErrorMessage(body []byte) string {
+	var errResp struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &errResp) != nil || errResp.Message == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s - %s", errResp.Code, errResp.Message)
+}
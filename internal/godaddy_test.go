@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// goDaddyStandIn is a minimal GoDaddy records API for one domain, holding
+// record sets by type and name
+type goDaddyStandIn struct {
+	mu   sync.Mutex
+	sets map[string][]goDaddyRecord
+}
+
+func (s *goDaddyStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "sso-key key:secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"code": "UNABLE_TO_AUTHENTICATE", "message": "Unauthorized"})
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/v1/domains/example.com/records/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		records := s.sets[key]
+		if records == nil {
+			records = []goDaddyRecord{}
+		}
+		json.NewEncoder(w).Encode(records)
+	case "PUT":
+		var records []goDaddyRecord
+		json.NewDecoder(r.Body).Decode(&records)
+		s.sets[key] = records
+	case "DELETE":
+		delete(s.sets, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestGoDaddyKeepsOtherRecords checks that replacing a record set keeps
+// its other values and leaves other names and types alone
+func TestGoDaddyKeepsOtherRecords(t *testing.T) {
+	standIn := &goDaddyStandIn{sets: map[string][]goDaddyRecord{
+		"A/www":    {{Data: "192.0.2.1", TTL: 600}, {Data: "192.0.2.9", TTL: 600}},
+		"AAAA/www": {{Data: "2001:db8::1", TTL: 600}},
+		"A/@":      {{Data: "192.0.2.10", TTL: 3600}},
+		"MX/@":     {{Data: "mail.example.com", TTL: 3600}},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newGoDaddyProvider("key", "secret", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "home", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("192.0.2.3", "example.com", "home", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := map[string][]goDaddyRecord{
+		"A/www":    {{Data: "192.0.2.9", TTL: 600}, {Data: "192.0.2.2", TTL: 600}},
+		"AAAA/www": {{Data: "2001:db8::1", TTL: 600}},
+		"A/@":      {{Data: "192.0.2.10", TTL: 3600}},
+		"MX/@":     {{Data: "mail.example.com", TTL: 3600}},
+	}
+	if fmt.Sprint(standIn.sets) != fmt.Sprint(want) {
+		t.Errorf("records are\n%v\nwant\n%v", standIn.sets, want)
+	}
+}
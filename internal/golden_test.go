@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// copyTestdata copies a testdata file into a temporary directory and
+// returns a local file for the copy
+func copyTestdata(t *testing.T, name string) *localFile {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &localFile{path: path}
+}
+
+// checkGolden compares the contents of file with the golden file name in
+// testdata, rewriting the golden file instead when -update is set
+func checkGolden(t *testing.T, file *localFile, name string) {
+	t.Helper()
+	got, err := os.ReadFile(file.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from %s\n--- got ---\n%s\n--- want ---\n%s", file.path, golden, got, want)
+	}
+}
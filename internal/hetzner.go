@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// HetznerProvider implements DNSProvider for Hetzner DNS
+type HetznerProvider struct {
+	api *restClient
+	ttl int
+
+	mu      sync.Mutex
+	zoneIDs map[string]string
+}
+
+// Hetzner DNS API structures
+type hetznerRecord struct {
+	ID     string `json:"id,omitempty"`
+	ZoneID string `json:"zone_id"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	TTL    int    `json:"ttl,omitempty"`
+}
+
+type hetznerRecordsResponse struct {
+	Records []hetznerRecord `json:"records"`
+	Meta    struct {
+		Pagination struct {
+			Page     int `json:"page"`
+			LastPage int `json:"last_page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+type hetznerRecordResponse struct {
+	Record hetznerRecord `json:"record"`
+}
+
+type hetznerZonesResponse struct {
+	Zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"zones"`
+}
+
+// newHetznerProvider creates a new Hetzner DNS provider instance. endpoint
+// overrides the API URL.
+func newHetznerProvider(token, endpoint string, ttl int) (*HetznerProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://dns.hetzner.com/api/v1"
+	}
+
+	header := http.Header{}
+	header.Set("Auth-API-Token", token)
+
+	return &HetznerProvider{
+		api:     newRESTClient(endpoint, header, hetznerErrorMessage),
+		ttl:     ttl,
+		zoneIDs: make(map[string]string),
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (h *HetznerProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	zoneID, err := h.zoneID(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("zone_id", zoneID)
+		query.Set("page", fmt.Sprint(page))
+		query.Set("per_page", "100")
+
+		var resp hetznerRecordsResponse
+		if err := h.api.do("GET", "/records?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, record := range resp.Records {
+			if strings.EqualFold(record.Name, subdomain) && record.Type == recordType {
+				records = append(records, DNSRecord{
+					RecordID: record.ID,
+					Value:    record.Value,
+				})
+			}
+		}
+		if resp.Meta.Pagination.Page >= resp.Meta.Pagination.LastPage {
+			return records, nil
+		}
+	}
+}
+
+// CreateRecord creates a new DNS record
+func (h *HetznerProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	zoneID, err := h.zoneID(domain)
+	if err != nil {
+		return "", err
+	}
+
+	createReq := hetznerRecord{
+		ZoneID: zoneID,
+		Type:   recordType,
+		Name:   subdomain,
+		Value:  value,
+		TTL:    h.ttl,
+	}
+
+	var resp hetznerRecordResponse
+	if err := h.api.do("POST", "/records", createReq, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Record.ID, nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (h *HetznerProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	zoneID, err := h.zoneID(domain)
+	if err != nil {
+		return err
+	}
+
+	updateReq := hetznerRecord{
+		ZoneID: zoneID,
+		Type:   recordType,
+		Name:   subdomain,
+		Value:  value,
+	}
+
+	return h.api.do("PUT", "/records/"+url.PathEscape(recordID), updateReq, nil)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (h *HetznerProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return h.api.do("DELETE", "/records/"+url.PathEscape(recordID), nil, nil)
+}
+
+// zoneID resolves the zone ID for domain and caches it
+func (h *HetznerProvider) zoneID(domain string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if id, ok := h.zoneIDs[domain]; ok {
+		return id, nil
+	}
+
+	var resp hetznerZonesResponse
+	err := h.api.do("GET", "/zones?name="+url.QueryEscape(domain), nil, &resp)
+	if err == errRESTNotFound {
+		return "", permanentError(fmt.Errorf("zone not found: %s", domain))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, zone := range resp.Zones {
+		if strings.EqualFold(zone.Name, domain) {
+			h.zoneIDs[domain] = zone.ID
+			return zone.ID, nil
+		}
+	}
+	return "", permanentError(fmt.Errorf("zone not found: %s", domain))
+}
+
+// hetznerErrorMessage extracts the message from an API error response
+func hetznerErrorMessage(body []byte) string {
+	var errResp struct {
+		Message string `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+	if errResp.Error.Message != "" {
+		return errResp.Error.Message
+	}
+	return errResp.Message
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hetznerStandIn is a minimal Hetzner DNS API for the example.com zone
+// that pages record listings two at a time
+type hetznerStandIn struct {
+	mu      sync.Mutex
+	records []hetznerRecord
+	nextID  int
+}
+
+func (s *hetznerStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Auth-API-Token") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid authentication credentials"})
+		return
+	}
+
+	id, hasID := strings.CutPrefix(r.URL.Path, "/records/")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/zones":
+		zones := []map[string]string{}
+		if r.URL.Query().Get("name") == "example.com" {
+			zones = append(zones, map[string]string{"id": "zone-1", "name": "example.com"})
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"zones": zones})
+
+	case r.Method == "GET" && r.URL.Path == "/records":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var resp hetznerRecordsResponse
+		resp.Records = []hetznerRecord{}
+		for i := (page - 1) * 2; i < page*2 && i < len(s.records); i++ {
+			resp.Records = append(resp.Records, s.records[i])
+		}
+		resp.Meta.Pagination.Page = page
+		resp.Meta.Pagination.LastPage = (len(s.records) + 1) / 2
+		json.NewEncoder(w).Encode(resp)
+
+	case r.Method == "POST" && r.URL.Path == "/records":
+		var record hetznerRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		s.nextID++
+		record.ID = fmt.Sprintf("rec-%d", s.nextID)
+		s.records = append(s.records, record)
+		json.NewEncoder(w).Encode(hetznerRecordResponse{Record: record})
+
+	case r.Method == "PUT" && hasID:
+		var update hetznerRecord
+		json.NewDecoder(r.Body).Decode(&update)
+		for i := range s.records {
+			if s.records[i].ID == id {
+				update.ID, update.TTL = id, s.records[i].TTL
+				s.records[i] = update
+				json.NewEncoder(w).Encode(hetznerRecordResponse{Record: update})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	case r.Method == "DELETE" && hasID:
+		for i := range s.records {
+			if s.records[i].ID == id {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// TestHetznerChanges checks paging and that changes touch only the record
+// they are made to
+func TestHetznerChanges(t *testing.T) {
+	others := []hetznerRecord{
+		{ID: "rec-a", ZoneID: "zone-1", Type: "NS", Name: "@", Value: "hydrogen.ns.hetzner.com.", TTL: 86400},
+		{ID: "rec-b", ZoneID: "zone-1", Type: "AAAA", Name: "www", Value: "2001:db8::1", TTL: 600},
+		{ID: "rec-c", ZoneID: "zone-1", Type: "A", Name: "mail", Value: "192.0.2.50", TTL: 600},
+	}
+	standIn := &hetznerStandIn{records: append(others, hetznerRecord{ID: "rec-d", ZoneID: "zone-1", Type: "A", Name: "www", Value: "192.0.2.1", TTL: 600})}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newHetznerProvider("token", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The www A record is on the second page
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].RecordID != "rec-d" {
+		t.Fatalf("GetRecords returned %+v, want rec-d", records)
+	}
+	if err := p.UpdateRecord("rec-d", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	id, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3")
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord(id, "example.com", "@", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := append(others, hetznerRecord{ID: "rec-d", ZoneID: "zone-1", Type: "A", Name: "www", Value: "192.0.2.2", TTL: 600})
+	if fmt.Sprint(standIn.records) != fmt.Sprint(want) {
+		t.Errorf("records are\n%v\nwant\n%v", standIn.records, want)
+	}
+
+	if _, err := p.GetRecords("example.org", "www", "A"); err == nil || IsRetryable(err) {
+		t.Errorf("unknown zone: got error %v, want a permanent error", err)
+	}
+}
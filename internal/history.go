@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"bufio"
+	"ddnsd/config"
+	"ddnsd/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// History events
+const (
+	HistoryDetected   = "detected"
+	HistoryCreated    = "created"
+	HistoryUpdated    = "updated"
+	HistoryDeleted    = "deleted"
+	HistoryFailed     = "failed"
+	HistorySuppressed = "suppressed"
+)
+
+// HistoryEntry is one line of the history file. Detected entries describe
+// an address family and leave Record empty.
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Record     string    `json:"record,omitempty"`
+	RecordType string    `json:"type"`
+	Provider   string    `json:"provider,omitempty"`
+	RecordID   string    `json:"record_id,omitempty"`
+	OldValue   string    `json:"old_value,omitempty"`
+	NewValue   string    `json:"new_value,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// HistoryFilter selects history entries. Zero fields match everything.
+type HistoryFilter struct {
+	Since      time.Time
+	Until      time.Time
+	Record     string
+	RecordType string
+	Event      string
+}
+
+// Match reports whether the entry passes the filter
+func (f HistoryFilter) Match(entry HistoryEntry) bool {
+	switch {
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.Time.Before(f.Until):
+		return false
+	case f.Record != "" && !strings.EqualFold(entry.Record, strings.TrimSuffix(f.Record, ".")):
+		return false
+	case f.RecordType != "" && !strings.EqualFold(entry.RecordType, f.RecordType):
+		return false
+	case f.Event != "" && entry.Event != f.Event:
+		return false
+	}
+	return true
+}
+
+// historyStore appends entries to a JSON Lines file
+type historyStore struct {
+	path     string
+	provider string
+
+	mu       sync.Mutex
+	detected map[string]string
+}
+
+// newHistoryStore returns a store, or nil when no history file is set
+func newHistoryStore(cfg *config.Config) *historyStore {
+	if cfg.HistoryFile == "" {
+		return nil
+	}
+	return &historyStore{path: cfg.HistoryFile, provider: cfg.Provider, detected: make(map[string]string)}
+}
+
+// Append writes an entry to the file. Failures are logged rather than
+// returned, so history never gets in the way of updates.
+func (h *historyStore) Append(entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Record != "" {
+		entry.Provider = h.provider
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		utils.LogWarning("Failed to encode history entry: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		utils.LogWarning("Failed to open history file: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		utils.LogWarning("Failed to write history file: %v", err)
+	}
+}
+
+// Detected records the addresses detected for a family when they differ
+// from the ones detected last, or on the first detection after start
+func (h *historyStore) Detected(recordType, ip string) {
+	h.mu.Lock()
+	old, seen := h.detected[recordType]
+	h.detected[recordType] = ip
+	h.mu.Unlock()
+
+	if !seen || old != ip {
+		h.Append(HistoryEntry{Event: HistoryDetected, RecordType: recordType, OldValue: old, NewValue: ip})
+	}
+}
+
+// ReadHistory returns the entries of the history file at path that match
+// filter, oldest first. Lines that cannot be parsed, such as one cut short
+// by a crash, are skipped and reported to warn.
+func ReadHistory(path string, filter HistoryFilter, warn func(format string, v ...interface{})) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			warn("Skipping history line %d: %v", lineNo, err)
+			continue
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+	return entries, nil
+}
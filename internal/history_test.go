@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestReadHistory checks filtering and that unreadable lines are reported
+// to the caller rather than printed
+func TestReadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := &historyStore{path: path, provider: "memory", detected: make(map[string]string)}
+	start := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+	h.Append(HistoryEntry{Time: start, Event: HistoryDetected, RecordType: "A", NewValue: "192.0.2.1"})
+	h.Append(HistoryEntry{Time: start.Add(time.Minute), Event: HistoryUpdated, Record: "www.example.com", RecordType: "A", OldValue: "192.0.2.9", NewValue: "192.0.2.1"})
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-05-17T12:02:00Z","event":"upd` + "\n")
+	f.Close()
+	h.Append(HistoryEntry{Time: start.Add(3 * time.Minute), Event: HistoryFailed, Record: "WWW.example.com", RecordType: "AAAA", Error: "timeout"})
+
+	var warnings []string
+	warn := func(format string, v ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, v...))
+	}
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		events []string
+	}{
+		{"all", HistoryFilter{}, []string{HistoryDetected, HistoryUpdated, HistoryFailed}},
+		{"record", HistoryFilter{Record: "www.example.com."}, []string{HistoryUpdated, HistoryFailed}},
+		{"type", HistoryFilter{RecordType: "aaaa"}, []string{HistoryFailed}},
+		{"event", HistoryFilter{Event: HistoryDetected}, []string{HistoryDetected}},
+		{"since", HistoryFilter{Since: start.Add(time.Minute)}, []string{HistoryUpdated, HistoryFailed}},
+		{"until", HistoryFilter{Until: start.Add(time.Minute)}, []string{HistoryDetected}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings = nil
+			entries, err := ReadHistory(path, tt.filter, warn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var events []string
+			for _, entry := range entries {
+				events = append(events, entry.Event)
+			}
+			if fmt.Sprint(events) != fmt.Sprint(tt.events) {
+				t.Errorf("got events %v, want %v", events, tt.events)
+			}
+			if len(warnings) != 1 {
+				t.Errorf("got warnings %q, want one for line 3", warnings)
+			}
+		})
+	}
+
+	entries, _ := ReadHistory(path, HistoryFilter{Event: HistoryUpdated}, warn)
+	if len(entries) != 1 || entries[0].Provider != "memory" {
+		t.Errorf("record entries should carry the provider, got %+v", entries)
+	}
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"ddnsd/config"
+	"ddnsd/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hook modes
+const (
+	hookModeRecord = "record"
+	hookModeChange = "change"
+)
+
+// hookEvent describes the change passed to a hook as environment variables
+type hookEvent struct {
+	Phase      string
+	OldIP      string
+	NewIP      string
+	Record     string
+	RecordType string
+	Result     string
+}
+
+// environ returns the event as DDNSD_* environment variables
+func (e hookEvent) environ() []string {
+	return []string{
+		"DDNSD_HOOK_PHASE=" + e.Phase,
+		"DDNSD_OLD_IP=" + e.OldIP,
+		"DDNSD_NEW_IP=" + e.NewIP,
+		"DDNSD_RECORD=" + e.Record,
+		"DDNSD_RECORD_TYPE=" + e.RecordType,
+		"DDNSD_RESULT=" + e.Result,
+	}
+}
+
+// errPreHook marks an update aborted by a failing pre-update hook
+type errPreHook struct {
+	err error
+}
+
+func (e *errPreHook) Error() string {
+	return fmt.Sprintf("update aborted: %v", e.err)
+}
+
+// hookRunner runs the configured pre- and post-update commands
+type hookRunner struct {
+	preCommand  string
+	postCommand string
+	timeout     time.Duration
+	mode        string
+}
+
+// newHookRunner returns a hook runner, or nil when no hooks are configured
+func newHookRunner(cfg *config.Config) *hookRunner {
+	if cfg.PreUpdateHook == "" && cfg.PostUpdateHook == "" {
+		return nil
+	}
+	return &hookRunner{
+		preCommand:  cfg.PreUpdateHook,
+		postCommand: cfg.PostUpdateHook,
+		timeout:     time.Duration(cfg.HookTimeout) * time.Second,
+		mode:        cfg.HookMode,
+	}
+}
+
+// perRecord reports whether hooks fire for every record rather than once
+// per IP change
+func (h *hookRunner) perRecord() bool {
+	return h.mode != hookModeChange
+}
+
+// Pre runs the pre-update hook. A failing pre-update hook aborts the update.
+func (h *hookRunner) Pre(log *utils.Logger, event hookEvent) error {
+	event.Phase = "pre"
+	event.Result = "pending"
+	return h.run(log, h.preCommand, event)
+}
+
+// Post runs the post-update hook. Failures are logged but do not change the
+// outcome of the update.
+func (h *hookRunner) Post(log *utils.Logger, event hookEvent) {
+	event.Phase = "post"
+	if err := h.run(log, h.postCommand, event); err != nil {
+		log.Error("Post-update hook failed: %v", err)
+	}
+}
+
+// run executes command through the shell with the event in its environment
+func (h *hookRunner) run(log *utils.Logger, command string, event hookEvent) error {
+	if command == "" {
+		return nil
+	}
+
+	log.Info("Running %s-update hook", event.Phase)
+	if err := runCommand(log, "hook", command, event.environ(), h.timeout); err != nil {
+		return fmt.Errorf("%s-update %v", event.Phase, err)
+	}
+	return nil
+}
+
+// runCommand executes command through the shell with env added to its
+// environment, logging each line of its combined output with a [label]
+// prefix. The command is killed after timeout.
+func runCommand(log *utils.Logger, label, command string, env []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.WaitDelay = time.Second
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			log.Info("[%s] %s", label, line)
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s timed out after %v", label, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %v", label, err)
+	}
+	return nil
+}
+
+// changeHooks runs hooks once per IP change for an address family. The
+// pre-update hook fires when the first record of the family needs a change,
+// and every record waits for it.
+type changeHooks struct {
+	runner     *hookRunner
+	log        *utils.Logger
+	domain     string
+	recordType string
+	newIP      string
+
+	once    sync.Once
+	err     error
+	oldIP   string
+	changed bool
+}
+
+// Pre runs the family's pre-update hook the first time it is called and
+// returns its result to every caller
+func (c *changeHooks) Pre(oldIP string) error {
+	c.once.Do(func() {
+		c.changed = true
+		c.oldIP = oldIP
+		c.err = c.runner.Pre(c.log, hookEvent{
+			OldIP:      oldIP,
+			NewIP:      c.newIP,
+			Record:     c.domain,
+			RecordType: c.recordType,
+		})
+	})
+	return c.err
+}
+
+// Post runs the family's post-update hook if a change was attempted
+func (c *changeHooks) Post(result string) {
+	if !c.changed || c.err != nil {
+		return
+	}
+	c.runner.Post(c.log, hookEvent{
+		OldIP:      c.oldIP,
+		NewIP:      c.newIP,
+		Record:     c.domain,
+		RecordType: c.recordType,
+		Result:     result,
+	})
+}
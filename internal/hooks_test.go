@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"ddnsd/utils"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hookLog returns a shell command appending the hook's environment to a file
+// in dir, and a function reading back the lines written so far
+func hookLog(t *testing.T, dir, name string) (string, func() []string) {
+	path := filepath.Join(dir, name)
+	command := fmt.Sprintf(`echo "$DDNSD_HOOK_PHASE $DDNSD_RECORD $DDNSD_RECORD_TYPE $DDNSD_OLD_IP>$DDNSD_NEW_IP $DDNSD_RESULT" >> '%s'`, path)
+	return command, func() []string {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		sort.Strings(lines)
+		return lines
+	}
+}
+
+// TestHookEnviron checks that the event reaches the command as DDNSD_*
+// environment variables
+func TestHookEnviron(t *testing.T) {
+	dir := t.TempDir()
+	h := &hookRunner{
+		preCommand:  fmt.Sprintf("env | grep ^DDNSD_ | sort > '%s'", filepath.Join(dir, "pre")),
+		postCommand: fmt.Sprintf("env | grep ^DDNSD_ | sort > '%s'", filepath.Join(dir, "post")),
+		timeout:     5 * time.Second,
+	}
+	event := hookEvent{OldIP: "192.0.2.1", NewIP: "192.0.2.2", Record: "www.example.com", RecordType: "A"}
+	if err := h.Pre(utils.NewLogger(""), event); err != nil {
+		t.Fatal(err)
+	}
+	event.Result = "updated"
+	h.Post(utils.NewLogger(""), event)
+
+	want := map[string]string{
+		"pre": "DDNSD_HOOK_PHASE=pre DDNSD_NEW_IP=192.0.2.2 DDNSD_OLD_IP=192.0.2.1 " +
+			"DDNSD_RECORD=www.example.com DDNSD_RECORD_TYPE=A DDNSD_RESULT=pending",
+		"post": "DDNSD_HOOK_PHASE=post DDNSD_NEW_IP=192.0.2.2 DDNSD_OLD_IP=192.0.2.1 " +
+			"DDNSD_RECORD=www.example.com DDNSD_RECORD_TYPE=A DDNSD_RESULT=updated",
+	}
+	for phase, env := range want {
+		data, err := os.ReadFile(filepath.Join(dir, phase))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(strings.Fields(string(data)), " "); got != env {
+			t.Errorf("%s hook environment = %s, want %s", phase, got, env)
+		}
+	}
+}
+
+// TestRunCommandTimeout checks that a command running past its timeout is
+// killed and reported
+func TestRunCommandTimeout(t *testing.T) {
+	start := time.Now()
+	err := runCommand(utils.NewLogger(""), "hook", "exec sleep 10", nil, 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("runCommand = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("runCommand returned after %v, want the command killed", elapsed)
+	}
+
+	if err := runCommand(utils.NewLogger(""), "hook", "echo output; exit 3", nil, 5*time.Second); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("runCommand = %v, want the exit status", err)
+	}
+}
+
+// TestPreHookBlocksChange checks that a failing pre-update hook leaves the
+// record alone and skips the post-update hook
+func TestPreHookBlocksChange(t *testing.T) {
+	dir := t.TempDir()
+	post, posted := hookLog(t, dir, "post")
+	provider := NewMemoryProvider()
+	seedRecords(t, provider, "www", "A", "192.0.2.1")
+	u := NewUpdater(provider, &config.Config{PreUpdateHook: "exit 3", PostUpdateHook: post, HookTimeout: 5, HookMode: "record"})
+
+	for _, subdomain := range []string{"www", "new"} {
+		result := u.runJob(updateJob{
+			domain: "example.com", subDomain: subdomain, recordType: "A",
+			ip: "192.0.2.9", ips: []string{"192.0.2.9"}, log: utils.NewLogger(""),
+		})
+		var preErr *errPreHook
+		if result.outcome != outcomeFailed || result.started || !errors.As(result.err, &preErr) {
+			t.Errorf("%s: result %v started=%v err=%v, want failed by the pre-update hook", subdomain, result.outcome, result.started, result.err)
+		}
+	}
+
+	if got := fmt.Sprint(provider.Records("example.com", "www", "A")); got != "[{1 192.0.2.1}]" {
+		t.Errorf("www holds %s, want it unchanged", got)
+	}
+	if got := provider.Records("example.com", "new", "A"); len(got) != 0 {
+		t.Errorf("new holds %v, want no record created", got)
+	}
+	if got := posted(); got != nil {
+		t.Errorf("post-update hook ran: %v", got)
+	}
+}
+
+// TestChangeHooksOnce checks that with HOOK_MODE=change each address family
+// fires its hooks once per change, not once per record
+func TestChangeHooksOnce(t *testing.T) {
+	dir := t.TempDir()
+	pre, preRan := hookLog(t, dir, "pre")
+	post, postRan := hookLog(t, dir, "post")
+	provider := NewMemoryProvider()
+	for _, subdomain := range []string{"@", "www", "mail"} {
+		seedRecords(t, provider, subdomain, "A", "192.0.2.1")
+	}
+	cfg := &config.Config{
+		Concurrency:    3,
+		IPv4Enabled:    true,
+		IPv4Domain:     "example.com",
+		IPv4SubDomains: []string{"@", "www", "mail"},
+		IPv4Sources:    []string{ipSource(t, "192.0.2.9")},
+		IPv6Enabled:    true,
+		IPv6Domain:     "example.com",
+		IPv6SubDomains: []string{"www", "mail"},
+		IPv6Sources:    []string{ipSource(t, "2001:db8::9")},
+		PreUpdateHook:  pre,
+		PostUpdateHook: post,
+		HookTimeout:    5,
+		HookMode:       "change",
+	}
+
+	summary := NewUpdater(provider, cfg).Run()
+	if summary.Updated != 3 || summary.Created != 2 || summary.Failed != 0 {
+		t.Errorf("summary %+v, want 3 updated and 2 created", summary)
+	}
+	wantPre := []string{"pre example.com A 192.0.2.1>192.0.2.9 pending", "pre example.com AAAA >2001:db8::9 pending"}
+	if got := preRan(); fmt.Sprint(got) != fmt.Sprint(wantPre) {
+		t.Errorf("pre-update hook ran %q, want %q", got, wantPre)
+	}
+	wantPost := []string{"post example.com A 192.0.2.1>192.0.2.9 updated", "post example.com AAAA >2001:db8::9 updated"}
+	if got := postRan(); fmt.Sprint(got) != fmt.Sprint(wantPost) {
+		t.Errorf("post-update hook ran %q, want %q", got, wantPost)
+	}
+
+	// Nothing changes in the next cycle, so no hook fires
+	if summary := NewUpdater(provider, cfg).Run(); summary.Unchanged != 5 {
+		t.Errorf("second cycle %+v, want 5 unchanged", summary)
+	}
+	if got := preRan(); len(got) != 2 {
+		t.Errorf("pre-update hook ran %d times over two cycles, want 2", len(got))
+	}
+	if got := postRan(); len(got) != 2 {
+		t.Errorf("post-update hook ran %d times over two cycles, want 2", len(got))
+	}
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+)
+
+// HostsFileProvider implements DNSProvider for a hosts-format file, as read
+// by dnsmasq (addn-hosts) or the CoreDNS hosts plugin. Only the address of
+// the entry for a managed name is changed; all other lines, including
+// comments and aliases, are kept as they are. A managed name sharing a line
+// with other names is moved to a line of its own.
+type HostsFileProvider struct {
+	file *localFile
+
+	// mu serialises read-modify-write cycles of the file
+	mu sync.Mutex
+}
+
+// hostsEntry is a parsed address line of a hosts file
+type hostsEntry struct {
+	ip    net.IP
+	names []string
+}
+
+// newHostsFileProvider creates a new hosts file provider instance
+func newHostsFileProvider(file *localFile) (*HostsFileProvider, error) {
+	return &HostsFileProvider{file: file}, nil
+}
+
+// GetRecords returns the addresses of the entries for the name in the
+// address family of recordType. The address doubles as the record ID.
+func (h *HostsFileProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := h.file.read()
+	if err != nil {
+		return nil, err
+	}
+
+	name := recordFullName(domain, subdomain)
+	var records []DNSRecord
+	for _, line := range strings.Split(string(data), "\n") {
+		if entry, ok := parseHostsLine(line); ok && entry.matches(name, recordType) {
+			value := entry.ip.String()
+			records = append(records, DNSRecord{RecordID: value, Value: value})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord appends an entry for the name
+func (h *HostsFileProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := h.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord changes the address of the entry with address recordID
+func (h *HostsFileProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return h.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the name from the entry with address recordID,
+// dropping the line when the name was its only one
+func (h *HostsFileProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return h.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save replaces the address oldValue of the name's entry with newValue, or
+// appends a new entry when there is none. An empty newValue, or one another
+// entry for the name already has, removes the name from the oldValue entry.
+func (h *HostsFileProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := h.file.read()
+	if err != nil {
+		return err
+	}
+
+	name := recordFullName(domain, subdomain)
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		if entry, ok := parseHostsLine(line); ok && entry.matches(name, recordType) && entry.ip.String() == newValue {
+			newValue = ""
+			break
+		}
+	}
+
+	replaced := newValue == ""
+	if oldValue != "" {
+		for i, line := range lines {
+			entry, ok := parseHostsLine(line)
+			if !ok || !entry.matches(name, recordType) || entry.ip.String() != oldValue {
+				continue
+			}
+			if newValue == "" {
+				if kept, ok := removeHostsName(line, name); ok {
+					lines[i] = kept
+				} else {
+					lines = append(lines[:i], lines[i+1:]...)
+				}
+			} else if kept, ok := removeHostsName(line, name); ok {
+				// Other names keep the old address; the name moves to a line
+				// of its own right below
+				updated := append([]string{}, lines[:i]...)
+				updated = append(updated, kept, newValue+"\t"+name)
+				lines = append(updated, lines[i+1:]...)
+			} else {
+				// Swap only the address, keeping indentation, aliases and comments
+				ip := strings.Fields(line)[0]
+				start := strings.Index(line, ip)
+				lines[i] = line[:start] + newValue + line[start+len(ip):]
+			}
+			replaced = true
+			break
+		}
+	}
+
+	updated := []byte(strings.Join(lines, "\n"))
+	if !replaced {
+		if len(updated) > 0 && !bytes.HasSuffix(updated, []byte("\n")) {
+			updated = append(updated, '\n')
+		}
+		updated = append(updated, newValue+"\t"+name+"\n"...)
+	}
+
+	return h.file.write(updated)
+}
+
+// removeHostsName removes name from an address line, keeping its comment.
+// It reports false when name is the only name and the line should go.
+func removeHostsName(line, name string) (string, bool) {
+	content, comment := line, ""
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		content, comment = line[:i], line[i:]
+	}
+
+	fields := strings.Fields(content)
+	kept := fields[:1]
+	for _, n := range fields[1:] {
+		if !strings.EqualFold(strings.TrimSuffix(n, "."), name) {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) == 1 {
+		return "", false
+	}
+
+	result := strings.Join(kept, "\t")
+	if comment != "" {
+		result += " " + comment
+	}
+	return result, true
+}
+
+// parseHostsLine parses an address line, ignoring comments. It reports
+// false for blank, comment and malformed lines.
+func parseHostsLine(line string) (hostsEntry, bool) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return hostsEntry{}, false
+	}
+
+	ip := net.ParseIP(fields[0])
+	if ip == nil {
+		return hostsEntry{}, false
+	}
+	return hostsEntry{ip: ip, names: fields[1:]}, true
+}
+
+// matches reports whether the entry is for name in the address family of
+// recordType
+func (e hostsEntry) matches(name, recordType string) bool {
+	if (e.ip.To4() != nil) != (recordType == "A") {
+		return false
+	}
+	for _, n := range e.names {
+		if strings.EqualFold(strings.TrimSuffix(n, "."), name) {
+			return true
+		}
+	}
+	return false
+}
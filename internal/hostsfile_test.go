@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"os"
+	"testing"
+)
+
+// TestHostsFileGolden applies a series of changes to a hosts file and
+// compares the result with a golden file
+func TestHostsFileGolden(t *testing.T) {
+	file := copyTestdata(t, "hosts.in")
+	h, err := newHostsFileProvider(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"update an entry with a comment", func() error {
+			return h.UpdateRecord("192.0.2.20", "example.com", "www", "A", "198.51.100.20")
+		}},
+		{"update a name sharing its line", func() error {
+			return h.UpdateRecord("192.0.2.30", "example.com", "app", "A", "198.51.100.30")
+		}},
+		{"update an IPv6 entry", func() error {
+			return h.UpdateRecord("2001:db8::20", "example.com", "www", "AAAA", "2001:db8::21")
+		}},
+		{"delete the only name of a line", func() error {
+			return h.DeleteRecord("192.0.2.40", "example.com", "old", "A")
+		}},
+		{"delete the apex from a shared line", func() error {
+			return h.DeleteRecord("192.0.2.50", "example.com", "@", "A")
+		}},
+		{"create an entry", func() error {
+			_, err := h.CreateRecord("example.com", "new", "A", "198.51.100.40")
+			return err
+		}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+	checkGolden(t, file, "hosts.golden")
+
+	records, err := h.GetRecords("example.com", "mail", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Value != "192.0.2.30" {
+		t.Errorf("mail.example.com has %+v, want 192.0.2.30", records)
+	}
+}
+
+// TestHostsFileUpdateToExistingAddress checks that an update to an address
+// the name already has removes the old entry instead of duplicating one
+func TestHostsFileUpdateToExistingAddress(t *testing.T) {
+	file := &localFile{path: t.TempDir() + "/hosts"}
+	if err := os.WriteFile(file.path, []byte("192.0.2.1\twww.example.com\n192.0.2.2\twww.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := newHostsFileProvider(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "192.0.2.2\twww.example.com\n"; string(data) != want {
+		t.Errorf("file holds %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HuaweiCloudProvider implements DNSProvider for Huawei Cloud DNS
+type HuaweiCloudProvider struct {
+	accessKey string
+	secretKey string
+	endpoint  string
+	ttl       int
+	client    *http.Client
+
+	mu      sync.Mutex
+	zoneIDs map[string]string
+}
+
+// Huawei Cloud API structures
+type huaweiZonesResponse struct {
+	Zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"zones"`
+}
+
+type huaweiRecordSet struct {
+	ID      string   `json:"id,omitempty"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl,omitempty"`
+	Records []string `json:"records"`
+}
+
+type huaweiRecordSetsResponse struct {
+	RecordSets []huaweiRecordSet `json:"recordsets"`
+}
+
+type huaweiErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	ErrorCode string `json:"error_code"`
+	ErrorMsg  string `json:"error_msg"`
+}
+
+// newHuaweiCloudProvider creates a new Huawei Cloud DNS provider instance.
+// region selects the regional endpoint; endpoint overrides the API URL.
+func newHuaweiCloudProvider(accessKey, secretKey, region, endpoint string, ttl int) (*HuaweiCloudProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://dns.myhuaweicloud.com"
+		if region != "" {
+			endpoint = fmt.Sprintf("https://dns.%s.myhuaweicloud.com", region)
+		}
+	}
+
+	return &HuaweiCloudProvider{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		ttl:       ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		zoneIDs: make(map[string]string),
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. Huawei Cloud stores records
+// as multi-value sets, so each value doubles as its record ID.
+func (h *HuaweiCloudProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	set, err := h.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+	return setRecords(set.Records), nil
+}
+
+// CreateRecord adds a value to the record set, creating the set if needed
+func (h *HuaweiCloudProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := h.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the record set with value
+func (h *HuaweiCloudProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return h.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the record set
+func (h *HuaweiCloudProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return h.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save writes the record set with oldValue replaced by newValue, keeping
+// the other values and the TTL. A set left empty is deleted.
+func (h *HuaweiCloudProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	zoneID, err := h.zoneID(domain)
+	if err != nil {
+		return err
+	}
+
+	current, err := h.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		if newValue == "" {
+			return nil
+		}
+		set := huaweiRecordSet{
+			Name:    recordFQDN(domain, subdomain),
+			Type:    recordType,
+			TTL:     h.ttl,
+			Records: []string{newValue},
+		}
+		return h.doRequest("POST", fmt.Sprintf("/v2/zones/%s/recordsets", zoneID), nil, set, nil)
+	}
+
+	values := replaceRecordValue(current.Records, oldValue, newValue)
+	if len(values) == 0 {
+		return h.doRequest("DELETE", fmt.Sprintf("/v2/zones/%s/recordsets/%s", zoneID, current.ID), nil, nil, nil)
+	}
+
+	set := huaweiRecordSet{
+		Name:    current.Name,
+		Type:    current.Type,
+		TTL:     current.TTL,
+		Records: values,
+	}
+	return h.doRequest("PUT", fmt.Sprintf("/v2/zones/%s/recordsets/%s", zoneID, current.ID), nil, set, nil)
+}
+
+// getRecordSet returns the record set for the name and type, or nil
+func (h *HuaweiCloudProvider) getRecordSet(domain, subdomain, recordType string) (*huaweiRecordSet, error) {
+	zoneID, err := h.zoneID(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	name := recordFQDN(domain, subdomain)
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("type", recordType)
+
+	var resp huaweiRecordSetsResponse
+	if err := h.doRequest("GET", fmt.Sprintf("/v2/zones/%s/recordsets", zoneID), query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	// The name filter is a fuzzy match, so compare names exactly
+	for _, set := range resp.RecordSets {
+		if strings.EqualFold(set.Name, name) && set.Type == recordType {
+			return &set, nil
+		}
+	}
+	return nil, nil
+}
+
+// zoneID resolves the public zone ID for domain and caches it
+func (h *HuaweiCloudProvider) zoneID(domain string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if id, ok := h.zoneIDs[domain]; ok {
+		return id, nil
+	}
+
+	query := url.Values{}
+	query.Set("name", domain+".")
+	query.Set("type", "public")
+
+	var resp huaweiZonesResponse
+	if err := h.doRequest("GET", "/v2/zones", query, nil, &resp); err != nil {
+		return "", err
+	}
+
+	for _, zone := range resp.Zones {
+		if strings.EqualFold(strings.TrimSuffix(zone.Name, "."), domain) {
+			h.zoneIDs[domain] = zone.ID
+			return zone.ID, nil
+		}
+	}
+	return "", permanentError(fmt.Errorf("zone not found: %s", domain))
+}
+
+// doRequest sends a signed API request and decodes the response into out
+// when it is not nil
+func (h *HuaweiCloudProvider) doRequest(method, path string, query url.Values, payload interface{}, out interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+		}
+	}
+
+	requestURL := h.endpoint + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signHuaweiRequest(req, body, h.accessKey, h.secretKey, time.Now())
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	if statusErr := statusError(resp, respBody); statusErr != nil {
+		var errResp huaweiErrorResponse
+		if json.Unmarshal(respBody, &errResp) != nil {
+			return statusErr
+		}
+		code, message := errResp.Code, errResp.Message
+		if code == "" {
+			code, message = errResp.ErrorCode, errResp.ErrorMsg
+		}
+		if code == "" {
+			return statusErr
+		}
+		apiErr := fmt.Errorf("API request failed: %s - %s", code, message)
+		if IsRetryable(statusErr) {
+			return retryableError(apiErr, retryAfter(statusErr))
+		}
+		return permanentError(apiErr)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return nil
+}
+
+// signHuaweiRequest signs req in place with the SDK-HMAC-SHA256 algorithm
+// used by Huawei Cloud APIs
+func signHuaweiRequest(req *http.Request, body []byte, accessKey, secretKey string, now time.Time) {
+	sdkDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Sdk-Date", sdkDate)
+
+	// The canonical URI always ends with a slash
+	uri := canonicalURI(req.URL.Path)
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+
+	signedHeaders := []string{"content-type", "host", "x-sdk-date"}
+	canonical := canonicalRequest(req, uri, signedHeaders, sha256Hex(body))
+
+	stringToSign := strings.Join([]string{
+		"SDK-HMAC-SHA256",
+		sdkDate,
+		sha256Hex([]byte(canonical)),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256([]byte(secretKey), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("SDK-HMAC-SHA256 Access=%s, SignedHeaders=%s, Signature=%s",
+		accessKey, strings.Join(signedHeaders, ";"), signature))
+}
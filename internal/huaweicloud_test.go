@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHuaweiSignKnownAnswer checks signatures against values computed
+// independently from the SDK-HMAC-SHA256 documentation
+func TestHuaweiSignKnownAnswer(t *testing.T) {
+	tests := []struct {
+		method, url, body string
+		want              string
+	}{
+		{
+			"GET", "https://dns.myhuaweicloud.com/v2/zones?type=public&name=example.com.", "",
+			"be5c8ad5304a43d952be35d3a7b0d311dba956f26c72c8cd779267e1eb53c5a5",
+		},
+		{
+			"POST", "https://dns.myhuaweicloud.com/v2/zones/zone-1/recordsets", `{"name":"www.example.com.","type":"A","ttl":300,"records":["192.0.2.1"]}`,
+			"42b2092ff937839d3c53face3faa88422676fdc7197e839da5da36ab684a0876",
+		},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		signHuaweiRequest(req, []byte(test.body), "HPUAEXAMPLE", "huawei-secret-key", time.Unix(1700000000, 0))
+
+		want := "SDK-HMAC-SHA256 Access=HPUAEXAMPLE, SignedHeaders=content-type;host;x-sdk-date, Signature=" + test.want
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s %s: Authorization is\n%s\nwant\n%s", test.method, test.url, got, want)
+		}
+		if got := req.Header.Get("X-Sdk-Date"); got != "20231114T221320Z" {
+			t.Errorf("%s %s: X-Sdk-Date is %s", test.method, test.url, got)
+		}
+	}
+}
+
+// huaweiStandIn is a minimal Huawei Cloud DNS API serving the example.com
+// zone. It re-signs every request to check the signature, and filters
+// record sets by name fuzzily like the real API.
+type huaweiStandIn struct {
+	mu     sync.Mutex
+	sets   []huaweiRecordSet
+	nextID int
+}
+
+func (s *huaweiStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if !s.signatureValid(r, body) {
+		s.fail(w, http.StatusUnauthorized, "APIGW.0301", "Incorrect IAM authentication information: verify aksk signature fail")
+		return
+	}
+
+	query := r.URL.Query()
+	id, hasID := strings.CutPrefix(r.URL.Path, "/v2/zones/zone-1/recordsets/")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v2/zones":
+		zones := []map[string]string{}
+		if query.Get("name") == "example.com." && query.Get("type") == "public" {
+			zones = append(zones, map[string]string{"id": "zone-1", "name": "example.com."})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"zones": zones})
+
+	case r.Method == "GET" && r.URL.Path == "/v2/zones/zone-1/recordsets":
+		resp := huaweiRecordSetsResponse{RecordSets: []huaweiRecordSet{}}
+		for _, set := range s.sets {
+			if strings.Contains(set.Name, query.Get("name")) && set.Type == query.Get("type") {
+				resp.RecordSets = append(resp.RecordSets, set)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case r.Method == "POST" && r.URL.Path == "/v2/zones/zone-1/recordsets":
+		var set huaweiRecordSet
+		json.Unmarshal(body, &set)
+		for _, existing := range s.sets {
+			if existing.Name == set.Name && existing.Type == set.Type {
+				s.fail(w, http.StatusBadRequest, "DNS.0312", "Attribute 'name' conflicts with an existing record set.")
+				return
+			}
+		}
+		s.nextID++
+		set.ID = fmt.Sprintf("set-%d", s.nextID)
+		s.sets = append(s.sets, set)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(set)
+
+	case hasID && (r.Method == "PUT" || r.Method == "DELETE"):
+		for i := range s.sets {
+			if s.sets[i].ID != id {
+				continue
+			}
+			if r.Method == "DELETE" {
+				s.sets = append(s.sets[:i], s.sets[i+1:]...)
+			} else {
+				var update huaweiRecordSet
+				json.Unmarshal(body, &update)
+				update.ID = id
+				s.sets[i] = update
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("{}"))
+			return
+		}
+		s.fail(w, http.StatusNotFound, "DNS.0305", "The record set does not exist.")
+
+	default:
+		s.fail(w, http.StatusNotFound, "APIGW.0101", "The API does not exist or has not been published in the environment")
+	}
+}
+
+// signatureValid signs a copy of the request again with the time it
+// claims and compares the Authorization headers
+func (s *huaweiStandIn) signatureValid(r *http.Request, body []byte) bool {
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Sdk-Date"))
+	if err != nil {
+		return false
+	}
+	check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), bytes.NewReader(body))
+	check.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	signHuaweiRequest(check, body, "HPUAEXAMPLE", "huawei-secret-key", signedAt)
+	return check.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (s *huaweiStandIn) fail(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
+
+// TestHuaweiCloudChanges checks that record sets keep their TTL and other
+// values, that only the exact name is changed and that an emptied set is
+// deleted
+func TestHuaweiCloudChanges(t *testing.T) {
+	standIn := &huaweiStandIn{nextID: 3, sets: []huaweiRecordSet{
+		{ID: "set-1", Name: "www.example.com.", Type: "A", TTL: 600, Records: []string{"192.0.2.1", "192.0.2.9"}},
+		{ID: "set-2", Name: "www.example.com.cn.", Type: "A", TTL: 600, Records: []string{"198.51.100.1"}},
+		{ID: "set-3", Name: "www.example.com.", Type: "AAAA", TTL: 600, Records: []string{"2001:db8::1"}},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newHuaweiCloudProvider("HPUAEXAMPLE", "huawei-secret-key", "", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := []huaweiRecordSet{
+		{ID: "set-1", Name: "www.example.com.", Type: "A", TTL: 600, Records: []string{"192.0.2.9", "192.0.2.2"}},
+		{ID: "set-2", Name: "www.example.com.cn.", Type: "A", TTL: 600, Records: []string{"198.51.100.1"}},
+		{ID: "set-4", Name: "example.com.", Type: "A", TTL: 300, Records: []string{"192.0.2.3"}},
+	}
+	if fmt.Sprint(standIn.sets) != fmt.Sprint(want) {
+		t.Errorf("record sets are\n%v\nwant\n%v", standIn.sets, want)
+	}
+}
+
+// TestHuaweiCloudErrors checks that a rejected signature is a permanent
+// error carrying the API code and message
+func TestHuaweiCloudErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc((&huaweiStandIn{}).serve))
+	defer server.Close()
+
+	p, _ := newHuaweiCloudProvider("HPUAEXAMPLE", "wrong-secret", "", server.URL, 300)
+	_, err := p.GetRecords("example.com", "www", "A")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "APIGW.0301 - Incorrect IAM authentication information") {
+		t.Errorf("got error %v, want a permanent signature error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// interfaceSourcePrefix marks an IP source that reads the address of a
+// network interface instead of asking a check URL
+const interfaceSourcePrefix = "interface:"
+
+// detectIPs returns the addresses of one family found by every source, in
+// source order and without duplicates. The family fails as a whole when any
+// source fails, so records are never reconciled to a partial set.
+func detectIPs(sources []string, ipType string) ([]string, error) {
+	var ips []string
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		ip, err := detectIP(source, ipType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", source, err)
+		}
+		if !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// detectIP returns the address a single source reports, checked to belong
+// to the family and normalized
+func detectIP(source, ipType string) (string, error) {
+	var value string
+	var err error
+	if name, ok := strings.CutPrefix(source, interfaceSourcePrefix); ok {
+		value, err = interfaceIP(name, ipType)
+	} else {
+		value, err = getPublicIP(source, ipType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil || (ip.To4() != nil) != (ipType == "IPv4") {
+		return "", fmt.Errorf("not an %s address: %q", ipType, value)
+	}
+	return ip.String(), nil
+}
+
+// interfaceIP returns the first global unicast address of the family
+// assigned to the named interface
+func interfaceIP(name, ipType string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to find interface: %v", err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to read interface addresses: %v", err)
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() != nil) == (ipType == "IPv4") {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no global %s address on interface %s", ipType, name)
+}
@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is one step of a parsed JSONPath expression
+type jsonPathStep struct {
+	// key selects an object member, index an array element, and wildcard
+	// every member or element
+	key      string
+	index    *int
+	wildcard bool
+
+	// filter keeps the array elements whose member at filterPath compares
+	// to filterValue with filterOp (== or !=)
+	filter      bool
+	filterPath  []jsonPathStep
+	filterOp    string
+	filterValue string
+}
+
+// parseJSONPath parses the JSONPath subset used to pick values out of
+// webhook responses: $.a.b, $['a'], $.a[0], $.a[-1], $.a[*], $.a.* and
+// filters such as $.records[?(@.type=='A')].
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") && !strings.HasPrefix(path, "@") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", path)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("invalid JSONPath %q: recursive descent is not supported", path)
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty member name", path)
+			}
+			if name == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: name})
+			}
+			rest = rest[end:]
+		case strings.HasPrefix(rest, "["):
+			end := jsonPathBracketEnd(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unterminated [", path)
+			}
+			step, err := parseJSONPathBracket(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %v", path, err)
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// jsonPathBracketEnd returns the index of the ] closing the bracket at the
+// start of s, skipping quoted strings and nested brackets, or -1
+func jsonPathBracketEnd(s string) int {
+	var quote byte
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// parseJSONPathBracket parses the contents of a [...] step
+func parseJSONPathBracket(expr string) (jsonPathStep, error) {
+	switch {
+	case expr == "*":
+		return jsonPathStep{wildcard: true}, nil
+	case strings.HasPrefix(expr, "?(") && strings.HasSuffix(expr, ")"):
+		return parseJSONPathFilter(strings.TrimSpace(expr[2 : len(expr)-1]))
+	case len(expr) >= 2 && (expr[0] == '\'' || expr[0] == '"') && expr[len(expr)-1] == expr[0]:
+		return jsonPathStep{key: expr[1 : len(expr)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(expr)
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("unsupported selector [%s]", expr)
+	}
+	return jsonPathStep{index: &index}, nil
+}
+
+// parseJSONPathFilter parses a filter expression such as @.type=='A'
+func parseJSONPathFilter(expr string) (jsonPathStep, error) {
+	i := jsonPathOperator(expr)
+	if i < 0 {
+		return jsonPathStep{}, fmt.Errorf("unsupported filter %q: only == and != are supported", expr)
+	}
+	op := expr[i : i+2]
+
+	path, err := parseJSONPath(strings.TrimSpace(expr[:i]))
+	if err != nil {
+		return jsonPathStep{}, err
+	}
+
+	value := strings.TrimSpace(expr[i+len(op):])
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return jsonPathStep{filter: true, filterPath: path, filterOp: op, filterValue: value}, nil
+}
+
+// jsonPathOperator returns the index of the first == or != in a filter
+// expression outside quoted strings, or -1
+func jsonPathOperator(expr string) int {
+	var quote byte
+	for i := 0; i+1 < len(expr); i++ {
+		switch c := expr[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case (c == '=' || c == '!') && expr[i+1] == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// evalJSONPath returns the nodes of doc selected by steps, in document
+// order. doc should be decoded with json.Decoder.UseNumber.
+func evalJSONPath(doc interface{}, steps []jsonPathStep) []interface{} {
+	nodes := []interface{}{doc}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// apply returns the nodes the step selects from node
+func (s jsonPathStep) apply(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			// Object members have no order once decoded; sort for stable results
+			var keys []string
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			var result []interface{}
+			for _, key := range keys {
+				result = append(result, v[key])
+			}
+			return result
+		}
+		if s.key != "" {
+			if member, ok := v[s.key]; ok {
+				return []interface{}{member}
+			}
+		}
+	case []interface{}:
+		switch {
+		case s.wildcard:
+			return v
+		case s.index != nil:
+			i := *s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		case s.filter:
+			var result []interface{}
+			for _, element := range v {
+				if s.matches(element) {
+					result = append(result, element)
+				}
+			}
+			return result
+		}
+	}
+	return nil
+}
+
+// matches reports whether an array element passes the filter
+func (s jsonPathStep) matches(element interface{}) bool {
+	found := false
+	for _, node := range evalJSONPath(element, s.filterPath) {
+		if value, err := jsonPathString(node); err == nil && value == s.filterValue {
+			found = true
+			break
+		}
+	}
+	return found == (s.filterOp == "==")
+}
+
+// jsonPathString returns a scalar node as a string
+func jsonPathString(node interface{}) (string, error) {
+	switch v := node.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", fmt.Errorf("value is null")
+	default:
+		return "", fmt.Errorf("value is not a scalar")
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const jsonPathTestDoc = `{
+	"ip": "192.0.2.1",
+	"count": 2,
+	"ok": true,
+	"none": null,
+	"odd key": "spaced",
+	"a.b": "dotted",
+	"data": {"ipv4": {"address": "192.0.2.2"}},
+	"records": [
+		{"id": 1, "type": "A", "name": "www", "content": "192.0.2.10", "proxied": false},
+		{"id": 2, "type": "AAAA", "name": "www", "content": "2001:db8::10"},
+		{"id": 3, "type": "A", "name": "home", "content": "192.0.2.11", "tags": {"env": "prod"}},
+		{"id": 4, "type": "TXT", "name": "a==b", "content": "x"}
+	],
+	"nested": [[1, 2], [3]],
+	"map": {"b": "2", "a": "1"}
+}`
+
+// TestJSONPath checks expression parsing and selection against one
+// document. Each result is the selected scalars joined with commas.
+func TestJSONPath(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(jsonPathTestDoc))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.ip", "192.0.2.1"},
+		{"$", "<not a scalar>"},
+		{" $.ip ", "192.0.2.1"},
+		{"$.count", "2"},
+		{"$.ok", "true"},
+		{"$.none", "<null>"},
+		{"$.missing", ""},
+		{"$.ip.deeper", ""},
+		{"$.data.ipv4.address", "192.0.2.2"},
+		{"$['data']['ipv4'].address", "192.0.2.2"},
+		{`$["odd key"]`, "spaced"},
+		{"$['a.b']", "dotted"},
+		{"$.records[0].content", "192.0.2.10"},
+		{"$.records[ 1 ].content", "2001:db8::10"},
+		{"$.records[-1].id", "4"},
+		{"$.records[9].id", ""},
+		{"$.records[-9].id", ""},
+		{"$.records[*].id", "1,2,3,4"},
+		{"$.records.*.type", "A,AAAA,A,TXT"},
+		{"$.map.*", "1,2"},
+		{"$.map[*]", "1,2"},
+		{"$.nested[*][*]", "1,2,3"},
+		{"$.nested[0][-1]", "2"},
+		{"$.ip[0]", ""},
+		{"$.records[?(@.type=='A')].content", "192.0.2.10,192.0.2.11"},
+		{`$.records[?(@.type == "AAAA")].content`, "2001:db8::10"},
+		{"$.records[?(@.type!='A')].id", "2,4"},
+		{"$.records[?(@.id==3)].name", "home"},
+		{"$.records[?(@.proxied==false)].id", "1"},
+		{"$.records[?(@.tags.env=='prod')].content", "192.0.2.11"},
+		{"$.records[?(@.name=='a==b')].id", "4"},
+		{"$.records[?(@.name!='a==b')].id", "1,2,3"},
+		{"$.records[?(@['type']=='A')].id", "1,3"},
+		{"$.records[?(@['name']=='a[0]')].id", ""},
+		{"$.records[?(@.type=='A')].id", "1,3"},
+		{"$.records[?(@.missing=='x')].id", ""},
+		{"$.records[?(@.missing!='x')].id", "1,2,3,4"},
+		{"$.map[?(@=='1')]", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			steps, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("parseJSONPath: %v", err)
+			}
+			var values []string
+			for _, node := range evalJSONPath(doc, steps) {
+				value, err := jsonPathString(node)
+				if err != nil {
+					value = "<" + err.Error()[len("value is "):] + ">"
+				}
+				values = append(values, value)
+			}
+			if got := strings.Join(values, ","); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestJSONPathErrors checks that unsupported expressions are rejected
+func TestJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"ip", "must start with $"},
+		{"", "must start with $"},
+		{"$..ip", "recursive descent is not supported"},
+		{"$.", "empty member name"},
+		{"$.a..b", "recursive descent is not supported"},
+		{"$.records[0", "unterminated ["},
+		{"$['a]", "unterminated ["},
+		{"$.records[1:2]", "unsupported selector [1:2]"},
+		{"$.records[?(@.id>1)]", "only == and != are supported"},
+		{"$.records[?(type=='A')]", "must start with $"},
+		{"$ip", "unexpected \"ip\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := parseJSONPath(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
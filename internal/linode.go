@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LinodeProvider implements DNSProvider for Linode (Akamai) DNS Manager
+type LinodeProvider struct {
+	api *restClient
+	ttl int
+
+	mu        sync.Mutex
+	domainIDs map[string]int64
+}
+
+// Linode API structures
+type linodeDomain struct {
+	ID     int64  `json:"id"`
+	Domain string `json:"domain"`
+}
+
+type linodeRecord struct {
+	ID     int64  `json:"id,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	TTL    int    `json:"ttl_sec,omitempty"`
+}
+
+type linodePage struct {
+	Page  int `json:"page"`
+	Pages int `json:"pages"`
+}
+
+// newLinodeProvider creates a new Linode provider instance. endpoint
+// overrides the API URL.
+func newLinodeProvider(token, endpoint string, ttl int) (*LinodeProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.linode.com/v4"
+	}
+
+	return &LinodeProvider{
+		api:       newRESTClient(endpoint, bearerHeader(token), linodeErrorMessage),
+		ttl:       ttl,
+		domainIDs: make(map[string]int64),
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (l *LinodeProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	domainID, err := l.domainID(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	name := recordRelativeName(subdomain)
+	for page := 1; ; page++ {
+		var resp struct {
+			linodePage
+			Data []linodeRecord `json:"data"`
+		}
+		path := fmt.Sprintf("/domains/%d/records?page=%d&page_size=500", domainID, page)
+		if err := l.api.do("GET", path, nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, record := range resp.Data {
+			if strings.EqualFold(record.Name, name) && record.Type == recordType {
+				records = append(records, DNSRecord{
+					RecordID: strconv.FormatInt(record.ID, 10),
+					Value:    record.Target,
+				})
+			}
+		}
+		if resp.Page >= resp.Pages {
+			return records, nil
+		}
+	}
+}
+
+// CreateRecord creates a new DNS record
+func (l *LinodeProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	domainID, err := l.domainID(domain)
+	if err != nil {
+		return "", err
+	}
+
+	createReq := linodeRecord{
+		Type:   recordType,
+		Name:   recordRelativeName(subdomain),
+		Target: value,
+		TTL:    l.ttl,
+	}
+
+	var resp linodeRecord
+	if err := l.api.do("POST", fmt.Sprintf("/domains/%d/records", domainID), createReq, &resp); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(resp.ID, 10), nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (l *LinodeProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	domainID, err := l.domainID(domain)
+	if err != nil {
+		return err
+	}
+
+	updateReq := linodeRecord{
+		Name:   recordRelativeName(subdomain),
+		Target: value,
+	}
+
+	return l.api.do("PUT", fmt.Sprintf("/domains/%d/records/%s", domainID, recordID), updateReq, nil)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (l *LinodeProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	domainID, err := l.domainID(domain)
+	if err != nil {
+		return err
+	}
+
+	return l.api.do("DELETE", fmt.Sprintf("/domains/%d/records/%s", domainID, recordID), nil, nil)
+}
+
+// domainID resolves the numeric domain ID for domain and caches it
+func (l *LinodeProvider) domainID(domain string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if id, ok := l.domainIDs[domain]; ok {
+		return id, nil
+	}
+
+	for page := 1; ; page++ {
+		var resp struct {
+			linodePage
+			Data []linodeDomain `json:"data"`
+		}
+		if err := l.api.do("GET", fmt.Sprintf("/domains?page=%d&page_size=500", page), nil, &resp); err != nil {
+			return 0, err
+		}
+
+		for _, d := range resp.Data {
+			if strings.EqualFold(d.Domain, domain) {
+				l.domainIDs[domain] = d.ID
+				return d.ID, nil
+			}
+		}
+		if resp.Page >= resp.Pages {
+			return 0, permanentError(fmt.Errorf("domain not found: %s", domain))
+		}
+	}
+}
+
+// linodeErrorMessage extracts the reasons from an API error response
+func linodeErrorMessage(body []byte) string {
+	var errResp struct {
+		Errors []struct {
+			Field  string `json:"field"`
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+
+	var reasons []string
+	for _, e := range errResp.Errors {
+		if e.Field != "" {
+			reasons = append(reasons, e.Field+": "+e.Reason)
+		} else {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// linodeStandIn is a minimal Linode domains API. example.com is the second
+// of two domains, and listings are paged two items at a time.
+type linodeStandIn struct {
+	mu          sync.Mutex
+	records     []linodeRecord
+	nextID      int64
+	domainLists int
+}
+
+var linodeStandInDomains = []linodeDomain{{ID: 100, Domain: "example.org"}, {ID: 200, Domain: "example.com"}}
+
+func (s *linodeStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]string{{"reason": "Invalid Token"}}})
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	switch path := r.URL.Path; {
+	case path == "/domains" && r.Method == "GET":
+		s.domainLists++
+		// One domain per page, so the lookup has to page
+		index := page - 1
+		resp := map[string]interface{}{"page": page, "pages": len(linodeStandInDomains), "data": []linodeDomain{linodeStandInDomains[index]}}
+		json.NewEncoder(w).Encode(resp)
+
+	case path == "/domains/200/records" && r.Method == "GET":
+		pages := (len(s.records) + 1) / 2
+		if pages == 0 {
+			pages = 1
+		}
+		data := []linodeRecord{}
+		for i := (page - 1) * 2; i < page*2 && i < len(s.records); i++ {
+			data = append(data, s.records[i])
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"page": page, "pages": pages, "data": data})
+
+	case path == "/domains/200/records" && r.Method == "POST":
+		var record linodeRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		s.nextID++
+		record.ID = s.nextID
+		s.records = append(s.records, record)
+		json.NewEncoder(w).Encode(record)
+
+	case strings.HasPrefix(path, "/domains/200/records/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/domains/200/records/"), 10, 64)
+		for i := range s.records {
+			if s.records[i].ID != id {
+				continue
+			}
+			switch r.Method {
+			case "PUT":
+				var update linodeRecord
+				json.NewDecoder(r.Body).Decode(&update)
+				s.records[i].Name, s.records[i].Target = update.Name, update.Target
+				json.NewEncoder(w).Encode(s.records[i])
+			case "DELETE":
+				s.records = append(s.records[:i], s.records[i+1:]...)
+				w.Write([]byte("{}"))
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]string{{"reason": "Not found"}}})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]string{{"reason": "Not found"}}})
+	}
+}
+
+// TestLinodeDomainLookup checks that the domain ID is found on a later page
+// and looked up only once, and that records are found across pages
+func TestLinodeDomainLookup(t *testing.T) {
+	standIn := &linodeStandIn{nextID: 4, records: []linodeRecord{
+		{ID: 1, Type: "A", Name: "mail", Target: "192.0.2.50", TTL: 600},
+		{ID: 2, Type: "AAAA", Name: "www", Target: "2001:db8::1", TTL: 600},
+		{ID: 3, Type: "A", Name: "", Target: "192.0.2.3", TTL: 600},
+		{ID: 4, Type: "A", Name: "www", Target: "192.0.2.1", TTL: 600},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newLinodeProvider("token", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		records, err := p.GetRecords("example.com", "www", "A")
+		if err != nil {
+			t.Fatalf("GetRecords: %v", err)
+		}
+		if want := []DNSRecord{{RecordID: "4", Value: "192.0.2.1"}}; fmt.Sprint(records) != fmt.Sprint(want) {
+			t.Errorf("GetRecords returned %v, want %v", records, want)
+		}
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	if standIn.domainLists != 2 {
+		t.Errorf("domains listed %d times, want 2 pages listed once", standIn.domainLists)
+	}
+}
+
+// TestLinodeErrors checks that an unknown domain is a permanent error and
+// that API reasons reach the caller
+func TestLinodeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc((&linodeStandIn{}).serve))
+	defer server.Close()
+
+	p, _ := newLinodeProvider("token", server.URL, 300)
+	if _, err := p.GetRecords("example.net", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "domain not found") {
+		t.Errorf("unknown domain: got error %v, want a permanent not found error", err)
+	}
+
+	p, _ = newLinodeProvider("wrong", server.URL, 300)
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Invalid Token") {
+		t.Errorf("wrong token: got error %v, want a permanent invalid token error", err)
+	}
+}
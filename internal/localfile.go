@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"ddnsd/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// localFile is a file rewritten by the local DNS backends, with an optional
+// command run after each change to make the server reload it
+type localFile struct {
+	path          string
+	reloadCommand string
+	reloadTimeout time.Duration
+	log           *utils.Logger
+}
+
+// read returns the file contents. A missing file reads as empty.
+func (f *localFile) read() ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, permanentError(fmt.Errorf("failed to read %s: %v", f.path, err))
+	}
+	return data, nil
+}
+
+// write atomically replaces the file with data and runs the reload command
+func (f *localFile) write(data []byte) error {
+	if err := writeFileAtomic(f.path, data); err != nil {
+		return permanentError(err)
+	}
+
+	if f.reloadCommand == "" {
+		return nil
+	}
+	if err := runCommand(f.log, "reload", f.reloadCommand, nil, f.reloadTimeout); err != nil {
+		return permanentError(fmt.Errorf("file updated but %v", err))
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partially written file. The mode of
+// an existing file is kept.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set mode of %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	return nil
+}
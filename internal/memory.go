@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryProvider implements DNSProvider with records held in memory. It
+// needs no credentials and can inject faults, so the updater can be run and
+// exercised without a real DNS provider.
+type MemoryProvider struct {
+	mu      sync.Mutex
+	records []memoryRecord
+	nextID  int
+
+	// Fault injection
+	latency     time.Duration
+	failureRate float64
+	duplicates  bool
+	failures    []error
+}
+
+// memoryRecord is a record held by MemoryProvider
+type memoryRecord struct {
+	id         string
+	name       string
+	recordType string
+	value      string
+}
+
+// NewMemoryProvider creates an empty in-memory provider without faults
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{nextID: 1}
+}
+
+// newFaultyMemoryProvider creates an in-memory provider with the faults
+// selected by configuration
+func newFaultyMemoryProvider(latency time.Duration, failureRate float64, duplicates bool) (*MemoryProvider, error) {
+	m := NewMemoryProvider()
+	m.SetLatency(latency)
+	m.SetFailureRate(failureRate)
+	m.SetDuplicates(duplicates)
+	return m, nil
+}
+
+// SetLatency delays every call by d
+func (m *MemoryProvider) SetLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = d
+}
+
+// SetFailureRate makes the given fraction of calls, between 0 and 1, fail
+// with a retryable error
+func (m *MemoryProvider) SetFailureRate(rate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failureRate = rate
+}
+
+// SetDuplicates makes CreateRecord store every record twice, as providers
+// that allow duplicate records may end up doing
+func (m *MemoryProvider) SetDuplicates(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duplicates = enabled
+}
+
+// FailNext makes the next calls return errs, one error per call
+func (m *MemoryProvider) FailNext(errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = append(m.failures, errs...)
+}
+
+// Records returns all records for the name and type without injecting
+// faults
+func (m *MemoryProvider) Records(domain, subdomain, recordType string) []DNSRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var records []DNSRecord
+	name := strings.ToLower(recordFullName(domain, subdomain))
+	for _, record := range m.records {
+		if record.name == name && record.recordType == recordType {
+			records = append(records, DNSRecord{RecordID: record.id, Value: record.value})
+		}
+	}
+	return records
+}
+
+// GetRecords returns the records for the name and type
+func (m *MemoryProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	if err := m.fault(); err != nil {
+		return nil, err
+	}
+	return m.Records(domain, subdomain, recordType), nil
+}
+
+// CreateRecord stores a new record
+func (m *MemoryProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := m.fault(); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := memoryRecord{
+		name:       strings.ToLower(recordFullName(domain, subdomain)),
+		recordType: recordType,
+		value:      value,
+	}
+	id := m.add(record)
+	if m.duplicates {
+		m.add(record)
+	}
+	return id, nil
+}
+
+// UpdateRecord changes the value of the record with recordID
+func (m *MemoryProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	if err := m.fault(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.records {
+		if m.records[i].id == recordID {
+			m.records[i].value = value
+			return nil
+		}
+	}
+	return permanentError(fmt.Errorf("record not found: %s", recordID))
+}
+
+// DeleteRecord removes the record with recordID
+func (m *MemoryProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	if err := m.fault(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.records {
+		if m.records[i].id == recordID {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+			return nil
+		}
+	}
+	return permanentError(fmt.Errorf("record not found: %s", recordID))
+}
+
+// add stores a record under a new ID. The caller holds mu.
+func (m *MemoryProvider) add(record memoryRecord) string {
+	record.id = strconv.Itoa(m.nextID)
+	m.nextID++
+	m.records = append(m.records, record)
+	return record.id
+}
+
+// fault waits out the latency and returns the injected error for a call,
+// if any
+func (m *MemoryProvider) fault() error {
+	m.mu.Lock()
+	latency := m.latency
+	var err error
+	if len(m.failures) > 0 {
+		err, m.failures = m.failures[0], m.failures[1:]
+	} else if m.failureRate > 0 && rand.Float64() < m.failureRate {
+		err = retryableError(fmt.Errorf("injected failure"), 0)
+	}
+	m.mu.Unlock()
+
+	time.Sleep(latency)
+	return err
+}
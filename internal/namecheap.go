@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NamecheapProvider implements DNSProvider for Namecheap. The API can only
+// replace a domain's whole host list, so every change is a read-modify-write
+// that carries all other records over unchanged.
+type NamecheapProvider struct {
+	apiUser  string
+	apiKey   string
+	clientIP string
+	checkURL string
+	endpoint string
+	ttl      int
+	client   *http.Client
+
+	// mu serialises read-modify-write cycles so concurrent updates of
+	// different hosts in a domain cannot overwrite each other
+	mu sync.Mutex
+}
+
+// Namecheap API structures
+type namecheapResponse struct {
+	Status string `xml:"Status,attr"`
+	Errors []struct {
+		Number  string `xml:"Number,attr"`
+		Message string `xml:",chardata"`
+	} `xml:"Errors>Error"`
+	HostsResult struct {
+		EmailType string          `xml:"EmailType,attr"`
+		Hosts     []namecheapHost `xml:"host"`
+	} `xml:"CommandResponse>DomainDNSGetHostsResult"`
+}
+
+type namecheapHost struct {
+	Name    string `xml:"Name,attr"`
+	Type    string `xml:"Type,attr"`
+	Address string `xml:"Address,attr"`
+	MXPref  string `xml:"MXPref,attr"`
+	TTL     string `xml:"TTL,attr"`
+}
+
+// newNamecheapProvider creates a new Namecheap provider instance. clientIP
+// is the whitelisted address sent with each call; when empty it is looked
+// up with checkURL before every operation, so a changed address is picked
+// up by the next update. endpoint overrides the API URL.
+func newNamecheapProvider(apiUser, apiKey, clientIP, checkURL, endpoint string, ttl int) (*NamecheapProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.namecheap.com/xml.response"
+	}
+
+	return &NamecheapProvider{
+		apiUser:  apiUser,
+		apiKey:   apiKey,
+		clientIP: clientIP,
+		checkURL: checkURL,
+		endpoint: endpoint,
+		ttl:      ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. Namecheap host IDs change
+// on every update, so each value doubles as its record ID.
+func (n *NamecheapProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	clientIP, err := n.currentClientIP()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.getHosts(clientIP, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, host := range resp.HostsResult.Hosts {
+		if strings.EqualFold(host.Name, subdomain) && host.Type == recordType {
+			values = append(values, host.Address)
+		}
+	}
+	return setRecords(values), nil
+}
+
+// CreateRecord adds a host record
+func (n *NamecheapProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := n.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the host record with value recordID
+func (n *NamecheapProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return n.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the host record with value recordID
+func (n *NamecheapProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return n.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save reads the host list, swaps oldValue for newValue among the hosts
+// with the name and type, and writes the list back with every other host
+// unchanged
+func (n *NamecheapProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	clientIP, err := n.currentClientIP()
+	if err != nil {
+		return err
+	}
+	resp, err := n.getHosts(clientIP, domain)
+	if err != nil {
+		return err
+	}
+
+	// Matching hosts are replaced in place of the first one, keeping its TTL
+	var hosts []namecheapHost
+	var values []string
+	insertAt := -1
+	ttl := strconv.Itoa(n.ttl)
+	for _, host := range resp.HostsResult.Hosts {
+		if strings.EqualFold(host.Name, subdomain) && host.Type == recordType {
+			if insertAt < 0 {
+				insertAt = len(hosts)
+				ttl = host.TTL
+			}
+			values = append(values, host.Address)
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	if insertAt < 0 {
+		insertAt = len(hosts)
+	}
+
+	var replaced []namecheapHost
+	for _, value := range replaceRecordValue(values, oldValue, newValue) {
+		replaced = append(replaced, namecheapHost{Name: subdomain, Type: recordType, Address: value, TTL: ttl})
+	}
+	hosts = append(hosts[:insertAt], append(replaced, hosts[insertAt:]...)...)
+
+	params := url.Values{}
+	if resp.HostsResult.EmailType != "" {
+		params.Set("EmailType", resp.HostsResult.EmailType)
+	}
+	for i, host := range hosts {
+		suffix := strconv.Itoa(i + 1)
+		params.Set("HostName"+suffix, host.Name)
+		params.Set("RecordType"+suffix, host.Type)
+		params.Set("Address"+suffix, host.Address)
+		if host.TTL != "" {
+			params.Set("TTL"+suffix, host.TTL)
+		}
+		if host.Type == "MX" {
+			params.Set("MXPref"+suffix, host.MXPref)
+		}
+	}
+
+	_, err = n.doRequest(clientIP, "namecheap.domains.dns.setHosts", domain, params)
+	return err
+}
+
+// currentClientIP returns the configured client IP, or looks up the
+// current public address when none is configured
+func (n *NamecheapProvider) currentClientIP() (string, error) {
+	if n.clientIP != "" {
+		return n.clientIP, nil
+	}
+	ip, err := getPublicIP(n.checkURL, "IPv4")
+	if err != nil {
+		return "", retryableError(fmt.Errorf("failed to get client IP: %v", err), 0)
+	}
+	return ip, nil
+}
+
+// getHosts returns the full host list of domain
+func (n *NamecheapProvider) getHosts(clientIP, domain string) (*namecheapResponse, error) {
+	return n.doRequest(clientIP, "namecheap.domains.dns.getHosts", domain, url.Values{})
+}
+
+// doRequest calls an API command for domain from clientIP. Parameters are
+// sent as a form body, since setHosts can exceed URL length limits.
+func (n *NamecheapProvider) doRequest(clientIP, command, domain string, params url.Values) (*namecheapResponse, error) {
+	sld, tld, ok := strings.Cut(domain, ".")
+	if !ok {
+		return nil, permanentError(fmt.Errorf("invalid domain: %s", domain))
+	}
+
+	params.Set("ApiUser", n.apiUser)
+	params.Set("ApiKey", n.apiKey)
+	params.Set("UserName", n.apiUser)
+	params.Set("ClientIp", clientIP)
+	params.Set("Command", command)
+	params.Set("SLD", sld)
+	params.Set("TLD", tld)
+
+	resp, err := n.client.PostForm(n.endpoint, params)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+	if statusErr := statusError(resp, body); statusErr != nil {
+		return nil, statusErr
+	}
+
+	var apiResp namecheapResponse
+	if err := xml.Unmarshal(body, &apiResp); err != nil {
+		return nil, permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	if apiResp.Status != "OK" {
+		if len(apiResp.Errors) == 0 {
+			return nil, permanentError(fmt.Errorf("API request failed: status %s", apiResp.Status))
+		}
+		apiErr := apiResp.Errors[0]
+		return nil, permanentError(fmt.Errorf("API request failed: %s - %s", apiErr.Number, strings.TrimSpace(apiErr.Message)))
+	}
+	return &apiResp, nil
+}
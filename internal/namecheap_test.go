@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// namecheapStandIn is a minimal Namecheap XML API holding one domain's
+// host list
+type namecheapStandIn struct {
+	mu        sync.Mutex
+	hosts     []namecheapHost
+	emailType string
+	clientIPs []string
+}
+
+func (s *namecheapStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ParseForm()
+	s.clientIPs = append(s.clientIPs, r.Form.Get("ClientIp"))
+	if r.Form.Get("ApiKey") != "key" || r.Form.Get("SLD") != "example" || r.Form.Get("TLD") != "com" {
+		fmt.Fprint(w, `<ApiResponse Status="ERROR"><Errors><Error Number="1011102">API Key is invalid</Error></Errors></ApiResponse>`)
+		return
+	}
+
+	switch r.Form.Get("Command") {
+	case "namecheap.domains.dns.getHosts":
+		type result struct {
+			EmailType string          `xml:"EmailType,attr"`
+			Hosts     []namecheapHost `xml:"host"`
+		}
+		out, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"ApiResponse"`
+			Status  string   `xml:"Status,attr"`
+			Result  result   `xml:"CommandResponse>DomainDNSGetHostsResult"`
+		}{Status: "OK", Result: result{EmailType: s.emailType, Hosts: s.hosts}})
+		w.Write(out)
+
+	case "namecheap.domains.dns.setHosts":
+		s.emailType = r.Form.Get("EmailType")
+		s.hosts = nil
+		for i := 1; r.Form.Has("HostName" + strconv.Itoa(i)); i++ {
+			n := strconv.Itoa(i)
+			s.hosts = append(s.hosts, namecheapHost{
+				Name:    r.Form.Get("HostName" + n),
+				Type:    r.Form.Get("RecordType" + n),
+				Address: r.Form.Get("Address" + n),
+				MXPref:  r.Form.Get("MXPref" + n),
+				TTL:     r.Form.Get("TTL" + n),
+			})
+		}
+		fmt.Fprint(w, `<ApiResponse Status="OK"><CommandResponse><DomainDNSSetHostsResult IsSuccess="true"/></CommandResponse></ApiResponse>`)
+	}
+}
+
+// TestNamecheapKeepsOtherHosts checks that rewriting the host list leaves
+// every unrelated host, including MX preferences and TTLs, as it was
+func TestNamecheapKeepsOtherHosts(t *testing.T) {
+	others := []namecheapHost{
+		{Name: "@", Type: "MX", Address: "mail.example.com.", MXPref: "10", TTL: "1800"},
+		{Name: "@", Type: "TXT", Address: "v=spf1 mx -all", TTL: "1800"},
+		{Name: "www", Type: "AAAA", Address: "2001:db8::1", TTL: "300"},
+		{Name: "blog", Type: "CNAME", Address: "example.github.io.", TTL: "1800"},
+	}
+	standIn := &namecheapStandIn{emailType: "MX"}
+	standIn.hosts = append([]namecheapHost{{Name: "www", Type: "A", Address: "192.0.2.1", TTL: "600"}}, others...)
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newNamecheapProvider("user", "key", "198.51.100.1", "", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "home", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("192.0.2.3", "example.com", "home", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := append([]namecheapHost{{Name: "www", Type: "A", Address: "192.0.2.2", TTL: "600"}}, others...)
+	if fmt.Sprint(standIn.hosts) != fmt.Sprint(want) {
+		t.Errorf("host list is\n%+v\nwant\n%+v", standIn.hosts, want)
+	}
+	if standIn.emailType != "MX" {
+		t.Errorf("EmailType is %q, want MX", standIn.emailType)
+	}
+}
+
+// TestNamecheapLooksUpClientIPEachTime checks that without a configured
+// client IP, a changed public address is used by the next call
+func TestNamecheapLooksUpClientIPEachTime(t *testing.T) {
+	standIn := &namecheapStandIn{}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	publicIP := "198.51.100.1"
+	check := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, publicIP)
+	}))
+	defer check.Close()
+
+	p, err := newNamecheapProvider("user", "key", "", check.URL, server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+		t.Fatal(err)
+	}
+	publicIP = "198.51.100.2"
+	if _, err := p.CreateRecord("example.com", "www", "A", "198.51.100.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"198.51.100.1", "198.51.100.2", "198.51.100.2"}
+	if fmt.Sprint(standIn.clientIPs) != fmt.Sprint(want) {
+		t.Errorf("sent client IPs %v, want %v", standIn.clientIPs, want)
+	}
+}
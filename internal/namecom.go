@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// NameComProvider implements DNSProvider for Name.com
+type NameComProvider struct {
+	api *restClient
+	ttl int
+}
+
+// Name.com API structures
+type nameComRecord struct {
+	ID     int64  `json:"id,omitempty"`
+	Host   string `json:"host"`
+	Type   string `json:"type"`
+	Answer string `json:"answer"`
+	TTL    int    `json:"ttl,omitempty"`
+}
+
+type nameComRecordsResponse struct {
+	Records  []nameComRecord `json:"records"`
+	NextPage int             `json:"nextPage"`
+}
+
+// newNameComProvider creates a new Name.com provider instance. endpoint
+// overrides the API URL.
+func newNameComProvider(username, token, endpoint string, ttl int) (*NameComProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.name.com/v4"
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+token)))
+
+	return &NameComProvider{
+		api: newRESTClient(endpoint, header, nameComErrorMessage),
+		ttl: ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (n *NameComProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	var records []DNSRecord
+	host := recordRelativeName(subdomain)
+	for page := 1; page != 0; {
+		var resp nameComRecordsResponse
+		path := fmt.Sprintf("/domains/%s/records?page=%d&perPage=1000", url.PathEscape(domain), page)
+		if err := n.api.do("GET", path, nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, record := range resp.Records {
+			if strings.EqualFold(record.Host, host) && record.Type == recordType {
+				records = append(records, DNSRecord{
+					RecordID: strconv.FormatInt(record.ID, 10),
+					Value:    record.Answer,
+				})
+			}
+		}
+		page = resp.NextPage
+	}
+	return records, nil
+}
+
+// CreateRecord creates a new DNS record
+func (n *NameComProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	createReq := nameComRecord{
+		Host:   recordRelativeName(subdomain),
+		Type:   recordType,
+		Answer: value,
+		TTL:    n.ttl,
+	}
+
+	var resp nameComRecord
+	if err := n.api.do("POST", fmt.Sprintf("/domains/%s/records", url.PathEscape(domain)), createReq, &resp); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(resp.ID, 10), nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (n *NameComProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	updateReq := nameComRecord{
+		Host:   recordRelativeName(subdomain),
+		Type:   recordType,
+		Answer: value,
+	}
+
+	path := fmt.Sprintf("/domains/%s/records/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	return n.api.do("PUT", path, updateReq, nil)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (n *NameComProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	path := fmt.Sprintf("/domains/%s/records/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	return n.api.do("DELETE", path, nil, nil)
+}
+
+// nameComErrorMessage extracts the message from an API error response
+func nameComErrorMessage(body []byte) string {
+	var errResp struct {
+		Message string `json:"message"`
+		Details string `json:"details"`
+	}
+	if json.Unmarshal(body, &errResp) != nil || errResp.Message == "" {
+		return ""
+	}
+	if errResp.Details != "" {
+		return errResp.Message + ": " + errResp.Details
+	}
+	return errResp.Message
+}
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// nameComStandIn is a minimal Name.com records API for example.com that
+// returns one record per page
+type nameComStandIn struct {
+	mu      sync.Mutex
+	records map[int64]nameComRecord
+	nextID  int64
+}
+
+func (s *nameComStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, token, _ := r.BasicAuth(); user != "user" || token != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Unauthenticated"})
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/domains/example.com/records")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+		return
+	}
+
+	switch {
+	case path == "" && r.Method == "GET":
+		var ids []int64
+		for id := range s.records {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		resp := nameComRecordsResponse{}
+		if page >= 1 && page <= len(ids) {
+			resp.Records = []nameComRecord{s.records[ids[page-1]]}
+			if page < len(ids) {
+				resp.NextPage = page + 1
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case path == "" && r.Method == "POST":
+		var record nameComRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		if net.ParseIP(record.Answer) == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid Argument", "details": "answer is not a valid address"})
+			return
+		}
+		s.nextID++
+		record.ID = s.nextID
+		s.records[record.ID] = record
+		json.NewEncoder(w).Encode(record)
+	default:
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/"), 10, 64)
+		record, exists := s.records[id]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			var update nameComRecord
+			json.NewDecoder(r.Body).Decode(&update)
+			record.Host, record.Answer = update.Host, update.Answer
+			s.records[id] = record
+			json.NewEncoder(w).Encode(record)
+		case "DELETE":
+			delete(s.records, id)
+			w.Write([]byte("{}"))
+		}
+	}
+}
+
+func newNameComTestProvider(t *testing.T, token string, records map[int64]nameComRecord) (*NameComProvider, *nameComStandIn) {
+	standIn := &nameComStandIn{records: records, nextID: int64(len(records))}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newNameComProvider("user", token, server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestNameComChanges checks that records are found across pages and that
+// changes leave other records alone
+func TestNameComChanges(t *testing.T) {
+	p, standIn := newNameComTestProvider(t, "token", map[int64]nameComRecord{
+		1: {ID: 1, Host: "", Type: "MX", Answer: "mail.example.com", TTL: 3600},
+		2: {ID: 2, Host: "www", Type: "A", Answer: "192.0.2.1", TTL: 600},
+		3: {ID: 3, Host: "www", Type: "AAAA", Answer: "2001:db8::1", TTL: 600},
+		4: {ID: 4, Host: "WWW", Type: "A", Answer: "192.0.2.9", TTL: 600},
+	})
+
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	want := []DNSRecord{{RecordID: "2", Value: "192.0.2.1"}, {RecordID: "4", Value: "192.0.2.9"}}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("GetRecords returned %v, want %v", records, want)
+	}
+
+	if err := p.UpdateRecord("2", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if id, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil || id != "5" {
+		t.Fatalf("CreateRecord: got ID %q and error %v, want ID 5", id, err)
+	}
+	if err := p.DeleteRecord("4", "example.com", "www", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	wantRecords := map[int64]nameComRecord{
+		1: {ID: 1, Host: "", Type: "MX", Answer: "mail.example.com", TTL: 3600},
+		2: {ID: 2, Host: "www", Type: "A", Answer: "192.0.2.2", TTL: 600},
+		3: {ID: 3, Host: "www", Type: "AAAA", Answer: "2001:db8::1", TTL: 600},
+		5: {ID: 5, Host: "", Type: "A", Answer: "192.0.2.3", TTL: 300},
+	}
+	if fmt.Sprint(standIn.records) != fmt.Sprint(wantRecords) {
+		t.Errorf("records are\n%v\nwant\n%v", standIn.records, wantRecords)
+	}
+}
+
+// TestNameComErrors checks that API messages and details reach the caller
+func TestNameComErrors(t *testing.T) {
+	p, _ := newNameComTestProvider(t, "wrong", map[int64]nameComRecord{})
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Unauthenticated") {
+		t.Errorf("wrong token: got error %v, want a permanent unauthenticated error", err)
+	}
+
+	p, _ = newNameComTestProvider(t, "token", map[int64]nameComRecord{})
+	if _, err := p.CreateRecord("example.com", "www", "A", "192.0.2"); err == nil || !strings.Contains(err.Error(), "Invalid Argument: answer is not a valid address") {
+		t.Errorf("invalid answer: got error %v, want the message and details", err)
+	}
+}
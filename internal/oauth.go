@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before expiry a cached token is refreshed
+const tokenExpiryMargin = time.Minute
+
+// oauthTokenResponse is the token endpoint response of RFC 6749
+type oauthTokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// cachedToken caches a bearer token until shortly before it expires
+type cachedToken struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	fetch  func() (string, time.Duration, error)
+}
+
+// Get returns the cached token, fetching a new one when it is missing or
+// about to expire
+func (c *cachedToken) Get() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+
+	token, lifetime, err := c.fetch()
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expiry = time.Now().Add(lifetime - tokenExpiryMargin)
+	return token, nil
+}
+
+// fetchOAuthToken posts form to an OAuth 2.0 token endpoint and returns the
+// access token and its lifetime
+func fetchOAuthToken(client *http.Client, tokenURL string, form url.Values) (string, time.Duration, error) {
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, permanentError(fmt.Errorf("failed to create token request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, requestError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, retryableError(fmt.Errorf("failed to read token response: %v", err), 0)
+	}
+
+	var tokenResp oauthTokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		if statusErr := statusError(resp, body); statusErr != nil {
+			return "", 0, statusErr
+		}
+		return "", 0, permanentError(fmt.Errorf("failed to parse token response: %v", err))
+	}
+
+	if tokenResp.Error != "" {
+		authErr := fmt.Errorf("authentication failed: %s - %s", tokenResp.Error, tokenResp.ErrorDescription)
+		if statusErr := statusError(resp, body); statusErr != nil && IsRetryable(statusErr) {
+			return "", 0, retryableError(authErr, retryAfter(statusErr))
+		}
+		return "", 0, permanentError(authErr)
+	}
+	if err := statusError(resp, body); err != nil {
+		return "", 0, err
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, permanentError(fmt.Errorf("authentication failed: empty access token"))
+	}
+
+	lifetime := time.Hour
+	if seconds, err := tokenResp.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		lifetime = time.Duration(seconds) * time.Second
+	}
+	return tokenResp.AccessToken, lifetime, nil
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ovhEndpoints maps OVH API regions to their base URLs
+var ovhEndpoints = map[string]string{
+	"ovh-eu": "https://eu.api.ovh.com/1.0",
+	"ovh-ca": "https://ca.api.ovh.com/1.0",
+	"ovh-us": "https://api.us.ovhcloud.com/1.0",
+}
+
+// OVHProvider implements DNSProvider for OVHcloud DNS
+type OVHProvider struct {
+	appKey      string
+	appSecret   string
+	consumerKey string
+	ttl         int
+	api         *restClient
+
+	// mu guards timeDelta, the offset between the API clock and the local
+	// clock, fetched once from /auth/time, and pending, the zones whose
+	// refresh is still due after a change
+	mu         sync.Mutex
+	timeDelta  time.Duration
+	timeSynced bool
+	pending    map[string]bool
+}
+
+// OVH API structures
+type ovhRecord struct {
+	ID        int64  `json:"id,omitempty"`
+	FieldType string `json:"fieldType,omitempty"`
+	SubDomain string `json:"subDomain"`
+	Target    string `json:"target"`
+	TTL       int    `json:"ttl,omitempty"`
+}
+
+// newOVHProvider creates a new OVH provider instance. region selects the
+// API endpoint (ovh-eu, ovh-ca or ovh-us); endpoint overrides it.
+func newOVHProvider(appKey, appSecret, consumerKey, region, endpoint string, ttl int) (*OVHProvider, error) {
+	if endpoint == "" {
+		if region == "" {
+			region = "ovh-eu"
+		}
+		var ok bool
+		if endpoint, ok = ovhEndpoints[region]; !ok {
+			return nil, fmt.Errorf("unknown OVH region: %s", region)
+		}
+	}
+
+	o := &OVHProvider{
+		appKey:      appKey,
+		appSecret:   appSecret,
+		consumerKey: consumerKey,
+		ttl:         ttl,
+		api:         newRESTClient(endpoint, nil, ovhErrorMessage),
+		pending:     make(map[string]bool),
+	}
+	o.api.sign = o.sign
+	return o, nil
+}
+
+// GetRecords retrieves the existing DNS records. A zone refresh still due
+// from an earlier change is run first.
+func (o *OVHProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	if err := o.syncTime(); err != nil {
+		return nil, err
+	}
+	if err := o.refreshPending(domain); err != nil {
+		return nil, err
+	}
+
+	subDomain := recordRelativeName(subdomain)
+	query := url.Values{}
+	query.Set("fieldType", recordType)
+	query.Set("subDomain", subDomain)
+
+	var ids []int64
+	if err := o.api.do("GET", fmt.Sprintf("/domain/zone/%s/record?%s", url.PathEscape(domain), query.Encode()), nil, &ids); err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	for _, id := range ids {
+		var record ovhRecord
+		if err := o.api.do("GET", fmt.Sprintf("/domain/zone/%s/record/%d", url.PathEscape(domain), id), nil, &record); err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(record.SubDomain, subDomain) && record.FieldType == recordType {
+			records = append(records, DNSRecord{
+				RecordID: strconv.FormatInt(record.ID, 10),
+				Value:    record.Target,
+			})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord creates a new DNS record and applies the zone change. A
+// failed refresh is not retried by posting the record again, which would
+// create a duplicate; the zone stays pending and is refreshed on the next
+// call.
+func (o *OVHProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := o.syncTime(); err != nil {
+		return "", err
+	}
+
+	createReq := ovhRecord{
+		FieldType: recordType,
+		SubDomain: recordRelativeName(subdomain),
+		Target:    value,
+		TTL:       o.ttl,
+	}
+
+	var record ovhRecord
+	if err := o.api.do("POST", fmt.Sprintf("/domain/zone/%s/record", url.PathEscape(domain)), createReq, &record); err != nil {
+		return "", err
+	}
+
+	recordID := strconv.FormatInt(record.ID, 10)
+	o.markPending(domain)
+	if err := o.refreshPending(domain); err != nil {
+		return recordID, permanentError(fmt.Errorf("record %s created but %v", recordID, err))
+	}
+	return recordID, nil
+}
+
+// UpdateRecord updates an existing DNS record and applies the zone change
+func (o *OVHProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	if err := o.syncTime(); err != nil {
+		return err
+	}
+
+	updateReq := ovhRecord{
+		SubDomain: recordRelativeName(subdomain),
+		Target:    value,
+	}
+
+	path := fmt.Sprintf("/domain/zone/%s/record/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	if err := o.api.do("PUT", path, updateReq, nil); err != nil {
+		return err
+	}
+	o.markPending(domain)
+	if err := o.refreshPending(domain); err != nil {
+		return retryableError(fmt.Errorf("record updated but %v", err), retryAfter(err))
+	}
+	return nil
+}
+
+// DeleteRecord deletes an existing DNS record and applies the zone change
+func (o *OVHProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	if err := o.syncTime(); err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/domain/zone/%s/record/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	if err := o.api.do("DELETE", path, nil, nil); err != nil {
+		return err
+	}
+	o.markPending(domain)
+	if err := o.refreshPending(domain); err != nil {
+		return permanentError(fmt.Errorf("record deleted but %v", err))
+	}
+	return nil
+}
+
+// markPending records that the zone needs a refresh to apply a change
+func (o *OVHProvider) markPending(domain string) {
+	o.mu.Lock()
+	o.pending[domain] = true
+	o.mu.Unlock()
+}
+
+// refreshPending applies record changes to the zone if a change left a
+// refresh due. The zone stays pending until the refresh succeeds.
+func (o *OVHProvider) refreshPending(domain string) error {
+	o.mu.Lock()
+	due := o.pending[domain]
+	o.mu.Unlock()
+	if !due {
+		return nil
+	}
+
+	if err := o.api.do("POST", fmt.Sprintf("/domain/zone/%s/refresh", url.PathEscape(domain)), nil, nil); err != nil {
+		return retryableError(fmt.Errorf("zone refresh failed: %v", err), retryAfter(err))
+	}
+
+	o.mu.Lock()
+	delete(o.pending, domain)
+	o.mu.Unlock()
+	return nil
+}
+
+// syncTime fetches the API server time once, so signatures stay valid when
+// the local clock drifts
+func (o *OVHProvider) syncTime() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.timeSynced {
+		return nil
+	}
+
+	var serverTime int64
+	if err := o.api.do("GET", "/auth/time", nil, &serverTime); err != nil {
+		return err
+	}
+	o.timeDelta = time.Until(time.Unix(serverTime, 0))
+	o.timeSynced = true
+	return nil
+}
+
+// sign adds the OVH authentication headers to req. The unauthenticated
+// /auth/time call is left unsigned.
+func (o *OVHProvider) sign(req *http.Request, body []byte) {
+	req.Header.Set("X-Ovh-Application", o.appKey)
+	if strings.HasSuffix(req.URL.Path, "/auth/time") {
+		return
+	}
+
+	o.mu.Lock()
+	timestamp := time.Now().Add(o.timeDelta).Unix()
+	o.mu.Unlock()
+
+	req.Header.Set("X-Ovh-Consumer", o.consumerKey)
+	req.Header.Set("X-Ovh-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Ovh-Signature", ovhSignature(o.appSecret, o.consumerKey, req.Method, req.URL.String(), string(body), timestamp))
+}
+
+// ovhSignature computes the OVH request signature:
+// "$1$" + hex(SHA1(secret+"+"+consumerKey+"+"+method+"+"+url+"+"+body+"+"+timestamp))
+func ovhSignature(appSecret, consumerKey, method, requestURL, body string, timestamp int64) string {
+	data := strings.Join([]string{appSecret, consumerKey, method, requestURL, body, strconv.FormatInt(timestamp, 10)}, "+")
+	sum := sha1.Sum([]byte(data))
+	return "$1$" + hex.EncodeToString(sum[:])
+}
+
+// ovhErrorMessage extracts the message from an API error response
+func ovhErrorMessage(body []byte) string {
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+	return errResp.Message
+}
@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"ddnsd/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOVHSignatureKnownAnswer checks request signatures against values
+// computed independently from the documented formula
+func TestOVHSignatureKnownAnswer(t *testing.T) {
+	tests := []struct {
+		method, url, body string
+		timestamp         int64
+		want              string
+	}{
+		{
+			"GET", "https://eu.api.ovh.com/1.0/domain/zone/example.com/record?fieldType=A&subDomain=www", "", 1700000000,
+			"$1$86684de6f3b2b87bae42a1d8681d7932ec687606",
+		},
+		{
+			"POST", "https://eu.api.ovh.com/1.0/domain/zone/example.com/record", `{"fieldType":"A","subDomain":"www","target":"192.0.2.1","ttl":300}`, 1700000042,
+			"$1$1573bd06ad382d41b06b07d0827c6ed4a46b7738",
+		},
+	}
+	for _, test := range tests {
+		if got := ovhSignature("app-secret", "consumer-key", test.method, test.url, test.body, test.timestamp); got != test.want {
+			t.Errorf("%s %s: got %s, want %s", test.method, test.url, got, test.want)
+		}
+	}
+}
+
+// ovhStandIn is a minimal OVH zone API for example.com that checks request
+// signatures against its own clock, which runs an hour ahead of the local one
+type ovhStandIn struct {
+	mu        sync.Mutex
+	records   map[int64]ovhRecord
+	nextID    int64
+	refreshes int
+
+	// failRefresh fails that many zone refreshes with a server error
+	failRefresh int
+}
+
+func (s *ovhStandIn) now() time.Time {
+	return time.Now().Add(time.Hour)
+}
+
+func (s *ovhStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/auth/time" {
+		json.NewEncoder(w).Encode(s.now().Unix())
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get("X-Ovh-Timestamp"), 10, 64)
+	signature := ovhSignature("app-secret", r.Header.Get("X-Ovh-Consumer"), r.Method, "http://"+r.Host+r.URL.RequestURI(), string(body), timestamp)
+	if r.Header.Get("X-Ovh-Application") != "app-key" || r.Header.Get("X-Ovh-Signature") != signature {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid signature"})
+		return
+	}
+	if drift := s.now().Unix() - timestamp; drift < -5 || drift > 5 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Query out of time"})
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/domain/zone/example.com/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "This service does not exist"})
+		return
+	}
+
+	switch {
+	case path == "refresh" && r.Method == "POST" && s.failRefresh > 0:
+		s.failRefresh--
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Internal server error"})
+	case path == "refresh" && r.Method == "POST":
+		s.refreshes++
+	case path == "record" && r.Method == "GET":
+		ids := []int64{}
+		for id, record := range s.records {
+			if record.FieldType == r.URL.Query().Get("fieldType") && record.SubDomain == r.URL.Query().Get("subDomain") {
+				ids = append(ids, id)
+			}
+		}
+		json.NewEncoder(w).Encode(ids)
+	case path == "record" && r.Method == "POST":
+		var record ovhRecord
+		json.Unmarshal(body, &record)
+		s.nextID++
+		record.ID = s.nextID
+		s.records[record.ID] = record
+		json.NewEncoder(w).Encode(record)
+	case strings.HasPrefix(path, "record/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "record/"), 10, 64)
+		record, exists := s.records[id]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "This record does not exist"})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(record)
+		case "PUT":
+			var update ovhRecord
+			json.Unmarshal(body, &update)
+			record.SubDomain, record.Target = update.SubDomain, update.Target
+			s.records[id] = record
+		case "DELETE":
+			delete(s.records, id)
+		}
+	}
+}
+
+// TestOVHChanges checks that signed requests are accepted by a server
+// whose clock differs from the local one and that each change refreshes
+// the zone
+func TestOVHChanges(t *testing.T) {
+	standIn := &ovhStandIn{
+		records: map[int64]ovhRecord{
+			1: {ID: 1, FieldType: "A", SubDomain: "www", Target: "192.0.2.1", TTL: 600},
+			2: {ID: 2, FieldType: "A", SubDomain: "www", Target: "192.0.2.9", TTL: 600},
+			3: {ID: 3, FieldType: "MX", SubDomain: "", Target: "10 mail.example.com.", TTL: 3600},
+		},
+		nextID: 3,
+	}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newOVHProvider("app-key", "app-secret", "consumer-key", "", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("GetRecords returned %v, want two records", records)
+	}
+	if err := p.UpdateRecord("1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	id, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3")
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2", "example.com", "www", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := map[int64]ovhRecord{
+		1: {ID: 1, FieldType: "A", SubDomain: "www", Target: "192.0.2.2", TTL: 600},
+		3: {ID: 3, FieldType: "MX", SubDomain: "", Target: "10 mail.example.com.", TTL: 3600},
+		4: {ID: 4, FieldType: "A", SubDomain: "", Target: "192.0.2.3", TTL: 300},
+	}
+	if id != "4" {
+		t.Errorf("CreateRecord returned ID %q, want 4", id)
+	}
+	if fmt.Sprint(standIn.records) != fmt.Sprint(want) {
+		t.Errorf("records are\n%v\nwant\n%v", standIn.records, want)
+	}
+	if standIn.refreshes != 3 {
+		t.Errorf("zone refreshed %d times, want 3", standIn.refreshes)
+	}
+}
+
+// TestOVHWrongSecret checks that a rejected signature is a permanent error
+// carrying the API message
+func TestOVHWrongSecret(t *testing.T) {
+	standIn := &ovhStandIn{records: map[int64]ovhRecord{}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newOVHProvider("app-key", "wrong-secret", "consumer-key", "", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.GetRecords("example.com", "www", "A")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "Invalid signature") {
+		t.Errorf("got error %v, want a permanent invalid signature error", err)
+	}
+}
+
+// TestOVHRefreshFailure checks that a zone refresh failing after a record
+// was posted does not post it again on retry, and that the refresh is run
+// on the next call instead
+func TestOVHRefreshFailure(t *testing.T) {
+	standIn := &ovhStandIn{records: map[int64]ovhRecord{}, failRefresh: 1}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newOVHProvider("app-key", "app-secret", "consumer-key", "", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	log := utils.NewLogger("")
+
+	err = policy.Do(log, "Create record", func() error {
+		_, err := p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+		return err
+	})
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "record 1 created but zone refresh failed") {
+		t.Errorf("CreateRecord = %v, want a permanent refresh failure", err)
+	}
+
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	if fmt.Sprint(records) != "[{1 192.0.2.1}]" {
+		t.Errorf("GetRecords returned %v, want the one created record", records)
+	}
+
+	// An update whose refresh fails is retried, as putting it again is safe
+	standIn.mu.Lock()
+	standIn.failRefresh = 1
+	standIn.mu.Unlock()
+	err = policy.Do(log, "Modify record", func() error {
+		return p.UpdateRecord("1", "example.com", "www", "A", "192.0.2.2")
+	})
+	if err != nil {
+		t.Errorf("UpdateRecord: %v", err)
+	}
+	if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := map[int64]ovhRecord{1: {ID: 1, FieldType: "A", SubDomain: "www", Target: "192.0.2.2", TTL: 300}}
+	if fmt.Sprint(standIn.records) != fmt.Sprint(want) {
+		t.Errorf("records are %v, want %v", standIn.records, want)
+	}
+	// The create's refresh on the next call and the update's retried one;
+	// the GetRecords after the update has nothing left to refresh
+	if standIn.refreshes != 2 || standIn.failRefresh != 0 {
+		t.Errorf("zone refreshed %d times with %d failures left, want 2 and 0", standIn.refreshes, standIn.failRefresh)
+	}
+}
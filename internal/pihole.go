@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// PiholeProvider implements DNSProvider for Pi-hole local DNS records,
+// through the REST API of Pi-hole v6. Local records are hosts-format
+// entries in the dns.hosts setting.
+type PiholeProvider struct {
+	api      *restClient
+	password string
+
+	// mu guards the session
+	mu       sync.Mutex
+	sid      string
+	loggedIn bool
+}
+
+// Pi-hole API structures
+type piholeAuthResponse struct {
+	Session struct {
+		Valid bool    `json:"valid"`
+		SID   *string `json:"sid"`
+	} `json:"session"`
+}
+
+type piholeHostsResponse struct {
+	Config struct {
+		DNS struct {
+			Hosts []string `json:"hosts"`
+		} `json:"dns"`
+	} `json:"config"`
+}
+
+// newPiholeProvider creates a new Pi-hole provider instance. endpoint is the
+// web interface URL, such as http://pi.hole, and password the web or app
+// password.
+func newPiholeProvider(password, endpoint string) (*PiholeProvider, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("API_ENDPOINT (Pi-hole URL) must be set for pihole")
+	}
+
+	p := &PiholeProvider{
+		api:      newRESTClient(strings.TrimSuffix(endpoint, "/")+"/api", http.Header{}, piholeErrorMessage),
+		password: password,
+	}
+	p.api.sign = p.signRequest
+	p.api.unauthorized = p.expireSession
+	return p, nil
+}
+
+// GetRecords returns the addresses of the local records for the name in
+// the address family of recordType. The address doubles as the record ID.
+func (p *PiholeProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	entries, _, err := p.findEntries(recordFullName(domain, subdomain), recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	for _, entry := range entries {
+		value := entry.ip.String()
+		records = append(records, DNSRecord{RecordID: value, Value: value})
+	}
+	return records, nil
+}
+
+// CreateRecord adds a local record for the name
+func (p *PiholeProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := p.do("PUT", piholeHostPath(value+" "+recordFullName(domain, subdomain)), nil); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the local record with address recordID. The new
+// entry is added before the old one is removed, and keeps its aliases.
+func (p *PiholeProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	entry, line, err := p.findEntry(recordFullName(domain, subdomain), recordType, recordID)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return permanentError(fmt.Errorf("local record %s for %s not found", recordID, recordFullName(domain, subdomain)))
+	}
+
+	if err := p.do("PUT", piholeHostPath(value+" "+strings.Join(entry.names, " ")), nil); err != nil {
+		return err
+	}
+	if err := p.do("DELETE", piholeHostPath(line), nil); err != nil {
+		return permanentError(fmt.Errorf("new record added but removing %q failed: %v", line, err))
+	}
+	return nil
+}
+
+// DeleteRecord removes the name from the local record with address
+// recordID, keeping the record when it has other names
+func (p *PiholeProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	name := recordFullName(domain, subdomain)
+	entry, line, err := p.findEntry(name, recordType, recordID)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	if kept, ok := removeHostsName(line, name); ok {
+		if err := p.do("PUT", piholeHostPath(kept), nil); err != nil {
+			return err
+		}
+	}
+	return p.do("DELETE", piholeHostPath(line), nil)
+}
+
+// findEntry returns the first local record for name in the address family
+// of recordType with address value, and its raw entry
+func (p *PiholeProvider) findEntry(name, recordType, value string) (*hostsEntry, string, error) {
+	entries, lines, err := p.findEntries(name, recordType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i, entry := range entries {
+		if entry.ip.String() == value {
+			return &entry, lines[i], nil
+		}
+	}
+	return nil, "", nil
+}
+
+// findEntries returns the local records for name in the address family of
+// recordType, and their raw entries
+func (p *PiholeProvider) findEntries(name, recordType string) ([]hostsEntry, []string, error) {
+	var resp piholeHostsResponse
+	if err := p.do("GET", "/config/dns/hosts", &resp); err != nil {
+		return nil, nil, err
+	}
+
+	var entries []hostsEntry
+	var lines []string
+	for _, line := range resp.Config.DNS.Hosts {
+		if entry, ok := parseHostsLine(line); ok && entry.matches(name, recordType) {
+			entries = append(entries, entry)
+			lines = append(lines, line)
+		}
+	}
+	return entries, lines, nil
+}
+
+// do sends an API request, logging in first when there is no session. A
+// request rejected because the session expired is retried once after
+// logging in again.
+func (p *PiholeProvider) do(method, path string, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		if err := p.login(); err != nil {
+			return err
+		}
+
+		err := p.api.do(method, path, nil, out)
+		if err == nil || attempt > 0 || p.hasSession() {
+			return err
+		}
+	}
+}
+
+// login creates a session unless one is active
+func (p *PiholeProvider) login() error {
+	if p.hasSession() {
+		return nil
+	}
+
+	var resp piholeAuthResponse
+	if err := p.api.do("POST", "/auth", map[string]string{"password": p.password}, &resp); err != nil {
+		return err
+	}
+	if !resp.Session.Valid {
+		return permanentError(fmt.Errorf("login failed: check the Pi-hole password"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Without a password, Pi-hole needs no session ID
+	p.sid = ""
+	if resp.Session.SID != nil {
+		p.sid = *resp.Session.SID
+	}
+	p.loggedIn = true
+	return nil
+}
+
+// hasSession reports whether a session is active
+func (p *PiholeProvider) hasSession() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loggedIn
+}
+
+// expireSession forgets the session after the API rejected it
+func (p *PiholeProvider) expireSession() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loggedIn = false
+	p.sid = ""
+}
+
+// signRequest adds the session ID to a request
+func (p *PiholeProvider) signRequest(req *http.Request, body []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sid != "" {
+		req.Header.Set("X-FTL-SID", p.sid)
+	}
+}
+
+// piholeHostPath returns the API path of a local record entry
+func piholeHostPath(entry string) string {
+	return "/config/dns/hosts/" + url.PathEscape(entry)
+}
+
+// piholeErrorMessage extracts the message from an API error response
+func piholeErrorMessage(body []byte) string {
+	var errResp struct {
+		Error struct {
+			Message string  `json:"message"`
+			Hint    *string `json:"hint"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) != nil || errResp.Error.Message == "" {
+		return ""
+	}
+	if errResp.Error.Hint != nil && *errResp.Error.Hint != "" {
+		return errResp.Error.Message + " (" + *errResp.Error.Hint + ")"
+	}
+	return errResp.Error.Message
+}
@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// piholeStandIn is a minimal Pi-hole v6 API holding the dns.hosts setting.
+// It issues numbered session IDs and accepts only the latest one.
+type piholeStandIn struct {
+	mu     sync.Mutex
+	hosts  []string
+	sid    string
+	logins int
+}
+
+func (s *piholeStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/api/auth" && r.Method == "POST" {
+		var login struct {
+			Password string `json:"password"`
+		}
+		json.NewDecoder(r.Body).Decode(&login)
+		if login.Password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"session":{"valid":false,"sid":null}}`)
+			return
+		}
+		s.logins++
+		s.sid = fmt.Sprintf("sid-%d", s.logins)
+		fmt.Fprintf(w, `{"session":{"valid":true,"sid":%q,"validity":300}}`, s.sid)
+		return
+	}
+
+	if s.sid == "" || r.Header.Get("X-FTL-SID") != s.sid {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"key":"unauthorized","message":"Unauthorized","hint":null}}`)
+		return
+	}
+
+	if r.URL.Path == "/api/config/dns/hosts" && r.Method == "GET" {
+		var resp piholeHostsResponse
+		resp.Config.DNS.Hosts = s.hosts
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	escaped, ok := strings.CutPrefix(r.URL.EscapedPath(), "/api/config/dns/hosts/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	entry, _ := url.PathUnescape(escaped)
+
+	index := -1
+	for i, line := range s.hosts {
+		if line == entry {
+			index = i
+		}
+	}
+	switch r.Method {
+	case "PUT":
+		if index >= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"key":"bad_request","message":"Item already present","hint":"Uniqueness of items is enforced"}}`)
+			return
+		}
+		s.hosts = append(s.hosts, entry)
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		if index < 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.hosts = append(s.hosts[:index], s.hosts[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newPiholeTestProvider(t *testing.T, password string, hosts ...string) (*PiholeProvider, *piholeStandIn) {
+	standIn := &piholeStandIn{hosts: hosts}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, err := newPiholeProvider(password, server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, standIn
+}
+
+// TestPiholeChanges checks that updates keep the aliases of a local record
+// and that deleting a name keeps the other names sharing its record
+func TestPiholeChanges(t *testing.T) {
+	p, standIn := newPiholeTestProvider(t, "password",
+		"192.0.2.1 www.example.com www",
+		"2001:db8::1 www.example.com",
+		"192.0.2.7 home.example.com nas.example.com",
+	)
+
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	if want := []DNSRecord{{RecordID: "192.0.2.1", Value: "192.0.2.1"}}; fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("GetRecords returned %v, want %v", records, want)
+	}
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("192.0.2.7", "example.com", "nas", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := []string{
+		"2001:db8::1 www.example.com",
+		"192.0.2.2 www.example.com www",
+		"192.0.2.7\thome.example.com",
+		"192.0.2.3 example.com",
+	}
+	if fmt.Sprint(standIn.hosts) != fmt.Sprint(want) {
+		t.Errorf("hosts are\n%q\nwant\n%q", standIn.hosts, want)
+	}
+	if standIn.logins != 1 {
+		t.Errorf("logged in %d times, want 1", standIn.logins)
+	}
+}
+
+// TestPiholeSessionExpiry checks that a request rejected because the
+// session expired logs in again and succeeds
+func TestPiholeSessionExpiry(t *testing.T) {
+	p, standIn := newPiholeTestProvider(t, "password", "192.0.2.1 www.example.com")
+
+	if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	standIn.mu.Lock()
+	standIn.sid = "expired"
+	standIn.mu.Unlock()
+
+	if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+		t.Fatalf("GetRecords after the session expired: %v", err)
+	}
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	if standIn.logins != 2 {
+		t.Errorf("logged in %d times, want 2", standIn.logins)
+	}
+}
+
+// TestPiholeErrors checks that a wrong password is a permanent error and
+// that API messages carry their hint
+func TestPiholeErrors(t *testing.T) {
+	p, _ := newPiholeTestProvider(t, "wrong")
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) {
+		t.Errorf("wrong password: got error %v, want a permanent error", err)
+	}
+
+	p, _ = newPiholeTestProvider(t, "password", "192.0.2.1 www.example.com")
+	_, err := p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+	if err == nil || !strings.Contains(err.Error(), "Item already present (Uniqueness of items is enforced)") {
+		t.Errorf("duplicate record: got error %v, want the message and hint", err)
+	}
+}
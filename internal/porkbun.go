@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PorkbunProvider implements DNSProvider for Porkbun DNS
+type PorkbunProvider struct {
+	apiKey    string
+	secretKey string
+	api       *restClient
+	ttl       int
+}
+
+// Porkbun API structures
+type porkbunRequest struct {
+	APIKey    string `json:"apikey"`
+	SecretKey string `json:"secretapikey"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Content   string `json:"content,omitempty"`
+	TTL       string `json:"ttl,omitempty"`
+}
+
+type porkbunResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	ID      json.Number `json:"id"`
+	Records []struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+		TTL     string `json:"ttl"`
+	} `json:"records"`
+}
+
+// newPorkbunProvider creates a new Porkbun provider instance. endpoint
+// overrides the API URL.
+func newPorkbunProvider(apiKey, secretKey, endpoint string, ttl int) (*PorkbunProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.porkbun.com/api/json/v3"
+	}
+
+	return &PorkbunProvider{
+		apiKey:    apiKey,
+		secretKey: secretKey,
+		api:       newRESTClient(endpoint, nil, porkbunErrorMessage),
+		ttl:       ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (p *PorkbunProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	path := fmt.Sprintf("/dns/retrieveByNameType/%s/%s/%s", url.PathEscape(domain), recordType, url.PathEscape(recordRelativeName(subdomain)))
+
+	var resp porkbunResponse
+	if err := p.do(path, p.request(), &resp); err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	fullName := recordFullName(domain, subdomain)
+	for _, record := range resp.Records {
+		if strings.EqualFold(record.Name, fullName) && record.Type == recordType {
+			records = append(records, DNSRecord{
+				RecordID: record.ID,
+				Value:    record.Content,
+			})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord creates a new DNS record
+func (p *PorkbunProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	createReq := p.request()
+	createReq.Name = recordRelativeName(subdomain)
+	createReq.Type = recordType
+	createReq.Content = value
+	createReq.TTL = fmt.Sprint(p.ttl)
+
+	var resp porkbunResponse
+	if err := p.do("/dns/create/"+url.PathEscape(domain), createReq, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.ID.String(), nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (p *PorkbunProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	editReq := p.request()
+	editReq.Name = recordRelativeName(subdomain)
+	editReq.Type = recordType
+	editReq.Content = value
+
+	var resp porkbunResponse
+	return p.do(fmt.Sprintf("/dns/edit/%s/%s", url.PathEscape(domain), url.PathEscape(recordID)), editReq, &resp)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (p *PorkbunProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	var resp porkbunResponse
+	return p.do(fmt.Sprintf("/dns/delete/%s/%s", url.PathEscape(domain), url.PathEscape(recordID)), p.request(), &resp)
+}
+
+// request returns a request body carrying the API credentials
+func (p *PorkbunProvider) request() porkbunRequest {
+	return porkbunRequest{
+		APIKey:    p.apiKey,
+		SecretKey: p.secretKey,
+	}
+}
+
+// do sends a request and checks the status in the response body. Porkbun
+// API calls are all POST requests with the credentials in the body.
+func (p *PorkbunProvider) do(path string, payload porkbunRequest, resp *porkbunResponse) error {
+	if err := p.api.do("POST", path, payload, resp); err != nil {
+		return err
+	}
+	if resp.Status != "SUCCESS" {
+		return permanentError(fmt.Errorf("API request failed: %s", resp.Message))
+	}
+	return nil
+}
+
+// porkbunErrorMessage extracts the message from an API error response
+func porkbunErrorMessage(body []byte) string {
+	var errResp porkbunResponse
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+	return errResp.Message
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// porkbunStandInRecord is a record held by porkbunStandIn
+type porkbunStandInRecord struct {
+	ID, Name, Type, Content, TTL string
+}
+
+// porkbunStandIn is a minimal Porkbun API for one domain
+type porkbunStandIn struct {
+	mu      sync.Mutex
+	records []porkbunStandInRecord
+	nextID  int
+}
+
+func (s *porkbunStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var req porkbunRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.APIKey != "pk1_key" || req.SecretKey != "sk1_secret" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ERROR", "message": "Invalid API key."})
+		return
+	}
+	fullName := strings.TrimPrefix(req.Name+".example.com", ".")
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	resp := map[string]interface{}{"status": "SUCCESS"}
+	switch {
+	case len(parts) == 5 && parts[1] == "retrieveByNameType":
+		name := strings.TrimPrefix(parts[4]+".example.com", ".")
+		var records []map[string]string
+		for _, record := range s.records {
+			if record.Name == name && record.Type == parts[3] {
+				records = append(records, map[string]string{"id": record.ID, "name": record.Name, "type": record.Type, "content": record.Content, "ttl": record.TTL})
+			}
+		}
+		resp["records"] = records
+	case len(parts) == 3 && parts[1] == "create":
+		s.nextID++
+		id := strconv.Itoa(s.nextID)
+		s.records = append(s.records, porkbunStandInRecord{id, fullName, req.Type, req.Content, req.TTL})
+		resp["id"] = s.nextID
+	case len(parts) == 4 && parts[1] == "edit":
+		for i, record := range s.records {
+			if record.ID == parts[3] {
+				s.records[i].Name, s.records[i].Type, s.records[i].Content = fullName, req.Type, req.Content
+			}
+		}
+	case len(parts) == 4 && parts[1] == "delete":
+		for i, record := range s.records {
+			if record.ID == parts[3] {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+				break
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// TestPorkbunKeepsOtherRecords checks that changes touch only the record
+// they are made to
+func TestPorkbunKeepsOtherRecords(t *testing.T) {
+	others := []porkbunStandInRecord{
+		{"2", "www.example.com", "A", "192.0.2.9", "600"},
+		{"3", "www.example.com", "AAAA", "2001:db8::1", "600"},
+		{"4", "example.com", "MX", "mail.example.com", "3600"},
+	}
+	standIn := &porkbunStandIn{nextID: 10}
+	standIn.records = append([]porkbunStandInRecord{{"1", "www.example.com", "A", "192.0.2.1", "600"}}, others...)
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newPorkbunProvider("pk1_key", "sk1_secret", server.URL, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("GetRecords returned %+v, %v; want the two www A records", records, err)
+	}
+	if err := p.UpdateRecord("1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	id, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3")
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord(id, "example.com", "@", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := append([]porkbunStandInRecord{{"1", "www.example.com", "A", "192.0.2.2", "600"}}, others...)
+	if fmt.Sprint(standIn.records) != fmt.Sprint(want) {
+		t.Errorf("records are\n%v\nwant\n%v", standIn.records, want)
+	}
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// PowerDNSProvider implements DNSProvider for the PowerDNS Authoritative
+// HTTP API
+type PowerDNSProvider struct {
+	api      *restClient
+	serverID string
+	ttl      int
+	rectify  bool
+	notify   bool
+
+	// pending holds the zones whose rectify or NOTIFY is still due after a
+	// change, so a failed one is retried even once the record is current
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// PowerDNS API structures
+type powerDNSRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type powerDNSRRSet struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        int              `json:"ttl,omitempty"`
+	ChangeType string           `json:"changetype,omitempty"`
+	Records    []powerDNSRecord `json:"records"`
+}
+
+type powerDNSZone struct {
+	RRSets []powerDNSRRSet `json:"rrsets"`
+}
+
+// newPowerDNSProvider creates a new PowerDNS provider instance. endpoint is
+// the API base URL, such as http://127.0.0.1:8081. When rectify or notify is
+// set, the zone is rectified or secondaries are notified after each change.
+func newPowerDNSProvider(apiKey, endpoint, serverID string, ttl int, rectify, notify bool) (*PowerDNSProvider, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("API_ENDPOINT (PowerDNS API URL) must be set for powerdns")
+	}
+
+	header := http.Header{}
+	header.Set("X-API-Key", apiKey)
+
+	return &PowerDNSProvider{
+		api:      newRESTClient(strings.TrimSuffix(endpoint, "/")+"/api/v1", header, powerDNSErrorMessage),
+		serverID: serverID,
+		ttl:      ttl,
+		rectify:  rectify,
+		notify:   notify,
+		pending:  make(map[string]bool),
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. PowerDNS stores records as
+// rrsets, so each value doubles as its record ID. Disabled records are
+// skipped. A rectify or NOTIFY still due from an earlier change is run
+// first.
+func (p *PowerDNSProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	if err := p.finishZone(domain); err != nil {
+		return nil, err
+	}
+
+	rrset, err := p.getRRSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if rrset == nil {
+		return nil, nil
+	}
+
+	var values []string
+	for _, record := range rrset.Records {
+		if !record.Disabled {
+			values = append(values, record.Content)
+		}
+	}
+	return setRecords(values), nil
+}
+
+// CreateRecord adds a value to the rrset, creating the rrset if needed
+func (p *PowerDNSProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := p.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the rrset with value
+func (p *PowerDNSProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return p.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the rrset
+func (p *PowerDNSProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return p.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save replaces the rrset with oldValue swapped for newValue, keeping the
+// TTL, the other values and disabled records. An rrset left empty is
+// deleted.
+func (p *PowerDNSProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	current, err := p.getRRSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	rrset := powerDNSRRSet{
+		Name:       recordFQDN(domain, subdomain),
+		Type:       recordType,
+		TTL:        p.ttl,
+		ChangeType: "REPLACE",
+	}
+
+	var values []string
+	if current != nil {
+		rrset.TTL = current.TTL
+		for _, record := range current.Records {
+			if record.Disabled {
+				rrset.Records = append(rrset.Records, record)
+			} else {
+				values = append(values, record.Content)
+			}
+		}
+	}
+	for _, value := range replaceRecordValue(values, oldValue, newValue) {
+		rrset.Records = append(rrset.Records, powerDNSRecord{Content: value})
+	}
+	if len(rrset.Records) == 0 {
+		if current == nil {
+			return nil
+		}
+		rrset.ChangeType = "DELETE"
+	}
+
+	patch := powerDNSZone{RRSets: []powerDNSRRSet{rrset}}
+	if err := p.api.do("PATCH", p.zonePath(domain), patch, nil); err != nil {
+		return err
+	}
+
+	if p.rectify || p.notify {
+		p.mu.Lock()
+		p.pending[domain] = true
+		p.mu.Unlock()
+	}
+	if err := p.finishZone(domain); err != nil {
+		return retryableError(fmt.Errorf("record updated but %v", err), 0)
+	}
+	return nil
+}
+
+// finishZone rectifies the zone and notifies secondaries if a change left
+// that due. The zone stays pending until both succeed.
+func (p *PowerDNSProvider) finishZone(domain string) error {
+	p.mu.Lock()
+	due := p.pending[domain]
+	p.mu.Unlock()
+	if !due {
+		return nil
+	}
+
+	if p.rectify {
+		if err := p.api.do("PUT", p.zonePath(domain)+"/rectify", nil, nil); err != nil {
+			return retryableError(fmt.Errorf("rectify failed: %v", err), 0)
+		}
+	}
+	if p.notify {
+		if err := p.api.do("PUT", p.zonePath(domain)+"/notify", nil, nil); err != nil {
+			return retryableError(fmt.Errorf("NOTIFY failed: %v", err), 0)
+		}
+	}
+
+	p.mu.Lock()
+	delete(p.pending, domain)
+	p.mu.Unlock()
+	return nil
+}
+
+// getRRSet returns the rrset for the name and type, or nil
+func (p *PowerDNSProvider) getRRSet(domain, subdomain, recordType string) (*powerDNSRRSet, error) {
+	name := recordFQDN(domain, subdomain)
+
+	query := url.Values{}
+	query.Set("rrset_name", name)
+	query.Set("rrset_type", recordType)
+
+	var zone powerDNSZone
+	err := p.api.do("GET", p.zonePath(domain)+"?"+query.Encode(), nil, &zone)
+	if err == errRESTNotFound {
+		return nil, permanentError(fmt.Errorf("zone not found: %s", domain))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers before 4.5 ignore the filter and return the whole zone
+	for _, rrset := range zone.RRSets {
+		if strings.EqualFold(rrset.Name, name) && rrset.Type == recordType {
+			return &rrset, nil
+		}
+	}
+	return nil, nil
+}
+
+// zonePath returns the API path of the zone for domain
+func (p *PowerDNSProvider) zonePath(domain string) string {
+	return fmt.Sprintf("/servers/%s/zones/%s", url.PathEscape(p.serverID), url.PathEscape(recordFQDN(domain, "@")))
+}
+
+// powerDNSErrorMessage extracts the message from an API error response
+func powerDNSErrorMessage(body []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+	return errResp.Error
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// powerDNSStandIn is a minimal PowerDNS API serving one zone
+type powerDNSStandIn struct {
+	mu          sync.Mutex
+	rrsets      map[string]powerDNSRRSet
+	patches     []powerDNSRRSet
+	rectified   int
+	notified    int
+	failRectify int
+}
+
+func newPowerDNSStandIn() (*powerDNSStandIn, *httptest.Server) {
+	s := &powerDNSStandIn{rrsets: make(map[string]powerDNSRRSet)}
+	return s, httptest.NewServer(http.HandlerFunc(s.serve))
+}
+
+func (s *powerDNSStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("X-API-Key") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	switch path := r.URL.Path; {
+	case r.Method == "GET" && path == "/api/v1/servers/localhost/zones/example.com.":
+		zone := powerDNSZone{RRSets: []powerDNSRRSet{}}
+		key := r.URL.Query().Get("rrset_name") + " " + r.URL.Query().Get("rrset_type")
+		if rrset, ok := s.rrsets[key]; ok {
+			zone.RRSets = append(zone.RRSets, rrset)
+		}
+		json.NewEncoder(w).Encode(zone)
+
+	case r.Method == "PATCH" && path == "/api/v1/servers/localhost/zones/example.com.":
+		var zone powerDNSZone
+		if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, rrset := range zone.RRSets {
+			s.patches = append(s.patches, rrset)
+			key := rrset.Name + " " + rrset.Type
+			if rrset.ChangeType == "DELETE" {
+				delete(s.rrsets, key)
+				continue
+			}
+			rrset.ChangeType = ""
+			s.rrsets[key] = rrset
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "PUT" && strings.HasSuffix(path, "/rectify"):
+		if s.failRectify > 0 {
+			s.failRectify--
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Rectify failed"})
+			return
+		}
+		s.rectified++
+		json.NewEncoder(w).Encode(map[string]string{"result": "Rectified"})
+
+	case r.Method == "PUT" && strings.HasSuffix(path, "/notify"):
+		s.notified++
+		json.NewEncoder(w).Encode(map[string]string{"result": "Notification queued"})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Not Found"})
+	}
+}
+
+// TestPowerDNSReplaceKeepsTTLAndDisabledRecords checks that a change
+// replaces the rrset with its TTL and disabled records intact
+func TestPowerDNSReplaceKeepsTTLAndDisabledRecords(t *testing.T) {
+	standIn, server := newPowerDNSStandIn()
+	defer server.Close()
+	standIn.rrsets["www.example.com. A"] = powerDNSRRSet{
+		Name: "www.example.com.", Type: "A", TTL: 3600,
+		Records: []powerDNSRecord{{Content: "192.0.2.1"}, {Content: "192.0.2.99", Disabled: true}},
+	}
+
+	p, err := newPowerDNSProvider("secret", server.URL, "localhost", 300, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+
+	patch := standIn.patches[len(standIn.patches)-1]
+	if patch.ChangeType != "REPLACE" || patch.TTL != 3600 {
+		t.Errorf("patch has changetype %q and TTL %d, want REPLACE and 3600", patch.ChangeType, patch.TTL)
+	}
+	got := standIn.rrsets["www.example.com. A"].Records
+	want := []powerDNSRecord{{Content: "192.0.2.99", Disabled: true}, {Content: "192.0.2.2"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("rrset holds %+v, want %+v", got, want)
+	}
+}
+
+// TestPowerDNSRetriesRectify checks that a rectify failing after the rrset
+// was written is retried, including by the next lookup once the record is
+// already current
+func TestPowerDNSRetriesRectify(t *testing.T) {
+	standIn, server := newPowerDNSStandIn()
+	defer server.Close()
+	standIn.failRectify = 1
+
+	p, err := newPowerDNSProvider("secret", server.URL, "localhost", 300, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+	if !IsRetryable(err) {
+		t.Fatalf("CreateRecord with a failing rectify: got error %v, want a retryable error", err)
+	}
+	if standIn.notified != 0 {
+		t.Errorf("NOTIFY sent before the zone was rectified")
+	}
+
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	if len(records) != 1 || records[0].Value != "192.0.2.1" {
+		t.Errorf("GetRecords returned %+v, want 192.0.2.1", records)
+	}
+	if standIn.rectified != 1 || standIn.notified != 1 {
+		t.Errorf("rectified %d and notified %d times, want 1 each", standIn.rectified, standIn.notified)
+	}
+
+	if _, err := p.GetRecords("example.com", "www", "A"); err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	if standIn.rectified != 1 || standIn.notified != 1 {
+		t.Errorf("rectify or NOTIFY repeated without a change")
+	}
+}
@@ -2,7 +2,10 @@ package internal
 
 import (
 	"ddnsd/config"
+	"ddnsd/utils"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // DNSRecord represents a DNS record
@@ -11,25 +14,173 @@ type DNSRecord struct {
 	Value    string
 }
 
-// DNSProvider defines the interface for DNS providers
+// DNSProvider defines the interface for DNS providers. GetRecords returns
+// every record for the name and type, or none when there are none.
 type DNSProvider interface {
-	GetRecord(domain, subdomain, recordType string) (*DNSRecord, error)
+	GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error)
 	CreateRecord(domain, subdomain, recordType, value string) (string, error)
 	UpdateRecord(recordID, domain, subdomain, recordType, value string) error
+	DeleteRecord(recordID, domain, subdomain, recordType string) error
 }
 
 // NewDNSProvider creates a new DNS provider based on configuration
 func NewDNSProvider(cfg *config.Config) (DNSProvider, error) {
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := cfg.RateLimit
+	if rate <= 0 {
+		rate = defaultRateLimits[cfg.Provider]
+	}
+	if rate <= 0 {
+		return provider, nil
+	}
+
+	return &rateLimitedProvider{
+		provider: provider,
+		limiter:  newTokenBucket(rate, cfg.RateLimitBurst),
+	}, nil
+}
+
+// newProvider creates the provider implementation selected by configuration
+func newProvider(cfg *config.Config) (DNSProvider, error) {
 	switch cfg.Provider {
 	case "dnspod":
-		return newDNSPodProvider(cfg.SecretID, cfg.SecretKey)
+		return newDNSPodProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint)
 	case "cloudflare":
-		return newCloudflareProvider(cfg.SecretID, cfg.SecretKey)
+		return newCloudflareProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint)
 	case "aliyun":
-		return newAliyunProvider(cfg.SecretID, cfg.SecretKey, true) // China edition
+		return newAliyunProvider(cfg.SecretID, cfg.SecretKey, true, cfg.APIEndpoint) // China edition
 	case "alibabacloud":
-		return newAliyunProvider(cfg.SecretID, cfg.SecretKey, false) // International edition
+		return newAliyunProvider(cfg.SecretID, cfg.SecretKey, false, cfg.APIEndpoint) // International edition
+	case "rfc2136":
+		return newRFC2136Provider(cfg.SecretID, cfg.SecretKey, cfg.TSIGAlgorithm, cfg.RFC2136Server, cfg.RFC2136Transport, cfg.RecordTTL)
+	case "route53":
+		return newRoute53Provider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "gcloud":
+		return newGoogleCloudProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "azure":
+		return newAzureProvider(cfg.SecretID, cfg.SecretKey, cfg.AzureTenantID, cfg.AzureSubscriptionID, cfg.AzureResourceGroup,
+			cfg.AuthEndpoint, cfg.APIEndpoint, cfg.RecordTTL)
+	case "huaweicloud":
+		return newHuaweiCloudProvider(cfg.SecretID, cfg.SecretKey, cfg.Region, cfg.APIEndpoint, cfg.RecordTTL)
+	case "volcengine":
+		return newVolcengineProvider(cfg.SecretID, cfg.SecretKey, cfg.Region, cfg.APIEndpoint, cfg.RecordTTL)
+	case "digitalocean":
+		return newDigitalOceanProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "linode":
+		return newLinodeProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "vultr":
+		return newVultrProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "hetzner":
+		return newHetznerProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "desec":
+		return newDeSECProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "godaddy":
+		return newGoDaddyProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "namecheap":
+		return newNamecheapProvider(cfg.SecretID, cfg.SecretKey, cfg.NamecheapClientIP, cfg.IPv4CheckURL, cfg.APIEndpoint, cfg.RecordTTL)
+	case "porkbun":
+		return newPorkbunProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "gandi":
+		return newGandiProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "ovh":
+		return newOVHProvider(cfg.SecretID, cfg.SecretKey, cfg.OVHConsumerKey, cfg.Region, cfg.APIEndpoint, cfg.RecordTTL)
+	case "namecom":
+		return newNameComProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint, cfg.RecordTTL)
+	case "dyndns2", "noip", "dynu", "duckdns", "oray":
+		return newDynDNSProvider(cfg.Provider, cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint)
+	case "powerdns":
+		return newPowerDNSProvider(cfg.SecretKey, cfg.APIEndpoint, cfg.PowerDNSServerID, cfg.RecordTTL, cfg.PowerDNSRectify, cfg.PowerDNSNotify)
+	case "pihole":
+		return newPiholeProvider(cfg.SecretKey, cfg.APIEndpoint)
+	case "adguard":
+		return newAdGuardHomeProvider(cfg.SecretID, cfg.SecretKey, cfg.APIEndpoint)
+	case "hosts":
+		return newHostsFileProvider(newLocalFile(cfg))
+	case "zonefile":
+		return newZoneFileProvider(newLocalFile(cfg), cfg.RecordTTL)
+	case "webhook":
+		return newWebhookProvider(cfg.SecretID, cfg.SecretKey,
+			webhookEndpoint{method: cfg.WebhookGetMethod, url: cfg.WebhookGetURL, body: cfg.WebhookGetBody},
+			webhookEndpoint{method: cfg.WebhookCreateMethod, url: cfg.WebhookCreateURL, body: cfg.WebhookCreateBody},
+			webhookEndpoint{method: cfg.WebhookUpdateMethod, url: cfg.WebhookUpdateURL, body: cfg.WebhookUpdateBody},
+			webhookEndpoint{method: cfg.WebhookDeleteMethod, url: cfg.WebhookDeleteURL, body: cfg.WebhookDeleteBody},
+			cfg.WebhookHeaders, cfg.WebhookIDPath, cfg.WebhookValuePath, cfg.WebhookCreateIDPath, cfg.RecordTTL)
+	case "exec":
+		return newExecProvider(cfg.ExecCommand, time.Duration(cfg.ExecTimeout)*time.Second, cfg.RecordTTL, utils.NewLogger("[exec] "))
+	case "memory":
+		return newFaultyMemoryProvider(time.Duration(cfg.MemoryLatency)*time.Millisecond, cfg.MemoryFailureRate, cfg.MemoryDuplicates)
 	default:
 		return nil, fmt.Errorf("unsupported DNS provider: %s", cfg.Provider)
 	}
-}
\ No newline at end of file
+}
+
+// newLocalFile returns the file managed by a local backend. The reload
+// command shares the hook timeout.
+func newLocalFile(cfg *config.Config) *localFile {
+	return &localFile{
+		path:          cfg.RecordFile,
+		reloadCommand: cfg.ReloadCommand,
+		reloadTimeout: time.Duration(cfg.HookTimeout) * time.Second,
+		log:           utils.NewLogger("[" + cfg.Provider + "] "),
+	}
+}
+
+// recordFullName returns the fully qualified record name without a
+// trailing dot
+func recordFullName(domain, subdomain string) string {
+	if subdomain == "@" {
+		return domain
+	}
+	return subdomain + "." + domain
+}
+
+// recordFQDN returns the fully qualified record name with a trailing dot
+func recordFQDN(domain, subdomain string) string {
+	return strings.TrimSuffix(recordFullName(domain, subdomain), ".") + "."
+}
+
+// recordRelativeName returns the record name relative to the domain, which
+// is empty for the apex
+func recordRelativeName(subdomain string) string {
+	if subdomain == "@" {
+		return ""
+	}
+	return subdomain
+}
+
+// replaceRecordValue returns values with oldValue replaced by newValue. When
+// oldValue is empty or absent, newValue is appended; when newValue is empty,
+// oldValue is removed. Providers that store records as multi-value sets use
+// the record value as its ID.
+func replaceRecordValue(values []string, oldValue, newValue string) []string {
+	result := make([]string, 0, len(values)+1)
+	replaced := false
+	for _, v := range values {
+		switch {
+		case v == newValue:
+			// Drop duplicates of the new value; it is added once below
+		case v == oldValue && !replaced:
+			replaced = true
+		default:
+			result = append(result, v)
+		}
+	}
+	if newValue == "" {
+		return result
+	}
+	return append(result, newValue)
+}
+
+// setRecords returns a record per value of a multi-value set, using the
+// value as the record ID
+func setRecords(values []string) []DNSRecord {
+	var records []DNSRecord
+	for _, value := range values {
+		records = append(records, DNSRecord{RecordID: value, Value: value})
+	}
+	return records
+}
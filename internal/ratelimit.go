@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultRateLimits holds the default request rate (requests per second)
+// for each provider, kept below the published API quotas
+var defaultRateLimits = map[string]float64{
+	"dnspod":       10,
+	"cloudflare":   4,
+	"aliyun":       10,
+	"alibabacloud": 10,
+	"route53":      5,
+	"gcloud":       10,
+	"azure":        10,
+	"huaweicloud":  10,
+	"volcengine":   10,
+	"digitalocean": 4,
+	"linode":       10,
+	"vultr":        5,
+	"hetzner":      1,
+	"desec":        1,
+	"godaddy":      1,
+	"namecheap":    0.5,
+	"porkbun":      2,
+	"gandi":        5,
+	"ovh":          10,
+	"namecom":      5,
+	"dyndns2":      1,
+	"noip":         1,
+	"dynu":         1,
+	"duckdns":      1,
+	"oray":         1,
+}
+
+// tokenBucket is a simple token-bucket rate limiter
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a limiter allowing rate requests per second with
+// bursts of up to burst requests
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available and consumes it
+func (b *tokenBucket) Wait() {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		time.Sleep(wait)
+	}
+}
+
+// rateLimitedProvider wraps a DNSProvider so every API call takes a token
+type rateLimitedProvider struct {
+	provider DNSProvider
+	limiter  *tokenBucket
+}
+
+// GetRecords retrieves the existing DNS records
+func (r *rateLimitedProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	r.limiter.Wait()
+	return r.provider.GetRecords(domain, subdomain, recordType)
+}
+
+// CreateRecord creates a new DNS record
+func (r *rateLimitedProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	r.limiter.Wait()
+	return r.provider.CreateRecord(domain, subdomain, recordType, value)
+}
+
+// UpdateRecord updates an existing DNS record
+func (r *rateLimitedProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	r.limiter.Wait()
+	return r.provider.UpdateRecord(recordID, domain, subdomain, recordType, value)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (r *rateLimitedProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	r.limiter.Wait()
+	return r.provider.DeleteRecord(recordID, domain, subdomain, recordType)
+}
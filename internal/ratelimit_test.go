@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTokenBucket checks that a burst passes at once and further requests
+// are spaced at the configured rate
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(100, 3)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		bucket.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("burst of 3 took %v, want no wait", elapsed)
+	}
+
+	start = time.Now()
+	for i := 0; i < 5; i++ {
+		bucket.Wait()
+	}
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("5 requests beyond the burst took %v, want at least 45ms at 100 per second", elapsed)
+	}
+}
+
+// TestTokenBucketRefill checks that idle time refills the bucket up to the
+// burst size only
+func TestTokenBucketRefill(t *testing.T) {
+	bucket := newTokenBucket(100, 2)
+	bucket.Wait()
+	bucket.Wait()
+	time.Sleep(100 * time.Millisecond) // enough for 10 tokens
+
+	start := time.Now()
+	bucket.Wait()
+	bucket.Wait()
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("2 requests after idling took %v, want no wait", elapsed)
+	}
+	bucket.Wait()
+	if elapsed := time.Since(start); elapsed < 8*time.Millisecond {
+		t.Errorf("a third request after idling took %v, want a wait for the next token", elapsed)
+	}
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errRESTNotFound is returned by restClient.do for 404 responses
+var errRESTNotFound = permanentError(fmt.Errorf("resource not found"))
+
+// restClient sends JSON requests to a token-authenticated REST API. It is
+// shared by the providers whose APIs need nothing beyond static headers.
+type restClient struct {
+	baseURL string
+	header  http.Header
+	client  *http.Client
+
+	// errorMessage extracts the API error message from an error response
+	// body. It returns an empty string when the body has no message.
+	errorMessage func(body []byte) string
+
+	// sign, when set, is called with each request and its body just before
+	// it is sent, for APIs that sign requests
+	sign func(req *http.Request, body []byte)
+
+	// unauthorized, when set, is called on 401 responses, for APIs whose
+	// sessions expire
+	unauthorized func()
+}
+
+// newRESTClient creates a client for the API at baseURL that sends header
+// with every request
+func newRESTClient(baseURL string, header http.Header, errorMessage func(body []byte) string) *restClient {
+	return &restClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		header:  header,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		errorMessage: errorMessage,
+	}
+}
+
+// bearerHeader returns request headers carrying an OAuth-style bearer token
+func bearerHeader(token string) http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	return header
+}
+
+// do sends a request with an optional JSON payload and decodes the response
+// into out when it is not nil. path is relative to the base URL unless it is
+// an absolute URL, as returned in pagination links. Failures are classified
+// as retryable or permanent.
+func (r *restClient) do(method, path string, payload interface{}, out interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+		}
+	}
+
+	requestURL := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		requestURL = r.baseURL + path
+	}
+
+	req, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.sign != nil {
+		r.sign(req, body)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errRESTNotFound
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.unauthorized != nil {
+		r.unauthorized()
+	}
+
+	if statusErr := statusError(resp, respBody); statusErr != nil {
+		message := ""
+		if r.errorMessage != nil {
+			message = r.errorMessage(respBody)
+		}
+		if message == "" {
+			return statusErr
+		}
+		apiErr := fmt.Errorf("API request failed: %s", message)
+		if IsRetryable(statusErr) {
+			return retryableError(apiErr, retryAfter(statusErr))
+		}
+		return permanentError(apiErr)
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"ddnsd/utils"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ProviderError is a provider failure classified as retryable or permanent
+type ProviderError struct {
+	Err        error
+	Retryable  bool
+	RetryAfter time.Duration
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// retryableError marks err as transient, optionally with a server-provided delay
+func retryableError(err error, retryAfter time.Duration) error {
+	return &ProviderError{Err: err, Retryable: true, RetryAfter: retryAfter}
+}
+
+// permanentError marks err as not worth retrying
+func permanentError(err error) error {
+	return &ProviderError{Err: err}
+}
+
+// requestError wraps a transport-level failure, treating timeouts and
+// connection errors as retryable
+func requestError(err error) error {
+	wrapped := fmt.Errorf("API request failed: %v", err)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return retryableError(wrapped, 0)
+	}
+	return permanentError(wrapped)
+}
+
+// statusError classifies a non-2xx HTTP response. It returns nil for
+// successful responses.
+func statusError(resp *http.Response, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err := fmt.Errorf("HTTP response error: status code=%d, body=%s", resp.StatusCode, truncate(string(body), 200))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return retryableError(err, parseRetryAfter(resp.Header.Get("Retry-After")))
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		return retryableError(err, parseRetryAfter(resp.Header.Get("Retry-After")))
+	default:
+		return permanentError(err)
+	}
+}
+
+// parseRetryAfter parses a Retry-After header in either seconds or HTTP-date form
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying
+func IsRetryable(err error) bool {
+	var provErr *ProviderError
+	if errors.As(err, &provErr) {
+		return provErr.Retryable
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter returns the server-requested delay carried by err, if any
+func retryAfter(err error) time.Duration {
+	var provErr *ProviderError
+	if errors.As(err, &provErr) {
+		return provErr.RetryAfter
+	}
+	return 0
+}
+
+// truncate shortens s to at most n bytes for log output
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// RetryPolicy controls how failed provider calls are retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// newRetryPolicy builds a retry policy from configuration
+func newRetryPolicy(cfg *config.Config) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: cfg.RetryMaxAttempts,
+		BaseDelay:   time.Duration(cfg.RetryBaseDelay) * time.Second,
+		MaxDelay:    time.Duration(cfg.RetryMaxDelay) * time.Second,
+	}
+}
+
+// Do runs fn until it succeeds, fails permanently or runs out of attempts
+func (p RetryPolicy) Do(log *utils.Logger, operation string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if wait := retryAfter(err); wait > 0 {
+			if wait > p.MaxDelay {
+				log.Warning("%s failed, server asked to retry after %v which exceeds the maximum delay, giving up: %v",
+					operation, wait, err)
+				return err
+			}
+			delay = wait
+		}
+
+		log.Warning("%s failed (attempt %d/%d), retrying in %v: %v",
+			operation, attempt, p.MaxAttempts, delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+}
+
+// backoff returns the delay before the next attempt using exponential
+// backoff with full jitter
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + time.Millisecond
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"ddnsd/utils"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestStatusError checks the classification of HTTP responses
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		wantErr    bool
+		retryable  bool
+		wantDelay  time.Duration
+	}{
+		{http.StatusOK, "", false, false, 0},
+		{http.StatusNoContent, "", false, false, 0},
+		{http.StatusTooManyRequests, "7", true, true, 7 * time.Second},
+		{http.StatusTooManyRequests, "", true, true, 0},
+		{http.StatusServiceUnavailable, "2", true, true, 2 * time.Second},
+		{http.StatusInternalServerError, "", true, true, 0},
+		{http.StatusRequestTimeout, "", true, true, 0},
+		{http.StatusBadRequest, "", true, false, 0},
+		{http.StatusUnauthorized, "", true, false, 0},
+		{http.StatusNotFound, "", true, false, 0},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.retryAfter != "" {
+			resp.Header.Set("Retry-After", test.retryAfter)
+		}
+		err := statusError(resp, []byte("body"))
+		if (err != nil) != test.wantErr || IsRetryable(err) != test.retryable || retryAfter(err) != test.wantDelay {
+			t.Errorf("status %d, Retry-After %q: got %v (retryable %v, delay %v), want error %v, retryable %v, delay %v",
+				test.status, test.retryAfter, err, IsRetryable(err), retryAfter(err), test.wantErr, test.retryable, test.wantDelay)
+		}
+	}
+}
+
+// TestParseRetryAfter checks both forms of the Retry-After header
+func TestParseRetryAfter(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":     0,
+		"0":    0,
+		"-5":   0,
+		"soon": 0,
+		"120":  2 * time.Minute,
+		"1":    time.Second,
+		time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat): 0,
+	} {
+		if got := parseRetryAfter(value); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v, want about an hour", date, got)
+	}
+}
+
+// TestRetryPolicyDo checks which failures are retried and how often
+func TestRetryPolicyDo(t *testing.T) {
+	transient := retryableError(fmt.Errorf("throttled"), 0)
+	permanent := permanentError(fmt.Errorf("bad credentials"))
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive calls, then nil
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", nil, 1, nil},
+		{"permanent error", []error{permanent}, 1, permanent},
+		{"plain error", []error{fmt.Errorf("unclassified")}, 1, nil},
+		{"transient then success", []error{transient, transient}, 3, nil},
+		{"attempt cap", []error{transient, transient, transient, transient, transient}, 4, transient},
+		{"transient then permanent", []error{transient, permanent}, 2, permanent},
+		{"retry after beyond maximum", []error{retryableError(fmt.Errorf("throttled"), time.Hour)}, 1, nil},
+	}
+
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	for _, test := range tests {
+		calls := 0
+		err := policy.Do(utils.NewLogger(""), test.name, func() error {
+			calls++
+			if calls <= len(test.errs) {
+				return test.errs[calls-1]
+			}
+			return nil
+		})
+		if calls != test.wantCalls {
+			t.Errorf("%s: %d calls, want %d", test.name, calls, test.wantCalls)
+		}
+		wantErr := test.wantErr
+		if wantErr == nil && calls <= len(test.errs) {
+			wantErr = test.errs[calls-1]
+		}
+		if err != wantErr {
+			t.Errorf("%s: got error %v, want %v", test.name, err, wantErr)
+		}
+	}
+}
+
+// TestRetryPolicyHonoursRetryAfter checks that the server's delay replaces
+// a shorter backoff
+func TestRetryPolicyHonoursRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+	var calls []time.Time
+	err := policy.Do(utils.NewLogger(""), "test", func() error {
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			return retryableError(fmt.Errorf("throttled"), 50*time.Millisecond)
+		}
+		return nil
+	})
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("got error %v after %d calls, want success after 2", err, len(calls))
+	}
+	if gap := calls[1].Sub(calls[0]); gap < 50*time.Millisecond {
+		t.Errorf("retried after %v, want at least the 50ms the server asked for", gap)
+	}
+}
+
+// TestRetryBackoffBounds checks that backoff grows exponentially up to the
+// maximum delay and never returns zero
+func TestRetryBackoffBounds(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+	ceilings := map[int]time.Duration{1: 10, 2: 20, 3: 40, 4: 40, 10: 40, 70: 40}
+	for attempt, ceiling := range ceilings {
+		ceiling *= time.Millisecond
+		for i := 0; i < 1000; i++ {
+			if d := policy.backoff(attempt); d <= 0 || d > ceiling+time.Millisecond {
+				t.Fatalf("backoff(%d) = %v, want within (0, %v]", attempt, d, ceiling+time.Millisecond)
+			}
+		}
+	}
+
+	if d := (RetryPolicy{}).backoff(1); d != 0 {
+		t.Errorf("backoff without delays = %v, want 0", d)
+	}
+}
@@ -0,0 +1,296 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RFC2136Provider implements DNSProvider using RFC 2136 dynamic updates,
+// optionally authenticated with TSIG. It works with self-hosted servers such
+// as BIND and Knot.
+type RFC2136Provider struct {
+	server  string
+	useTCP  bool
+	ttl     uint32
+	key     *tsigKey
+	timeout time.Duration
+
+	mu      sync.Mutex
+	primary map[string]string
+}
+
+// newRFC2136Provider creates a new RFC 2136 provider instance. keyName and
+// secret may be empty to send unsigned updates. When server is empty the
+// primary nameserver of each zone is discovered from its SOA record.
+func newRFC2136Provider(keyName, secret, algorithm, server, transport string, ttl int) (*RFC2136Provider, error) {
+	p := &RFC2136Provider{
+		useTCP:  strings.EqualFold(transport, "tcp"),
+		ttl:     uint32(ttl),
+		timeout: 5 * time.Second,
+		primary: make(map[string]string),
+	}
+
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		p.server = server
+	}
+
+	if keyName != "" || secret != "" {
+		if keyName == "" || secret == "" {
+			return nil, fmt.Errorf("both TSIG key name and secret must be set")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TSIG secret: %v", err)
+		}
+		key, err := newTSIGKey(keyName, algorithm, decoded)
+		if err != nil {
+			return nil, err
+		}
+		p.key = key
+	}
+
+	return p, nil
+}
+
+// GetRecords retrieves the existing DNS records by querying the zone's
+// primary nameserver directly
+func (p *RFC2136Provider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	rrType, err := dnsRecordType(recordType)
+	if err != nil {
+		return nil, permanentError(err)
+	}
+	fqdn := recordFullName(domain, subdomain)
+
+	msg := newDNSMessage(dnsOpcodeQuery)
+	msg.Question = []dnsQuestion{{Name: fqdn, Type: rrType, Class: dnsClassIN}}
+
+	resp, err := p.exchange(domain, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.rcode() {
+	case dnsRcodeSuccess:
+	case dnsRcodeNXDomain:
+		return nil, nil
+	default:
+		return nil, rfc2136RcodeError("query", resp.rcode())
+	}
+
+	var records []DNSRecord
+	for _, rr := range resp.Answer {
+		if rr.Type == rrType && strings.EqualFold(strings.TrimSuffix(rr.Name, "."), fqdn) {
+			value := net.IP(rr.Data).String()
+			records = append(records, DNSRecord{
+				RecordID: value,
+				Value:    value,
+			})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord adds a record to the RRset. The record value doubles as its ID.
+func (p *RFC2136Provider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	add, err := p.rr(domain, subdomain, recordType, value)
+	if err != nil {
+		return "", permanentError(err)
+	}
+
+	if err := p.update(domain, []dnsRR{add}); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the record whose value is recordID with value in a
+// single atomic UPDATE
+func (p *RFC2136Provider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	add, err := p.rr(domain, subdomain, recordType, value)
+	if err != nil {
+		return permanentError(err)
+	}
+
+	updates := []dnsRR{add}
+	if recordID != "" && recordID != value {
+		remove, err := p.rr(domain, subdomain, recordType, recordID)
+		if err != nil {
+			return permanentError(err)
+		}
+		// Class NONE with TTL 0 deletes this exact record from the RRset
+		remove.Class = dnsClassNONE
+		remove.TTL = 0
+		updates = []dnsRR{remove, add}
+	}
+
+	return p.update(domain, updates)
+}
+
+// DeleteRecord removes the record whose value is recordID from the RRset
+func (p *RFC2136Provider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	remove, err := p.rr(domain, subdomain, recordType, recordID)
+	if err != nil {
+		return permanentError(err)
+	}
+	remove.Class = dnsClassNONE
+	remove.TTL = 0
+
+	return p.update(domain, []dnsRR{remove})
+}
+
+// rr builds an IN-class resource record for the given value
+func (p *RFC2136Provider) rr(domain, subdomain, recordType, value string) (dnsRR, error) {
+	rrType, err := dnsRecordType(recordType)
+	if err != nil {
+		return dnsRR{}, err
+	}
+	data, err := dnsIPRData(recordType, value)
+	if err != nil {
+		return dnsRR{}, err
+	}
+	return dnsRR{
+		Name:  recordFullName(domain, subdomain),
+		Type:  rrType,
+		Class: dnsClassIN,
+		TTL:   p.ttl,
+		Data:  data,
+	}, nil
+}
+
+// update sends an UPDATE message for zone with the given update section
+func (p *RFC2136Provider) update(zone string, updates []dnsRR) error {
+	msg := newDNSMessage(dnsOpcodeUpdate)
+	msg.Question = []dnsQuestion{{Name: zone, Type: dnsTypeSOA, Class: dnsClassIN}}
+	msg.Authority = updates
+
+	resp, err := p.exchange(zone, msg)
+	if err != nil {
+		return err
+	}
+	if resp.rcode() != dnsRcodeSuccess {
+		return rfc2136RcodeError("update", resp.rcode())
+	}
+	return nil
+}
+
+// exchange signs msg when a TSIG key is configured, sends it to the zone's
+// server and verifies the response
+func (p *RFC2136Provider) exchange(zone string, msg *dnsMessage) (*dnsMessage, error) {
+	server, err := p.serverFor(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var wire, requestMAC []byte
+	if p.key != nil {
+		wire, requestMAC, err = p.key.sign(msg, time.Now())
+	} else {
+		wire, err = msg.pack()
+	}
+	if err != nil {
+		return nil, permanentError(fmt.Errorf("failed to build DNS message: %v", err))
+	}
+
+	respWire, err := dnsExchange(server, wire, p.useTCP, p.timeout)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	resp, err := unpackDNSMessage(respWire)
+	if err != nil {
+		return nil, permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	if resp.ID != msg.ID || resp.Flags&dnsFlagResponse == 0 {
+		return nil, retryableError(fmt.Errorf("unexpected response from %s", server), 0)
+	}
+
+	if p.key != nil {
+		if err := p.key.verify(respWire, resp, requestMAC, time.Now()); err != nil {
+			return nil, permanentError(fmt.Errorf("TSIG verification failed: %v", err))
+		}
+	}
+	return resp, nil
+}
+
+// serverFor returns the configured server, or discovers the primary
+// nameserver of zone from its SOA record and caches it
+func (p *RFC2136Provider) serverFor(zone string) (string, error) {
+	if p.server != "" {
+		return p.server, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if server, ok := p.primary[zone]; ok {
+		return server, nil
+	}
+
+	server, err := discoverPrimary(zone, p.timeout)
+	if err != nil {
+		return "", retryableError(fmt.Errorf("failed to discover primary nameserver for %s: %v", zone, err), 0)
+	}
+	p.primary[zone] = server
+	return server, nil
+}
+
+// discoverPrimary looks up the NS records of zone, asks them for the SOA and
+// returns the address of its MNAME. The first reachable nameserver is used
+// when the MNAME does not resolve.
+func discoverPrimary(zone string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	nameservers, err := authoritativeServers(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	for _, ns := range nameservers {
+		msg := newDNSMessage(dnsOpcodeQuery)
+		msg.Question = []dnsQuestion{{Name: zone, Type: dnsTypeSOA, Class: dnsClassIN}}
+		wire, err := msg.pack()
+		if err != nil {
+			return "", err
+		}
+		respWire, err := dnsExchange(ns, wire, false, timeout)
+		if err != nil {
+			continue
+		}
+		resp, err := unpackDNSMessage(respWire)
+		if err != nil {
+			continue
+		}
+		for _, rr := range resp.Answer {
+			if rr.Type != dnsTypeSOA {
+				continue
+			}
+			mname, err := soaPrimary(rr.Data)
+			if err != nil {
+				break
+			}
+			addrs, err := net.DefaultResolver.LookupHost(ctx, mname)
+			if err == nil && len(addrs) > 0 {
+				return net.JoinHostPort(addrs[0], "53"), nil
+			}
+		}
+		return ns, nil
+	}
+	return "", fmt.Errorf("no nameserver of %s answered", zone)
+}
+
+// rfc2136RcodeError converts a response code into a classified error
+func rfc2136RcodeError(operation string, rcode int) error {
+	err := fmt.Errorf("DNS %s failed: %s", operation, dnsRcodeName(rcode))
+	if rcode == dnsRcodeServFail {
+		return retryableError(err, 0)
+	}
+	return permanentError(err)
+}
@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Known-answer vectors computed independently from RFC 8945 for the key
+// update-key with secret "secret-key-for-tsig-tests", hmac-sha256, a fudge
+// of 300 and message ID 0x1234
+const (
+	tsigTestSecret = "c2VjcmV0LWtleS1mb3ItdHNpZy10ZXN0cw=="
+
+	// UPDATE of example.com adding www.example.com 300 IN A 192.0.2.1,
+	// signed at 1700000000
+	tsigTestRequest = "123428000001000000010001076578616d706c6503636f6d000006000103777777076578616d706c6503636f6d00000100010000012c0004c00002010a7570646174652d6b65790000fa00ff00000000003d0b686d61632d7368613235360000006553f100012c00206786a712c72d2f7372794eb95d6cb0c3b052a5d5963460912bfba52eddf1970c123400000000"
+	tsigTestMAC     = "6786a712c72d2f7372794eb95d6cb0c3b052a5d5963460912bfba52eddf1970c"
+
+	// NOERROR response to the request, signed at 1700000001
+	tsigTestResponse = "1234a8000001000000000001076578616d706c6503636f6d00000600010a7570646174652d6b65790000fa00ff00000000003d0b686d61632d7368613235360000006553f101012c0020f27c2cf8d7c1f57e763faabdb8c468297553761d5339c854f9c59359cf053ad4123400000000"
+)
+
+func newTestTSIGKey(t *testing.T, secret string) *tsigKey {
+	t.Helper()
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := newTSIGKey("update-key.", "hmac-sha256", decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// TestTSIGSignKnownAnswer checks the signed request byte for byte
+func TestTSIGSignKnownAnswer(t *testing.T) {
+	key := newTestTSIGKey(t, tsigTestSecret)
+	data, _ := dnsIPRData("A", "192.0.2.1")
+	msg := newDNSMessage(dnsOpcodeUpdate)
+	msg.ID = 0x1234
+	msg.Question = []dnsQuestion{{Name: "example.com", Type: dnsTypeSOA, Class: dnsClassIN}}
+	msg.Authority = []dnsRR{{Name: "www.example.com", Type: dnsTypeA, Class: dnsClassIN, TTL: 300, Data: data}}
+
+	wire, mac, err := key.sign(msg, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(mac); got != tsigTestMAC {
+		t.Errorf("MAC is %s, want %s", got, tsigTestMAC)
+	}
+	if got := hex.EncodeToString(wire); got != tsigTestRequest {
+		t.Errorf("signed request is\n%s\nwant\n%s", got, tsigTestRequest)
+	}
+}
+
+// TestTSIGVerifyKnownAnswer checks that the signed response verifies, and
+// that tampering, a wrong key or a stale signature are rejected
+func TestTSIGVerifyKnownAnswer(t *testing.T) {
+	requestMAC, _ := hex.DecodeString(tsigTestMAC)
+	signedAt := time.Unix(1700000001, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		tamper func([]byte)
+		now    time.Time
+		err    string
+	}{
+		{"valid", tsigTestSecret, nil, signedAt, ""},
+		{"within fudge", tsigTestSecret, nil, signedAt.Add(299 * time.Second), ""},
+		{"stale", tsigTestSecret, nil, signedAt.Add(301 * time.Second), "outside fudge window"},
+		{"wrong secret", base64.StdEncoding.EncodeToString([]byte("another-secret")), nil, signedAt, "signature mismatch"},
+		{"tampered flags", tsigTestSecret, func(b []byte) { b[3] |= 5 }, signedAt, "signature mismatch"},
+		{"tampered MAC", tsigTestSecret, func(b []byte) { b[len(b)-7] ^= 1 }, signedAt, "signature mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wire, _ := hex.DecodeString(tsigTestResponse)
+			if tt.tamper != nil {
+				tt.tamper(wire)
+			}
+			msg, err := unpackDNSMessage(wire)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = newTestTSIGKey(t, tt.secret).verify(wire, msg, requestMAC, tt.now)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("verify failed: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// rfc2136StandIn is an in-process authoritative server for example.com
+// that accepts TSIG-signed UPDATEs and answers queries from its records
+type rfc2136StandIn struct {
+	conn net.PacketConn
+	key  *tsigKey
+
+	mu      sync.Mutex
+	records map[string][]string // "name type" -> values
+	updates int
+}
+
+func newRFC2136StandIn(t *testing.T, records map[string][]string) *rfc2136StandIn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &rfc2136StandIn{conn: conn, key: newTestTSIGKey(t, tsigTestSecret), records: records}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *rfc2136StandIn) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := s.handle(append([]byte(nil), buf[:n]...)); resp != nil {
+			s.conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// handle answers one request, signing the response when the request was
+// signed with the server's key
+func (s *rfc2136StandIn) handle(wire []byte) []byte {
+	req, err := unpackDNSMessage(wire)
+	if err != nil || len(req.Question) != 1 {
+		return nil
+	}
+	resp := &dnsMessage{ID: req.ID, Flags: dnsFlagResponse | req.Flags&(0xF<<11), Question: req.Question, tsigOffset: -1}
+
+	var requestMAC []byte
+	if req.tsigOffset >= 0 {
+		tsig, err := parseTSIG(req.Additional[len(req.Additional)-1].Data)
+		if err != nil {
+			return nil
+		}
+		unsigned := append([]byte(nil), wire[:req.tsigOffset]...)
+		binary.BigEndian.PutUint16(unsigned[10:], uint16(len(req.Additional)-1))
+		mac := hmac.New(s.key.newHash, s.key.secret)
+		mac.Write(unsigned)
+		mac.Write(s.key.tsigVariables(tsig.timeSigned, 0, nil))
+		if !hmac.Equal(mac.Sum(nil), tsig.mac) {
+			// BADSIG is reported in an unsigned response (RFC 8945 5.3.2)
+			resp.Flags |= 9
+			out, _ := resp.pack()
+			return out
+		}
+		requestMAC = tsig.mac
+	}
+
+	s.mu.Lock()
+	switch int(req.Flags>>11) & 0xF {
+	case dnsOpcodeQuery:
+		q := req.Question[0]
+		values, ok := s.records[strings.ToLower(q.Name)+" "+dnsTypeName(q.Type)]
+		if !ok {
+			resp.Flags |= dnsRcodeNXDomain
+		}
+		for _, value := range values {
+			data, _ := dnsIPRData(dnsTypeName(q.Type), value)
+			resp.Answer = append(resp.Answer, dnsRR{Name: q.Name, Type: q.Type, Class: dnsClassIN, TTL: 300, Data: data})
+		}
+	case dnsOpcodeUpdate:
+		if requestMAC == nil {
+			resp.Flags |= 5 // REFUSED
+			break
+		}
+		s.updates++
+		for _, rr := range req.Authority {
+			key := strings.ToLower(rr.Name) + " " + dnsTypeName(rr.Type)
+			value := net.IP(rr.Data).String()
+			values := replaceRecordValue(s.records[key], value, "")
+			if rr.Class == dnsClassIN {
+				values = append(values, value)
+			}
+			if len(values) == 0 {
+				delete(s.records, key)
+			} else {
+				s.records[key] = values
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	out, _ := resp.pack()
+	if requestMAC == nil {
+		return out
+	}
+	return s.signResponse(out, resp, requestMAC)
+}
+
+// signResponse appends a TSIG record covering the request MAC and out
+func (s *rfc2136StandIn) signResponse(out []byte, resp *dnsMessage, requestMAC []byte) []byte {
+	timeSigned := uint64(time.Now().Unix())
+	mac := hmac.New(s.key.newHash, s.key.secret)
+	mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC))))
+	mac.Write(requestMAC)
+	mac.Write(out)
+	mac.Write(s.key.tsigVariables(timeSigned, 0, nil))
+	sum := mac.Sum(nil)
+
+	data, _ := appendDNSName(nil, s.key.algorithm)
+	data = appendUint48(data, timeSigned)
+	data = binary.BigEndian.AppendUint16(data, tsigFudge)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(sum)))
+	data = append(data, sum...)
+	data = binary.BigEndian.AppendUint16(data, resp.ID)
+	data = append(data, 0, 0, 0, 0)
+	out, _ = appendDNSRR(out, dnsRR{Name: s.key.name, Type: dnsTypeTSIG, Class: dnsClassANY, Data: data})
+	binary.BigEndian.PutUint16(out[10:], 1)
+	return out
+}
+
+// dnsTypeName returns the record type of an address type code
+func dnsTypeName(rrType uint16) string {
+	if rrType == dnsTypeAAAA {
+		return "AAAA"
+	}
+	return "A"
+}
+
+// TestRFC2136Updates runs creates, updates and deletes against the
+// in-process server and checks what it holds after each
+func TestRFC2136Updates(t *testing.T) {
+	server := newRFC2136StandIn(t, map[string][]string{
+		"example.com A":      {"192.0.2.100"},
+		"mail.example.com A": {"192.0.2.200"},
+	})
+
+	p, err := newRFC2136Provider("update-key", tsigTestSecret, "hmac-sha256", server.conn.LocalAddr().String(), "udp", 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := func(subdomain, recordType string) string {
+		t.Helper()
+		records, err := p.GetRecords("example.com", subdomain, recordType)
+		if err != nil {
+			t.Fatalf("GetRecords(%s, %s): %v", subdomain, recordType, err)
+		}
+		var values []string
+		for _, record := range records {
+			values = append(values, record.Value)
+		}
+		return strings.Join(values, ",")
+	}
+
+	if got := values("www", "A"); got != "" {
+		t.Fatalf("www A holds %q before any change", got)
+	}
+	if _, err := p.CreateRecord("example.com", "www", "A", "192.0.2.1"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "www", "AAAA", "2001:db8::1"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if err := p.UpdateRecord("192.0.2.100", "example.com", "@", "A", "192.0.2.101"); err != nil {
+		t.Fatalf("UpdateRecord of the apex: %v", err)
+	}
+
+	for _, tt := range []struct{ subdomain, recordType, want string }{
+		{"www", "A", "192.0.2.2"},
+		{"www", "AAAA", "2001:db8::1"},
+		{"@", "A", "192.0.2.101"},
+		{"mail", "A", "192.0.2.200"},
+	} {
+		if got := values(tt.subdomain, tt.recordType); got != tt.want {
+			t.Errorf("%s %s holds %q, want %q", tt.subdomain, tt.recordType, got, tt.want)
+		}
+	}
+
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if got := values("www", "AAAA"); got != "" {
+		t.Errorf("www AAAA holds %q after delete", got)
+	}
+}
+
+// TestRFC2136WrongKey checks that an update signed with the wrong secret
+// fails permanently and changes nothing
+func TestRFC2136WrongKey(t *testing.T) {
+	server := newRFC2136StandIn(t, make(map[string][]string))
+
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), 32))
+	p, err := newRFC2136Provider("update-key", secret, "hmac-sha256", server.conn.LocalAddr().String(), "udp", 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("CreateRecord with the wrong key: got error %v, want a permanent error", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.updates != 0 || len(server.records) != 0 {
+		t.Errorf("server applied an update signed with the wrong key")
+	}
+}
@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route53Provider implements DNSProvider for AWS Route 53
+type Route53Provider struct {
+	accessKeyID     string
+	secretAccessKey string
+	endpoint        string
+	ttl             int
+	client          *http.Client
+	signer          hmacSigner
+
+	mu      sync.Mutex
+	zoneIDs map[string]string
+}
+
+// Route 53 API structures
+const route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+type route53HostedZonesResponse struct {
+	HostedZones []struct {
+		ID   string `xml:"Id"`
+		Name string `xml:"Name"`
+	} `xml:"HostedZones>HostedZone"`
+}
+
+type route53RecordSet struct {
+	Name    string                  `xml:"Name"`
+	Type    string                  `xml:"Type"`
+	TTL     int                     `xml:"TTL,omitempty"`
+	Records []route53ResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type route53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+// values returns the record values of the set
+func (s *route53RecordSet) values() []string {
+	values := make([]string, 0, len(s.Records))
+	for _, record := range s.Records {
+		values = append(values, record.Value)
+	}
+	return values
+}
+
+type route53RecordSetsResponse struct {
+	RecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53Change struct {
+	Action    string           `xml:"Action"`
+	RecordSet route53RecordSet `xml:"ResourceRecordSet"`
+}
+
+type route53ErrorResponse struct {
+	Error struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+// newRoute53Provider creates a new Route 53 provider instance. endpoint
+// overrides the API URL, for example to use a local fake.
+func newRoute53Provider(accessKeyID, secretAccessKey, endpoint string, ttl int) (*Route53Provider, error) {
+	if endpoint == "" {
+		endpoint = "https://route53.amazonaws.com"
+	}
+
+	return &Route53Provider{
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		ttl:             ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		signer:  newAWSSigner("us-east-1", "route53"),
+		zoneIDs: make(map[string]string),
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records. Route 53 stores records as
+// sets, so each value doubles as its record ID.
+func (r *Route53Provider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	set, err := r.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+	return setRecords(set.values()), nil
+}
+
+// CreateRecord adds a value to the record set
+func (r *Route53Provider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := r.upsert(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord replaces the value recordID in the record set with value
+func (r *Route53Provider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return r.upsert(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the value recordID from the record set
+func (r *Route53Provider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return r.upsert(domain, subdomain, recordType, recordID, "")
+}
+
+// upsert reads the current record set, replaces oldValue with newValue and
+// writes the set back with UPSERT, keeping the existing TTL. A set left
+// empty is deleted.
+func (r *Route53Provider) upsert(domain, subdomain, recordType, oldValue, newValue string) error {
+	zoneID, err := r.zoneID(domain)
+	if err != nil {
+		return err
+	}
+
+	current, err := r.getRecordSet(domain, subdomain, recordType)
+	if err != nil {
+		return err
+	}
+
+	set := route53RecordSet{
+		Name: recordFQDN(domain, subdomain),
+		Type: recordType,
+		TTL:  r.ttl,
+	}
+	var values []string
+	if current != nil {
+		values = current.values()
+		if current.TTL > 0 {
+			set.TTL = current.TTL
+		}
+	}
+	for _, value := range replaceRecordValue(values, oldValue, newValue) {
+		set.Records = append(set.Records, route53ResourceRecord{Value: value})
+	}
+
+	action := "UPSERT"
+	if len(set.Records) == 0 {
+		if current == nil {
+			return nil
+		}
+		// DELETE must match the current set exactly
+		action, set = "DELETE", *current
+	}
+
+	change := route53ChangeRequest{
+		Xmlns:   route53Namespace,
+		Changes: []route53Change{{Action: action, RecordSet: set}},
+	}
+	body, err := xml.Marshal(change)
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+	}
+
+	return r.doRequest("POST", fmt.Sprintf("/2013-04-01/hostedzone/%s/rrset/", zoneID), nil, body, nil)
+}
+
+// getRecordSet returns the record set for the name and type, or nil
+func (r *Route53Provider) getRecordSet(domain, subdomain, recordType string) (*route53RecordSet, error) {
+	zoneID, err := r.zoneID(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	name := recordFQDN(domain, subdomain)
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("type", recordType)
+	query.Set("maxitems", "1")
+
+	var resp route53RecordSetsResponse
+	if err := r.doRequest("GET", fmt.Sprintf("/2013-04-01/hostedzone/%s/rrset", zoneID), query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	// Listing starts at the requested name, so the first set may be another record
+	for _, set := range resp.RecordSets {
+		if strings.EqualFold(set.Name, name) && set.Type == recordType {
+			return &set, nil
+		}
+	}
+	return nil, nil
+}
+
+// zoneID resolves the hosted zone ID for domain and caches it
+func (r *Route53Provider) zoneID(domain string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.zoneIDs[domain]; ok {
+		return id, nil
+	}
+
+	query := url.Values{}
+	query.Set("dnsname", domain)
+	query.Set("maxitems", "1")
+
+	var resp route53HostedZonesResponse
+	if err := r.doRequest("GET", "/2013-04-01/hostedzonesbyname", query, nil, &resp); err != nil {
+		return "", err
+	}
+
+	for _, zone := range resp.HostedZones {
+		if strings.EqualFold(strings.TrimSuffix(zone.Name, "."), domain) {
+			id := strings.TrimPrefix(zone.ID, "/hostedzone/")
+			r.zoneIDs[domain] = id
+			return id, nil
+		}
+	}
+	return "", permanentError(fmt.Errorf("hosted zone not found: %s", domain))
+}
+
+// doRequest sends a SigV4-signed API request and decodes the XML response
+// into out when it is not nil
+func (r *Route53Provider) doRequest(method, path string, query url.Values, body []byte, out interface{}) error {
+	requestURL := r.endpoint + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	r.signer.sign(req, body, r.accessKeyID, r.secretAccessKey, time.Now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp route53ErrorResponse
+		if xml.Unmarshal(respBody, &errResp) == nil && errResp.Error.Code != "" {
+			apiErr := fmt.Errorf("API request failed: %s - %s", errResp.Error.Code, errResp.Error.Message)
+			switch errResp.Error.Code {
+			case "Throttling", "ThrottlingException", "PriorRequestNotComplete", "ServiceUnavailable":
+				return retryableError(apiErr, parseRetryAfter(resp.Header.Get("Retry-After")))
+			}
+			if resp.StatusCode >= 500 {
+				return retryableError(apiErr, 0)
+			}
+			return permanentError(apiErr)
+		}
+		return statusError(resp, respBody)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(respBody, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return nil
+}
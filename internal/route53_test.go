@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// route53StandIn is a minimal Route 53 API serving the example.com hosted
+// zone. It re-signs every request to check the signature covers what was
+// sent.
+type route53StandIn struct {
+	mu       sync.Mutex
+	sets     map[string]route53RecordSet // "name type" -> set
+	changes  []route53Change
+	throttle int
+}
+
+func (s *route53StandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if !s.signatureValid(r, body) {
+		s.fail(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match")
+		return
+	}
+	if s.throttle > 0 {
+		s.throttle--
+		s.fail(w, http.StatusBadRequest, "Throttling", "Rate exceeded")
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/2013-04-01/hostedzonesbyname":
+		fmt.Fprintf(w, `<ListHostedZonesByNameResponse><HostedZones><HostedZone><Id>/hostedzone/Z1D633PJN98FT9</Id><Name>example.com.</Name></HostedZone></HostedZones></ListHostedZonesByNameResponse>`)
+
+	case r.Method == "GET" && r.URL.Path == "/2013-04-01/hostedzone/Z1D633PJN98FT9/rrset":
+		// Listing starts at the requested name and type, like the real API
+		var resp route53RecordSetsResponse
+		for _, key := range []string{query.Get("name") + " " + query.Get("type"), "zzz.example.com. A"} {
+			if set, ok := s.sets[key]; ok {
+				resp.RecordSets = append(resp.RecordSets, set)
+				break
+			}
+		}
+		out, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"ListResourceRecordSetsResponse"`
+			route53RecordSetsResponse
+		}{route53RecordSetsResponse: resp})
+		w.Write(out)
+
+	case r.Method == "POST" && r.URL.Path == "/2013-04-01/hostedzone/Z1D633PJN98FT9/rrset/":
+		var req route53ChangeRequest
+		if err := xml.Unmarshal(body, &req); err != nil {
+			s.fail(w, http.StatusBadRequest, "InvalidInput", err.Error())
+			return
+		}
+		for _, change := range req.Changes {
+			s.changes = append(s.changes, change)
+			key := change.RecordSet.Name + " " + change.RecordSet.Type
+			if change.Action == "DELETE" {
+				if fmt.Sprint(s.sets[key]) != fmt.Sprint(change.RecordSet) {
+					s.fail(w, http.StatusBadRequest, "InvalidChangeBatch", "record set does not match")
+					return
+				}
+				delete(s.sets, key)
+				continue
+			}
+			s.sets[key] = change.RecordSet
+		}
+		fmt.Fprint(w, `<ChangeResourceRecordSetsResponse><ChangeInfo><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`)
+
+	default:
+		s.fail(w, http.StatusNotFound, "NoSuchHostedZone", "not found")
+	}
+}
+
+// signatureValid signs a copy of the request again with the time it
+// claims and compares the Authorization headers
+func (s *route53StandIn) signatureValid(r *http.Request, body []byte) bool {
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	check.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	if len(body) == 0 {
+		body = nil
+	}
+	newAWSSigner("us-east-1", "route53").sign(check, body, "AKIDEXAMPLE", testAWSSecretKey, signedAt)
+	return check.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (s *route53StandIn) fail(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error></ErrorResponse>`, code, message)
+}
+
+func newRoute53StandIn(t *testing.T, sets ...route53RecordSet) (*route53StandIn, string) {
+	s := &route53StandIn{sets: make(map[string]route53RecordSet)}
+	for _, set := range sets {
+		s.sets[set.Name+" "+set.Type] = set
+	}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func route53Set(name, recordType string, ttl int, values ...string) route53RecordSet {
+	set := route53RecordSet{Name: name, Type: recordType, TTL: ttl}
+	for _, value := range values {
+		set.Records = append(set.Records, route53ResourceRecord{Value: value})
+	}
+	return set
+}
+
+// TestRoute53ChangesKeepOtherValues checks that changes rewrite only the
+// value they are made to, keep the TTL, and delete a set left empty
+func TestRoute53ChangesKeepOtherValues(t *testing.T) {
+	standIn, endpoint := newRoute53StandIn(t,
+		route53Set("www.example.com.", "A", 600, "192.0.2.1", "192.0.2.9"),
+		route53Set("www.example.com.", "AAAA", 600, "2001:db8::1"),
+		route53Set("zzz.example.com.", "A", 300, "192.0.2.50"),
+	)
+	p, err := newRoute53Provider("AKIDEXAMPLE", testAWSSecretKey, endpoint, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UpdateRecord("192.0.2.1", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("2001:db8::1", "example.com", "www", "AAAA"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+
+	want := map[string]route53RecordSet{
+		"www.example.com. A": route53Set("www.example.com.", "A", 600, "192.0.2.9", "192.0.2.2"),
+		"example.com. A":     route53Set("example.com.", "A", 300, "192.0.2.3"),
+		"zzz.example.com. A": route53Set("zzz.example.com.", "A", 300, "192.0.2.50"),
+	}
+	if fmt.Sprint(standIn.sets) != fmt.Sprint(want) {
+		t.Errorf("record sets are\n%v\nwant\n%v", standIn.sets, want)
+	}
+
+	// Listing starts at the requested name, so another set must not be
+	// mistaken for a missing one
+	records, err := p.GetRecords("example.com", "home", "A")
+	if err != nil || len(records) != 0 {
+		t.Errorf("GetRecords of a missing name returned %+v, %v", records, err)
+	}
+}
+
+// TestRoute53Errors checks how API errors are classified
+func TestRoute53Errors(t *testing.T) {
+	standIn, endpoint := newRoute53StandIn(t)
+
+	p, err := newRoute53Provider("AKIDEXAMPLE", testAWSSecretKey, endpoint, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	standIn.throttle = 1
+	if _, err := p.GetRecords("example.com", "www", "A"); !IsRetryable(err) || !strings.Contains(err.Error(), "Throttling") {
+		t.Errorf("throttled request: got error %v, want a retryable Throttling error", err)
+	}
+
+	wrongKey, err := newRoute53Provider("AKIDEXAMPLE", "not-the-secret", endpoint, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongKey.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("request with the wrong secret: got error %v, want a permanent SignatureDoesNotMatch error", err)
+	}
+}
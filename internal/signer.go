@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hmacSigner implements the scoped HMAC-SHA256 request signing scheme used
+// by AWS Signature Version 4 and Volcengine. The schemes differ only in
+// their names, header names and key derivation prefix.
+type hmacSigner struct {
+	algorithm   string
+	keyPrefix   string
+	scopeSuffix string
+	dateHeader  string
+	hashHeader  string
+	region      string
+	service     string
+}
+
+// newAWSSigner returns a signer for AWS Signature Version 4
+func newAWSSigner(region, service string) hmacSigner {
+	return hmacSigner{
+		algorithm:   "AWS4-HMAC-SHA256",
+		keyPrefix:   "AWS4",
+		scopeSuffix: "aws4_request",
+		dateHeader:  "X-Amz-Date",
+		hashHeader:  "X-Amz-Content-Sha256",
+		region:      region,
+		service:     service,
+	}
+}
+
+// newVolcengineSigner returns a signer for Volcengine OpenAPI requests
+func newVolcengineSigner(region, service string) hmacSigner {
+	return hmacSigner{
+		algorithm:   "HMAC-SHA256",
+		scopeSuffix: "request",
+		dateHeader:  "X-Date",
+		hashHeader:  "X-Content-Sha256",
+		region:      region,
+		service:     service,
+	}
+}
+
+// sign signs req in place, setting the date, payload hash and
+// Authorization headers
+func (s hmacSigner) sign(req *http.Request, body []byte, accessKey, secretKey string, now time.Time) {
+	timestamp := now.UTC().Format("20060102T150405Z")
+	date := timestamp[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set(s.dateHeader, timestamp)
+	req.Header.Set(s.hashHeader, payloadHash)
+
+	signedHeaders := []string{"host", strings.ToLower(s.hashHeader), strings.ToLower(s.dateHeader)}
+	if req.Header.Get("Content-Type") != "" {
+		signedHeaders = append(signedHeaders, "content-type")
+	}
+	sort.Strings(signedHeaders)
+
+	canonical := canonicalRequest(req, canonicalURI(req.URL.Path), signedHeaders, payloadHash)
+
+	scope := fmt.Sprintf("%s/%s/%s/%s", date, s.region, s.service, s.scopeSuffix)
+	stringToSign := strings.Join([]string{
+		s.algorithm,
+		timestamp,
+		scope,
+		sha256Hex([]byte(canonical)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(secretKey, date), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.algorithm, accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// signingKey derives the key for one day's requests from the secret key
+func (s hmacSigner) signingKey(secretKey, date string) []byte {
+	key := hmacSHA256([]byte(s.keyPrefix+secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	return hmacSHA256(key, s.scopeSuffix)
+}
+
+// canonicalRequest builds the canonical request shared by the SigV4-style
+// signing schemes (AWS, Volcengine, Huawei Cloud and Aliyun ACS3).
+// signedHeaders must be lowercase and sorted.
+func canonicalRequest(req *http.Request, uri string, signedHeaders []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalURI URI-encodes each segment of path
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery encodes query parameters sorted by key
+func canonicalQuery(values url.Values) string {
+	var parts []string
+	for key, vals := range values {
+		for _, val := range vals {
+			parts = append(parts, uriEncode(key)+"="+uriEncode(val))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes s as specified by RFC 3986, leaving only
+// unreserved characters
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns HMAC-SHA256 of data keyed with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sha256Hex returns the hex-encoded SHA-256 digest of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testAWSSecretKey is the example secret key of the AWS documentation
+const testAWSSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+
+// TestAWSSigningKey checks key derivation against the example in the AWS
+// Signature Version 4 documentation
+func TestAWSSigningKey(t *testing.T) {
+	key := newAWSSigner("us-east-1", "iam").signingKey(testAWSSecretKey, "20120215")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signing key is %s, want %s", got, want)
+	}
+}
+
+// TestAWSSignKnownAnswer checks Route 53 request signatures against values
+// computed independently from the Signature Version 4 specification
+func TestAWSSignKnownAnswer(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "query",
+			method:        "GET",
+			url:           "https://route53.amazonaws.com/2013-04-01/hostedzone/Z1D633PJN98FT9/rrset?type=A&name=www.example.com.&maxitems=1",
+			signedHeaders: "host;x-amz-content-sha256;x-amz-date",
+			signature:     "302cd07a1a3cf5a67c401ddd886af6294f0367f8c947066fddd25a2e5926df17",
+		},
+		{
+			name:          "body",
+			method:        "POST",
+			url:           "https://route53.amazonaws.com/2013-04-01/hostedzone/Z1D633PJN98FT9/rrset/",
+			body:          `<ChangeResourceRecordSetsRequest xmlns="https://route53.amazonaws.com/doc/2013-04-01/"></ChangeResourceRecordSetsRequest>`,
+			signedHeaders: "content-type;host;x-amz-content-sha256;x-amz-date",
+			signature:     "2118af60b47d81fdc42bae887d60b5b5b76b7ba20d3d6bbf4a4c2e49cf9f63dd",
+		},
+	}
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body []byte
+			if tt.body != "" {
+				body = []byte(tt.body)
+				req.Header.Set("Content-Type", "application/xml")
+			}
+			newAWSSigner("us-east-1", "route53").sign(req, body, "AKIDEXAMPLE", testAWSSecretKey, now)
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/route53/aws4_request, SignedHeaders=" +
+				tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization is\n%s\nwant\n%s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date is %s", got)
+			}
+		})
+	}
+}
+
+// TestURIEncode checks the RFC 3986 encoding used in canonical requests
+func TestURIEncode(t *testing.T) {
+	for in, want := range map[string]string{
+		"www.example.com.": "www.example.com.",
+		"a b+c":            "a%20b%2Bc",
+		"~_-":              "~_-",
+		"*/=":              "%2A%2F%3D",
+	} {
+		if got := uriEncode(in); got != want {
+			t.Errorf("uriEncode(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := canonicalURI("/a b/c"); got != "/a%20b/c" {
+		t.Errorf("canonicalURI kept the space: %s", got)
+	}
+}
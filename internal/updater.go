@@ -5,70 +5,357 @@ import (
 	"ddnsd/utils"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
-// RunSequentialUpdates performs IPv4 and IPv6 updates in sequence
-func RunSequentialUpdates(provider DNSProvider, cfg *config.Config) {
-	if cfg.IPv4Enabled {
-		utils.WithLogPrefix("[IPv4] ", func() {
-			updateIPv4Records(provider, cfg)
-		})
+// updateOutcome describes what happened to a single record in a cycle
+type updateOutcome int
+
+const (
+	outcomeUnchanged updateOutcome = iota
+	outcomeUpdated
+	outcomeCreated
+	outcomeFailed
+	outcomeSuppressed
+)
+
+// String returns the outcome name passed to hooks
+func (o updateOutcome) String() string {
+	switch o {
+	case outcomeUnchanged:
+		return "unchanged"
+	case outcomeUpdated:
+		return "updated"
+	case outcomeCreated:
+		return "created"
+	case outcomeSuppressed:
+		return "suppressed"
+	default:
+		return "failed"
 	}
+}
 
+// updateJob is a single record update scheduled within a cycle
+type updateJob struct {
+	domain     string
+	subDomain  string
+	recordType string
+	ip         string   // ips joined with commas, for logs and hooks
+	ips        []string // the addresses the record should hold
+	reconcile  bool     // the family has several sources, so ips is a set
+	log        *utils.Logger
+	hooks      *changeHooks
+}
+
+// fullDomain returns the fully qualified name of the record
+func (j updateJob) fullDomain() string {
+	return recordFullName(j.domain, j.subDomain)
+}
+
+// updateResult is the result of running an updateJob
+type updateResult struct {
+	job      updateJob
+	outcome  updateOutcome
+	oldValue string
+	started  bool // the change got past the pre-update hook
+	applied  bool // at least one change reached the provider
+	verified bool
+	err      error
+}
+
+// CycleSummary aggregates the results of one update cycle
+type CycleSummary struct {
+	Unchanged  int
+	Updated    int
+	Created    int
+	Failed     int
+	Suppressed int
+	Verified   int
+	Duration   time.Duration
+}
+
+// Updater runs update cycles for every configured record
+type Updater struct {
+	cfg      *config.Config
+	provider DNSProvider
+	policy   RetryPolicy
+	verifier *propagationVerifier
+	hooks    *hookRunner
+	failover *failover
+	damper   *ipDamper
+	limiter  *changeLimiter
+	history  *historyStore
+}
+
+// NewUpdater creates an Updater for the given provider and configuration
+func NewUpdater(provider DNSProvider, cfg *config.Config) *Updater {
+	return &Updater{
+		cfg:      cfg,
+		provider: provider,
+		policy:   newRetryPolicy(cfg),
+		verifier: newPropagationVerifier(cfg),
+		hooks:    newHookRunner(cfg),
+		failover: newFailover(cfg),
+		damper:   newIPDamper(cfg),
+		limiter:  newChangeLimiter(cfg),
+		history:  newHistoryStore(cfg),
+	}
+}
+
+// Run performs IPv4 and IPv6 updates for every configured record, running
+// up to cfg.Concurrency record updates in parallel
+func (u *Updater) Run() CycleSummary {
+	cfg := u.cfg
+	start := time.Now()
+
+	var jobs []updateJob
+	var families []*changeHooks
+	failedFamilies := 0
+	if cfg.IPv4Enabled {
+		familyJobs, hooks, ok := u.buildJobs(utils.NewLogger("[IPv4] "), cfg.IPv4Sources, "IPv4", "A", cfg.IPv4Domain, cfg.IPv4SubDomains)
+		jobs = append(jobs, familyJobs...)
+		families = append(families, hooks)
+		if !ok {
+			failedFamilies++
+		}
+	}
 	if cfg.IPv6Enabled {
-		utils.WithLogPrefix("[IPv6] ", func() {
-			updateIPv6Records(provider, cfg)
-		})
+		familyJobs, hooks, ok := u.buildJobs(utils.NewLogger("[IPv6] "), cfg.IPv6Sources, "IPv6", "AAAA", cfg.IPv6Domain, cfg.IPv6SubDomains)
+		jobs = append(jobs, familyJobs...)
+		families = append(families, hooks)
+		if !ok {
+			failedFamilies++
+		}
+	}
+
+	results := u.runJobs(jobs, cfg.Concurrency)
+	u.runChangeHooks(families, results)
+
+	summary := CycleSummary{Failed: failedFamilies}
+	for _, result := range results {
+		switch result.outcome {
+		case outcomeUnchanged:
+			summary.Unchanged++
+		case outcomeUpdated:
+			summary.Updated++
+		case outcomeCreated:
+			summary.Created++
+		case outcomeFailed:
+			summary.Failed++
+		case outcomeSuppressed:
+			summary.Suppressed++
+		}
+		if result.verified {
+			summary.Verified++
+		}
 	}
+	summary.Duration = time.Since(start)
+
+	logSummary(summary, results)
+	return summary
 }
 
-// updateIPv4Records updates all IPv4 DNS records
-func updateIPv4Records(provider DNSProvider, cfg *config.Config) {
-	utils.LogInfo("Starting record update")
-	ipv4, err := getPublicIP(cfg.IPv4CheckURL, "IPv4")
+// buildJobs detects the current IPs for one address family, or picks the
+// healthy one with failover, holds back changes that are not yet stable and
+// returns a job per subdomain. ok is false when the IPs could not be
+// determined.
+func (u *Updater) buildJobs(log *utils.Logger, sources []string, ipType, recordType, domain string, subDomains []string) ([]updateJob, *changeHooks, bool) {
+	var ips []string
+	var err error
+	if u.failover != nil {
+		var ip string
+		ip, err = u.failover.Select(log, ipType, sources)
+		ips = []string{ip}
+	} else {
+		ips, err = detectIPs(sources, ipType)
+	}
 	if err != nil {
-		utils.LogError("Update failed: Error getting IP address - %v", err)
-		utils.LogInfo("Update completed")
-		return
+		log.Error("Update failed: Error getting IP address - %v", err)
+		if u.history != nil {
+			u.history.Append(HistoryEntry{Event: HistoryFailed, RecordType: recordType, Error: err.Error()})
+		}
+		return nil, nil, false
+	}
+	if u.history != nil {
+		u.history.Detected(recordType, strings.Join(ips, ","))
+	}
+	if u.damper != nil {
+		detected := strings.Join(ips, ",")
+		var suppressed bool
+		if ips, suppressed = u.damper.Filter(log, ipType, ips); suppressed && u.history != nil {
+			for _, subDomain := range subDomains {
+				u.history.Append(HistoryEntry{
+					Event:      HistorySuppressed,
+					Record:     recordFullName(domain, subDomain),
+					RecordType: recordType,
+					OldValue:   strings.Join(ips, ","),
+					NewValue:   detected,
+					Error:      "address not yet stable",
+				})
+			}
+		}
+	}
+	ip := strings.Join(ips, ",")
+	if len(ips) > 1 {
+		log.Info("Current IP addresses: %s", ip)
+	} else {
+		log.Info("Current IP address: %s", ip)
 	}
-	utils.LogInfo("Current IP address: %s", ipv4)
 
-	for _, subDomain := range cfg.IPv4SubDomains {
-		fullDomain := fmt.Sprintf("%s.%s", subDomain, cfg.IPv4Domain)
-		utils.LogInfo("Processing subdomain: %s", fullDomain)
+	var hooks *changeHooks
+	if u.hooks != nil && !u.hooks.perRecord() {
+		hooks = &changeHooks{
+			runner:     u.hooks,
+			log:        log,
+			domain:     domain,
+			recordType: recordType,
+			newIP:      ip,
+		}
+	}
 
-		if err := updateRecord(provider, cfg.IPv4Domain, subDomain, ipv4, "A"); err != nil {
-			utils.LogError("Subdomain update failed: %s - %v", subDomain, err)
+	jobs := make([]updateJob, 0, len(subDomains))
+	for _, subDomain := range subDomains {
+		jobs = append(jobs, updateJob{
+			domain:     domain,
+			subDomain:  subDomain,
+			recordType: recordType,
+			ip:         ip,
+			ips:        ips,
+			reconcile:  len(sources) > 1 && u.failover == nil,
+			log:        log.WithPrefix(fmt.Sprintf("[%s] ", recordFullName(domain, subDomain))),
+			hooks:      hooks,
+		})
+	}
+	return jobs, hooks, true
+}
+
+// runJobs runs jobs on a bounded worker pool. Results are returned in the
+// same order as jobs.
+func (u *Updater) runJobs(jobs []updateJob, concurrency int) []updateResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]updateResult, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = u.runJob(jobs[i])
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// runJob updates a single record and, when enabled, verifies that the
+// authoritative nameservers serve the new value and runs per-record hooks
+func (u *Updater) runJob(job updateJob) updateResult {
+	result := u.updateRecord(job)
+	if result.changeSuppressed() {
+		job.log.Warning("Record change to %s suppressed: %v (MAX_CHANGES_PER_HOUR=%d)", job.ip, result.err, u.cfg.MaxChangesPerHour)
+		u.recordHistory(job, HistoryEntry{Event: HistorySuppressed, OldValue: result.oldValue, NewValue: job.ip, Error: result.err.Error()})
+		result.outcome, result.err = outcomeSuppressed, nil
+		return result
+	}
+	if result.err != nil {
+		job.log.Error("Subdomain update failed: %s - %v", job.subDomain, result.err)
+	}
+	// A change that failed part way still counts, as records did change
+	if u.limiter != nil && result.applied {
+		u.limiter.Record(job)
+	}
+
+	if u.verifier != nil && (result.outcome == outcomeUpdated || result.outcome == outcomeCreated) {
+		if err := u.verifier.Verify(job.log, job.domain, job.subDomain, job.recordType, job.ips); err != nil {
+			job.log.Error("Propagation verification failed: %v", err)
+			result.outcome = outcomeFailed
+			result.err = fmt.Errorf("propagation verification failed: %v", err)
+		} else {
+			result.verified = true
 		}
 	}
 
-	utils.LogInfo("Update completed")
+	// The post-update hook pairs with a pre-update hook that ran, so records
+	// that failed before any change was attempted do not fire it
+	if u.hooks != nil && u.hooks.perRecord() && result.started {
+		u.hooks.Post(job.log, hookEvent{
+			OldIP:      result.oldValue,
+			NewIP:      job.ip,
+			Record:     job.fullDomain(),
+			RecordType: job.recordType,
+			Result:     result.outcome.String(),
+		})
+	}
+
+	if result.outcome == outcomeFailed {
+		u.recordHistory(job, HistoryEntry{Event: HistoryFailed, OldValue: result.oldValue, NewValue: job.ip, Error: result.err.Error()})
+	}
+
+	return result
 }
 
-// updateIPv6Records updates all IPv6 DNS records
-func updateIPv6Records(provider DNSProvider, cfg *config.Config) {
-	utils.LogInfo("Starting record update")
-	ipv6, err := getPublicIP(cfg.IPv6CheckURL, "IPv6")
-	if err != nil {
-		utils.LogError("Update failed: Error getting IP address - %v", err)
-		utils.LogInfo("Update completed")
+// recordHistory appends an entry about the job's record to the history,
+// when enabled
+func (u *Updater) recordHistory(job updateJob, entry HistoryEntry) {
+	if u.history == nil {
 		return
 	}
-	utils.LogInfo("Current IP address: %s", ipv6)
-
-	for _, subDomain := range cfg.IPv6SubDomains {
-		fullDomain := fmt.Sprintf("%s.%s", subDomain, cfg.IPv6Domain)
-		utils.LogInfo("Processing subdomain: %s", fullDomain)
+	entry.Record = job.fullDomain()
+	entry.RecordType = job.recordType
+	u.history.Append(entry)
+}
 
-		if err := updateRecord(provider, cfg.IPv6Domain, subDomain, ipv6, "AAAA"); err != nil {
-			utils.LogError("Subdomain update failed: %s - %v", subDomain, err)
+// runChangeHooks runs the once-per-change post-update hook for each address
+// family whose records changed in this cycle
+func (u *Updater) runChangeHooks(families []*changeHooks, results []updateResult) {
+	for _, hooks := range families {
+		if hooks == nil {
+			continue
+		}
+		outcome := "updated"
+		for _, result := range results {
+			if result.job.hooks == hooks && result.outcome == outcomeFailed {
+				outcome = "failed"
+				break
+			}
 		}
+		hooks.Post(outcome)
 	}
+}
 
-	utils.LogInfo("Update completed")
+// logSummary prints one aggregated line per cycle plus the failed records
+func logSummary(summary CycleSummary, results []updateResult) {
+	utils.LogInfo("Update cycle completed in %v: %d updated, %d created, %d unchanged, %d failed",
+		summary.Duration.Round(time.Millisecond), summary.Updated, summary.Created, summary.Unchanged, summary.Failed)
+	if summary.Verified > 0 {
+		utils.LogInfo("Propagation verified for %d records", summary.Verified)
+	}
+	if summary.Suppressed > 0 {
+		utils.LogWarning("Changes suppressed for %d records by MAX_CHANGES_PER_HOUR", summary.Suppressed)
+	}
+
+	for _, result := range results {
+		if result.outcome == outcomeFailed {
+			utils.LogWarning("Failed record: %s (%s) - %v",
+				result.job.fullDomain(), result.job.recordType, result.err)
+		}
+	}
 }
 
 // getPublicIP retrieves public IP address from specified URL
@@ -97,32 +384,323 @@ func getPublicIP(url, ipType string) (string, error) {
 	return ip, nil
 }
 
-// updateRecord creates or updates a single DNS record
-func updateRecord(provider DNSProvider, domain, subDomain, ip, recordType string) error {
-	record, err := provider.GetRecord(domain, subDomain, recordType)
+// startChange checks the change cap and runs the pre-update hook for a
+// record about to change
+func (u *Updater) startChange(job updateJob, oldValue string) error {
+	if u.limiter != nil {
+		if err := u.limiter.Allow(job); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch {
+	case job.hooks != nil:
+		err = job.hooks.Pre(oldValue)
+	case u.hooks != nil:
+		err = u.hooks.Pre(job.log, hookEvent{
+			OldIP:      oldValue,
+			NewIP:      job.ip,
+			Record:     job.fullDomain(),
+			RecordType: job.recordType,
+		})
+	}
+	if err != nil {
+		return &errPreHook{err: err}
+	}
+	return nil
+}
+
+// updateRecord creates or updates a single DNS record, retrying transient
+// provider failures according to policy
+func (u *Updater) updateRecord(job updateJob) updateResult {
+	provider, policy, log := u.provider, u.policy, job.log
+	log.Info("Processing subdomain")
+
+	failed := func(err error) updateResult {
+		return updateResult{job: job, outcome: outcomeFailed, err: err}
+	}
+
+	var records []DNSRecord
+	err := policy.Do(log, "Query record", func() error {
+		var err error
+		records, err = provider.GetRecords(job.domain, job.subDomain, job.recordType)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to query record: %v", err)
+		return failed(fmt.Errorf("failed to query record: %v", err))
+	}
+
+	if job.reconcile {
+		return u.reconcileRecords(job, records)
 	}
 
-	if record != nil {
-		if record.Value == ip {
-			utils.LogInfo("IP address unchanged, no update needed")
-			return nil
+	if len(records) > 1 {
+		return u.updateDuplicates(job, records)
+	}
+
+	if len(records) == 1 {
+		record := records[0]
+		if record.Value == job.ip {
+			log.Info("IP address unchanged, no update needed")
+			return updateResult{job: job, outcome: outcomeUnchanged, oldValue: record.Value}
+		}
+
+		if err := u.startChange(job, record.Value); err != nil {
+			return failed(err)
 		}
 
 		// Update existing record
-		if err := provider.UpdateRecord(record.RecordID, domain, subDomain, recordType, ip); err != nil {
-			return fmt.Errorf("failed to modify record: %v", err)
+		err := policy.Do(log, "Modify record", func() error {
+			return provider.UpdateRecord(record.RecordID, job.domain, job.subDomain, job.recordType, job.ip)
+		})
+		if err != nil {
+			result := failed(fmt.Errorf("failed to modify record: %v", err))
+			result.oldValue, result.started = record.Value, true
+			return result
 		}
-		utils.LogInfo("Record updated successfully")
-		return nil
+		log.Info("Record updated successfully: %s -> %s", record.Value, job.ip)
+		u.recordHistory(job, HistoryEntry{Event: HistoryUpdated, RecordID: record.RecordID, OldValue: record.Value, NewValue: job.ip})
+		return updateResult{job: job, outcome: outcomeUpdated, oldValue: record.Value, started: true, applied: true}
+	}
+
+	if err := u.startChange(job, ""); err != nil {
+		return failed(err)
 	}
 
 	// Create new record
-	recordID, err := provider.CreateRecord(domain, subDomain, recordType, ip)
+	var recordID string
+	err = policy.Do(log, "Create record", func() error {
+		var err error
+		recordID, err = provider.CreateRecord(job.domain, job.subDomain, job.recordType, job.ip)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create record: %v", err)
+		result := failed(fmt.Errorf("failed to create record: %v", err))
+		result.started = true
+		return result
 	}
-	utils.LogInfo("Record created successfully, ID=%s", recordID)
-	return nil
-}
\ No newline at end of file
+	log.Info("Record created successfully, ID=%s", recordID)
+	u.recordHistory(job, HistoryEntry{Event: HistoryCreated, RecordID: recordID, NewValue: job.ip})
+	return updateResult{job: job, outcome: outcomeCreated, started: true, applied: true}
+}
+
+// updateDuplicates applies the duplicate policy when several records exist
+// for the name: update updates every record to the new IP, delete keeps one
+// record and deletes the rest, and refuse changes nothing and fails the
+// record
+func (u *Updater) updateDuplicates(job updateJob, records []DNSRecord) updateResult {
+	provider, policy, log := u.provider, u.policy, job.log
+	log.Warning("Found %d %s records for the name: %s", len(records), job.recordType, describeRecords(records))
+
+	var update, remove []DNSRecord
+	switch u.cfg.DuplicatePolicy {
+	case "refuse":
+		log.Error("Refusing to update: DUPLICATE_POLICY=refuse; remove the extra records or change the policy")
+		return updateResult{job: job, outcome: outcomeFailed, oldValue: records[0].Value,
+			err: fmt.Errorf("refused to update %d records for the name (DUPLICATE_POLICY=refuse)", len(records))}
+
+	case "delete":
+		// Keep a record that already has the IP, or else the first one
+		keep := 0
+		for i, record := range records {
+			if record.Value == job.ip {
+				keep = i
+				break
+			}
+		}
+		if records[keep].Value != job.ip {
+			update = records[keep : keep+1]
+		}
+		remove = append(append(remove, records[:keep]...), records[keep+1:]...)
+		log.Info("DUPLICATE_POLICY=delete: keeping %s, deleting %s", describeRecords(records[keep:keep+1]), describeRecords(remove))
+
+	default:
+		for _, record := range records {
+			if record.Value != job.ip {
+				update = append(update, record)
+			}
+		}
+		if len(update) == 0 {
+			log.Info("DUPLICATE_POLICY=update: all records already have the IP address, no update needed")
+			return updateResult{job: job, outcome: outcomeUnchanged, oldValue: job.ip}
+		}
+		log.Info("DUPLICATE_POLICY=update: updating %s", describeRecords(update))
+	}
+
+	oldValue := job.ip
+	if len(update) > 0 {
+		oldValue = update[0].Value
+	} else if len(remove) > 0 {
+		oldValue = remove[0].Value
+	}
+	if err := u.startChange(job, oldValue); err != nil {
+		return updateResult{job: job, outcome: outcomeFailed, err: err}
+	}
+
+	// A failure part way leaves the changes already made in place
+	applied := false
+	failed := func(err error) updateResult {
+		return updateResult{job: job, outcome: outcomeFailed, oldValue: oldValue, started: true, applied: applied, err: err}
+	}
+
+	for _, record := range update {
+		err := policy.Do(log, "Modify record", func() error {
+			return provider.UpdateRecord(record.RecordID, job.domain, job.subDomain, job.recordType, job.ip)
+		})
+		if err != nil {
+			return failed(fmt.Errorf("failed to modify record %s: %v", record.RecordID, err))
+		}
+		log.Info("Record updated successfully: %s -> %s", record.Value, job.ip)
+		u.recordHistory(job, HistoryEntry{Event: HistoryUpdated, RecordID: record.RecordID, OldValue: record.Value, NewValue: job.ip})
+		applied = true
+	}
+
+	for _, record := range remove {
+		err := policy.Do(log, "Delete record", func() error {
+			return provider.DeleteRecord(record.RecordID, job.domain, job.subDomain, job.recordType)
+		})
+		if err != nil {
+			return failed(fmt.Errorf("failed to delete record %s: %v", record.RecordID, err))
+		}
+		log.Info("Record deleted successfully: %s", describeRecords([]DNSRecord{record}))
+		u.recordHistory(job, HistoryEntry{Event: HistoryDeleted, RecordID: record.RecordID, OldValue: record.Value})
+		applied = true
+	}
+
+	return updateResult{job: job, outcome: outcomeUpdated, oldValue: oldValue, started: true, applied: true}
+}
+
+// reconcileRecords brings the records for the name to exactly the set of
+// addresses in job.ips. Records with a stale value are updated to a missing
+// address where possible; the remaining missing addresses are created and
+// the remaining stale records, including repeats of a wanted value, deleted.
+func (u *Updater) reconcileRecords(job updateJob, records []DNSRecord) updateResult {
+	provider, policy, log := u.provider, u.policy, job.log
+
+	wanted := make(map[string]bool, len(job.ips))
+	for _, ip := range job.ips {
+		wanted[ip] = true
+	}
+	present := make(map[string]bool, len(records))
+	var stale []DNSRecord
+	for _, record := range records {
+		value := normalizeIP(record.Value)
+		if wanted[value] && !present[value] {
+			present[value] = true
+		} else {
+			stale = append(stale, record)
+		}
+	}
+	var missing []string
+	for _, ip := range job.ips {
+		if !present[ip] {
+			missing = append(missing, ip)
+		}
+	}
+
+	oldValues := make([]string, 0, len(records))
+	for _, record := range records {
+		oldValues = append(oldValues, record.Value)
+	}
+	oldValue := strings.Join(oldValues, ",")
+
+	if len(stale) == 0 && len(missing) == 0 {
+		log.Info("Records already hold every IP address, no update needed")
+		return updateResult{job: job, outcome: outcomeUnchanged, oldValue: oldValue}
+	}
+	log.Info("Reconciling %d %s records to %s: adding %s, removing %s",
+		len(records), job.recordType, job.ip, describeValues(missing), describeRecords(stale))
+
+	if err := u.startChange(job, oldValue); err != nil {
+		return updateResult{job: job, outcome: outcomeFailed, err: err}
+	}
+
+	// A failure part way leaves the changes already made in place
+	applied := false
+	failed := func(err error) updateResult {
+		return updateResult{job: job, outcome: outcomeFailed, oldValue: oldValue, started: true, applied: applied, err: err}
+	}
+
+	// Reuse stale records for missing addresses before creating or deleting
+	for len(stale) > 0 && len(missing) > 0 {
+		record, ip := stale[0], missing[0]
+		err := policy.Do(log, "Modify record", func() error {
+			return provider.UpdateRecord(record.RecordID, job.domain, job.subDomain, job.recordType, ip)
+		})
+		if err != nil {
+			return failed(fmt.Errorf("failed to modify record %s: %v", record.RecordID, err))
+		}
+		log.Info("Record updated successfully: %s -> %s", record.Value, ip)
+		u.recordHistory(job, HistoryEntry{Event: HistoryUpdated, RecordID: record.RecordID, OldValue: record.Value, NewValue: ip})
+		applied = true
+		stale, missing = stale[1:], missing[1:]
+	}
+
+	for _, ip := range missing {
+		var recordID string
+		err := policy.Do(log, "Create record", func() error {
+			var err error
+			recordID, err = provider.CreateRecord(job.domain, job.subDomain, job.recordType, ip)
+			return err
+		})
+		if err != nil {
+			return failed(fmt.Errorf("failed to create record for %s: %v", ip, err))
+		}
+		log.Info("Record created successfully: %s, ID=%s", ip, recordID)
+		u.recordHistory(job, HistoryEntry{Event: HistoryCreated, RecordID: recordID, NewValue: ip})
+		applied = true
+	}
+
+	for _, record := range stale {
+		err := policy.Do(log, "Delete record", func() error {
+			return provider.DeleteRecord(record.RecordID, job.domain, job.subDomain, job.recordType)
+		})
+		if err != nil {
+			return failed(fmt.Errorf("failed to delete record %s: %v", record.RecordID, err))
+		}
+		log.Info("Record deleted successfully: %s", describeRecords([]DNSRecord{record}))
+		u.recordHistory(job, HistoryEntry{Event: HistoryDeleted, RecordID: record.RecordID, OldValue: record.Value})
+		applied = true
+	}
+
+	if len(records) == 0 {
+		return updateResult{job: job, outcome: outcomeCreated, started: true, applied: true}
+	}
+	return updateResult{job: job, outcome: outcomeUpdated, oldValue: oldValue, started: true, applied: true}
+}
+
+// normalizeIP returns value in canonical form when it is an IP address, so
+// differently formatted IPv6 values compare equal
+func normalizeIP(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return value
+}
+
+// describeValues lists values for logging
+func describeValues(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+// describeRecords lists records for logging. The ID is left out when the
+// value doubles as the ID.
+func describeRecords(records []DNSRecord) string {
+	if len(records) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.RecordID == record.Value {
+			parts = append(parts, record.Value)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (ID=%s)", record.Value, record.RecordID))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,437 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"ddnsd/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ipSource serves an address as an IP detection URL for the duration of the
+// test and returns its URL
+func ipSource(t *testing.T, ip string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ip)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// seedRecords creates records for the name in a provider without faults
+func seedRecords(t *testing.T, p DNSProvider, subdomain, recordType string, values ...string) {
+	for _, value := range values {
+		if _, err := p.CreateRecord("example.com", subdomain, recordType, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// failingNames wraps a provider, failing every call for the listed names
+type failingNames struct {
+	DNSProvider
+	names map[string]bool
+}
+
+func (f failingNames) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	if f.names[subdomain] {
+		return nil, permanentError(fmt.Errorf("injected failure for %s", subdomain))
+	}
+	return f.DNSProvider.GetRecords(domain, subdomain, recordType)
+}
+
+// TestRunJobsOrder checks that a worker pool returns results in job order
+// while running jobs in parallel
+func TestRunJobsOrder(t *testing.T) {
+	provider := NewMemoryProvider()
+	provider.SetLatency(20 * time.Millisecond)
+	u := NewUpdater(provider, &config.Config{Concurrency: 4})
+
+	var jobs []updateJob
+	for i := 0; i < 8; i++ {
+		subdomain := fmt.Sprintf("host%d", i)
+		jobs = append(jobs, updateJob{
+			domain: "example.com", subDomain: subdomain, recordType: "A",
+			ip: fmt.Sprintf("192.0.2.%d", i), ips: []string{fmt.Sprintf("192.0.2.%d", i)},
+			log: utils.NewLogger(""),
+		})
+	}
+
+	start := time.Now()
+	results := u.runJobs(jobs, 4)
+	elapsed := time.Since(start)
+
+	for i, result := range results {
+		if result.job.subDomain != jobs[i].subDomain || result.outcome != outcomeCreated {
+			t.Errorf("result %d is %s %v, want %s created", i, result.job.subDomain, result.outcome, jobs[i].subDomain)
+		}
+		records := provider.Records("example.com", jobs[i].subDomain, "A")
+		if len(records) != 1 || records[0].Value != jobs[i].ip {
+			t.Errorf("%s holds %v, want %s", jobs[i].subDomain, records, jobs[i].ip)
+		}
+	}
+	// 8 jobs of two 20ms calls take 320ms one at a time
+	if elapsed > 250*time.Millisecond {
+		t.Errorf("jobs took %v, want them run in parallel", elapsed)
+	}
+}
+
+// TestRunSummaryConcurrent checks the cycle summary counts when records
+// are updated in parallel
+func TestRunSummaryConcurrent(t *testing.T) {
+	memory := NewMemoryProvider()
+	seedRecords(t, memory, "same", "A", "192.0.2.1")
+	seedRecords(t, memory, "stale", "A", "192.0.2.9")
+	memory.SetLatency(5 * time.Millisecond)
+	provider := failingNames{memory, map[string]bool{"broken": true}}
+
+	cfg := &config.Config{
+		IPv4Enabled:    true,
+		IPv4Domain:     "example.com",
+		IPv4SubDomains: []string{"new1", "same", "broken", "stale", "new2", "new3"},
+		IPv4Sources:    []string{ipSource(t, "192.0.2.1")},
+		Concurrency:    3,
+	}
+	summary := NewUpdater(provider, cfg).Run()
+
+	want := CycleSummary{Unchanged: 1, Updated: 1, Created: 3, Failed: 1}
+	summary.Duration = 0
+	if summary != want {
+		t.Errorf("summary is %+v, want %+v", summary, want)
+	}
+	for _, subdomain := range []string{"new1", "same", "stale", "new2", "new3"} {
+		if records := memory.Records("example.com", subdomain, "A"); len(records) != 1 || records[0].Value != "192.0.2.1" {
+			t.Errorf("%s holds %v, want 192.0.2.1", subdomain, records)
+		}
+	}
+}
+
+// concurrentCalls counts the calls a provider is serving at the same time
+type concurrentCalls struct {
+	DNSProvider
+	mu            sync.Mutex
+	current, peak int
+}
+
+func (c *concurrentCalls) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	c.mu.Lock()
+	c.current++
+	if c.current > c.peak {
+		c.peak = c.current
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.current--
+		c.mu.Unlock()
+	}()
+	return c.DNSProvider.GetRecords(domain, subdomain, recordType)
+}
+
+// TestRunJobsBounded checks that no more than UPDATE_CONCURRENCY records
+// are processed at once
+func TestRunJobsBounded(t *testing.T) {
+	memory := NewMemoryProvider()
+	memory.SetLatency(10 * time.Millisecond)
+	provider := &concurrentCalls{DNSProvider: memory}
+	u := NewUpdater(provider, &config.Config{Concurrency: 2})
+
+	var jobs []updateJob
+	for i := 0; i < 6; i++ {
+		jobs = append(jobs, updateJob{
+			domain: "example.com", subDomain: fmt.Sprintf("host%d", i), recordType: "A",
+			ip: "192.0.2.1", ips: []string{"192.0.2.1"}, log: utils.NewLogger(""),
+		})
+	}
+	u.runJobs(jobs, 2)
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if provider.peak != 2 {
+		t.Errorf("%d records were queried at once, want 2", provider.peak)
+	}
+}
+
+// changeCalls records the changes an updater asks a provider to make
+type changeCalls struct {
+	DNSProvider
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *changeCalls) add(call string) {
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	c.mu.Unlock()
+}
+
+func (c *changeCalls) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	c.add("create " + value)
+	return c.DNSProvider.CreateRecord(domain, subdomain, recordType, value)
+}
+
+func (c *changeCalls) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	c.add("update " + recordID + " " + value)
+	return c.DNSProvider.UpdateRecord(recordID, domain, subdomain, recordType, value)
+}
+
+func (c *changeCalls) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	c.add("delete " + recordID)
+	return c.DNSProvider.DeleteRecord(recordID, domain, subdomain, recordType)
+}
+
+// TestUpdateDuplicates runs each duplicate policy against names holding
+// several records, seeded the way MEMORY_DUPLICATES creates them or with
+// mixed values
+func TestUpdateDuplicates(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		duplicates string   // created with MEMORY_DUPLICATES, as IDs 1 and 2
+		seed       []string // created one by one
+		fail       []error
+		outcome    updateOutcome
+		started    bool
+		err        string
+		calls      string
+		records    string
+	}{
+		{
+			name: "update every copy", policy: "update", duplicates: "192.0.2.1",
+			outcome: outcomeUpdated, started: true,
+			calls:   "[update 1 192.0.2.9 update 2 192.0.2.9]",
+			records: "[{1 192.0.2.9} {2 192.0.2.9}]",
+		},
+		{
+			name: "update only stale records", policy: "update", seed: []string{"192.0.2.1", "192.0.2.9", "192.0.2.2"},
+			outcome: outcomeUpdated, started: true,
+			calls:   "[update 1 192.0.2.9 update 3 192.0.2.9]",
+			records: "[{1 192.0.2.9} {2 192.0.2.9} {3 192.0.2.9}]",
+		},
+		{
+			name: "default policy updates", policy: "", duplicates: "192.0.2.1",
+			outcome: outcomeUpdated, started: true,
+			calls:   "[update 1 192.0.2.9 update 2 192.0.2.9]",
+			records: "[{1 192.0.2.9} {2 192.0.2.9}]",
+		},
+		{
+			name: "update with every copy current", policy: "update", duplicates: "192.0.2.9",
+			outcome: outcomeUnchanged,
+			calls:   "[]",
+			records: "[{1 192.0.2.9} {2 192.0.2.9}]",
+		},
+		{
+			name: "delete keeps the record holding the IP", policy: "delete", seed: []string{"192.0.2.1", "192.0.2.9", "192.0.2.1"},
+			outcome: outcomeUpdated, started: true,
+			calls:   "[delete 1 delete 3]",
+			records: "[{2 192.0.2.9}]",
+		},
+		{
+			name: "delete updates the first record", policy: "delete", duplicates: "192.0.2.1",
+			outcome: outcomeUpdated, started: true,
+			calls:   "[update 1 192.0.2.9 delete 2]",
+			records: "[{1 192.0.2.9}]",
+		},
+		{
+			name: "refuse changes nothing", policy: "refuse", seed: []string{"192.0.2.1", "192.0.2.2"},
+			outcome: outcomeFailed, err: "DUPLICATE_POLICY=refuse",
+			calls:   "[]",
+			records: "[{1 192.0.2.1} {2 192.0.2.2}]",
+		},
+		{
+			name: "delete fails part way", policy: "delete", seed: []string{"192.0.2.1", "192.0.2.9", "192.0.2.1"},
+			fail:    []error{nil, nil, permanentError(fmt.Errorf("injected failure"))},
+			outcome: outcomeFailed, started: true, err: "failed to delete record 3: injected failure",
+			calls:   "[delete 1 delete 3]",
+			records: "[{2 192.0.2.9} {3 192.0.2.1}]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := NewMemoryProvider()
+			if tt.duplicates != "" {
+				memory.SetDuplicates(true)
+				seedRecords(t, memory, "www", "A", tt.duplicates)
+				memory.SetDuplicates(false)
+			}
+			seedRecords(t, memory, "www", "A", tt.seed...)
+			memory.FailNext(tt.fail...)
+			provider := &changeCalls{DNSProvider: memory}
+			u := NewUpdater(provider, &config.Config{DuplicatePolicy: tt.policy})
+
+			result := u.updateRecord(updateJob{
+				domain: "example.com", subDomain: "www", recordType: "A",
+				ip: "192.0.2.9", ips: []string{"192.0.2.9"}, log: utils.NewLogger(""),
+			})
+			if result.outcome != tt.outcome || result.started != tt.started {
+				t.Errorf("result %v started=%v, want %v started=%v", result.outcome, result.started, tt.outcome, tt.started)
+			}
+			if tt.err == "" && result.err != nil || tt.err != "" && (result.err == nil || !strings.Contains(result.err.Error(), tt.err)) {
+				t.Errorf("error %v, want %q", result.err, tt.err)
+			}
+			if got := fmt.Sprint(provider.calls); got != tt.calls {
+				t.Errorf("calls %s, want %s", got, tt.calls)
+			}
+			if got := fmt.Sprint(memory.Records("example.com", "www", "A")); got != tt.records {
+				t.Errorf("records %s, want %s", got, tt.records)
+			}
+		})
+	}
+}
+
+// valueSet is a provider storing one name as a multi-value set, using each
+// value as its record ID the way set-based providers do
+type valueSet struct {
+	mu       sync.Mutex
+	values   []string
+	failures []error
+}
+
+// change applies fn to the set unless the next injected failure is due
+func (v *valueSet) change(fn func()) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.failures) > 0 {
+		err := v.failures[0]
+		v.failures = v.failures[1:]
+		if err != nil {
+			return err
+		}
+	}
+	fn()
+	return nil
+}
+
+func (v *valueSet) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	var records []DNSRecord
+	err := v.change(func() { records = setRecords(v.values) })
+	return records, err
+}
+
+func (v *valueSet) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	return value, v.change(func() { v.values = replaceRecordValue(v.values, "", value) })
+}
+
+func (v *valueSet) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return v.change(func() { v.values = replaceRecordValue(v.values, recordID, value) })
+}
+
+func (v *valueSet) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return v.change(func() { v.values = replaceRecordValue(v.values, recordID, "") })
+}
+
+// TestReconcileRecords brings names with several sources to the detected
+// set of addresses, in a store with record IDs and in one using values as
+// IDs
+func TestReconcileRecords(t *testing.T) {
+	injected := permanentError(fmt.Errorf("injected failure"))
+	tests := []struct {
+		name    string
+		store   string // "id" for MemoryProvider, "value" for valueSet
+		seed    []string
+		ips     []string
+		fail    []error
+		outcome updateOutcome
+		started bool
+		err     string
+		calls   string
+		records string
+	}{
+		{
+			name: "create into an empty name", store: "id", ips: []string{"192.0.2.1", "192.0.2.2"},
+			outcome: outcomeCreated, started: true,
+			calls:   "[create 192.0.2.1 create 192.0.2.2]",
+			records: "[{1 192.0.2.1} {2 192.0.2.2}]",
+		},
+		{
+			name: "reuse stale records", store: "id", seed: []string{"192.0.2.8", "192.0.2.1", "192.0.2.9"}, ips: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"},
+			outcome: outcomeUpdated, started: true,
+			calls:   "[update 1 192.0.2.2 update 3 192.0.2.3 create 192.0.2.4]",
+			records: "[{1 192.0.2.2} {2 192.0.2.1} {3 192.0.2.3} {4 192.0.2.4}]",
+		},
+		{
+			name: "delete repeats of a wanted value", store: "id", seed: []string{"192.0.2.1", "192.0.2.1", "192.0.2.2", "192.0.2.9"}, ips: []string{"192.0.2.1", "192.0.2.2"},
+			outcome: outcomeUpdated, started: true,
+			calls:   "[delete 2 delete 4]",
+			records: "[{1 192.0.2.1} {3 192.0.2.2}]",
+		},
+		{
+			name: "non-canonical IPv6 is unchanged", store: "id", seed: []string{"2001:0db8:0000::0001", "2001:DB8::2"}, ips: []string{"2001:db8::1", "2001:db8::2"},
+			outcome: outcomeUnchanged,
+			calls:   "[]",
+			records: "[{1 2001:0db8:0000::0001} {2 2001:DB8::2}]",
+		},
+		{
+			name: "fail part way", store: "id", seed: []string{"192.0.2.1", "192.0.2.8", "192.0.2.9", "192.0.2.1"}, ips: []string{"192.0.2.1", "192.0.2.2"},
+			fail:    []error{nil, nil, nil, injected},
+			outcome: outcomeFailed, started: true, err: "failed to delete record 4: injected failure",
+			calls:   "[update 2 192.0.2.2 delete 3 delete 4]",
+			records: "[{1 192.0.2.1} {2 192.0.2.2} {4 192.0.2.1}]",
+		},
+		{
+			name: "value store reuses stale values", store: "value", seed: []string{"192.0.2.9", "192.0.2.1"}, ips: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+			outcome: outcomeUpdated, started: true,
+			calls:   "[update 192.0.2.9 192.0.2.2 create 192.0.2.3]",
+			records: "[{192.0.2.1 192.0.2.1} {192.0.2.2 192.0.2.2} {192.0.2.3 192.0.2.3}]",
+		},
+		{
+			name: "value store deletes stale values", store: "value", seed: []string{"192.0.2.1", "192.0.2.8", "192.0.2.2", "192.0.2.9"}, ips: []string{"192.0.2.2"},
+			outcome: outcomeUpdated, started: true,
+			calls:   "[delete 192.0.2.1 delete 192.0.2.8 delete 192.0.2.9]",
+			records: "[{192.0.2.2 192.0.2.2}]",
+		},
+		{
+			name: "value store non-canonical IPv6 is unchanged", store: "value", seed: []string{"2001:db8:0:0:0:0:0:1"}, ips: []string{"2001:db8::1"},
+			outcome: outcomeUnchanged,
+			calls:   "[]",
+			records: "[{2001:db8:0:0:0:0:0:1 2001:db8:0:0:0:0:0:1}]",
+		},
+		{
+			name: "value store fails part way", store: "value", seed: []string{"192.0.2.8", "192.0.2.9"}, ips: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+			fail:    []error{nil, nil, injected},
+			outcome: outcomeFailed, started: true, err: "failed to modify record 192.0.2.9: injected failure",
+			calls:   "[update 192.0.2.8 192.0.2.1 update 192.0.2.9 192.0.2.2]",
+			records: "[{192.0.2.9 192.0.2.9} {192.0.2.1 192.0.2.1}]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var store DNSProvider
+			var records func() []DNSRecord
+			if tt.store == "id" {
+				memory := NewMemoryProvider()
+				seedRecords(t, memory, "www", "A", tt.seed...)
+				memory.FailNext(tt.fail...)
+				store = memory
+				records = func() []DNSRecord { return memory.Records("example.com", "www", "A") }
+			} else {
+				set := &valueSet{values: tt.seed, failures: tt.fail}
+				store = set
+				records = func() []DNSRecord { return setRecords(set.values) }
+			}
+			provider := &changeCalls{DNSProvider: store}
+			u := NewUpdater(provider, &config.Config{})
+
+			result := u.updateRecord(updateJob{
+				domain: "example.com", subDomain: "www", recordType: "A",
+				ip: strings.Join(tt.ips, ","), ips: tt.ips, reconcile: true, log: utils.NewLogger(""),
+			})
+			if result.outcome != tt.outcome || result.started != tt.started {
+				t.Errorf("result %v started=%v, want %v started=%v", result.outcome, result.started, tt.outcome, tt.started)
+			}
+			if tt.err == "" && result.err != nil || tt.err != "" && (result.err == nil || !strings.Contains(result.err.Error(), tt.err)) {
+				t.Errorf("error %v, want %q", result.err, tt.err)
+			}
+			if got := fmt.Sprint(provider.calls); got != tt.calls {
+				t.Errorf("calls %s, want %s", got, tt.calls)
+			}
+			if got := fmt.Sprint(records()); got != tt.records {
+				t.Errorf("records %s, want %s", got, tt.records)
+			}
+		})
+	}
+}
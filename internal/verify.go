@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"context"
+	"ddnsd/config"
+	"ddnsd/utils"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// propagationVerifier checks that a zone's authoritative nameservers serve
+// the value that was just written to the provider
+type propagationVerifier struct {
+	timeout  time.Duration
+	interval time.Duration
+	servers  func(ctx context.Context, domain string) ([]string, error)
+}
+
+// newPropagationVerifier returns a verifier, or nil when verification is disabled
+func newPropagationVerifier(cfg *config.Config) *propagationVerifier {
+	if !cfg.VerifyEnabled {
+		return nil
+	}
+	return &propagationVerifier{
+		timeout:  time.Duration(cfg.VerifyTimeout) * time.Second,
+		interval: time.Duration(cfg.VerifyInterval) * time.Second,
+		servers:  authoritativeServers,
+	}
+}
+
+// Verify polls every authoritative nameserver of domain until all of them
+// answer with exactly values for the record, so stale values that are still
+// served fail verification, or the timeout passes
+func (v *propagationVerifier) Verify(log *utils.Logger, domain, subDomain, recordType string, values []string) error {
+	value := strings.Join(values, ",")
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+
+	fqdn := domain
+	if subDomain != "@" {
+		fqdn = subDomain + "." + domain
+	}
+
+	servers, err := v.servers(ctx, domain)
+	if err != nil {
+		return fmt.Errorf("failed to find authoritative nameservers: %v", err)
+	}
+
+	pending := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		pending[server] = true
+	}
+
+	for attempt := 1; ; attempt++ {
+		for server := range pending {
+			served, err := queryServer(ctx, server, fqdn, recordType)
+			if err != nil {
+				log.Warning("Verification query to %s failed: %v", server, err)
+				continue
+			}
+			if sameIPs(served, values) {
+				delete(pending, server)
+			}
+		}
+
+		if len(pending) == 0 {
+			log.Info("Verified %s on %d authoritative nameservers after %d attempts", value, len(servers), attempt)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not served exactly by %s after %v", value, strings.Join(sortedKeys(pending), ", "), v.timeout)
+		case <-time.After(v.interval):
+		}
+	}
+}
+
+// authoritativeServers resolves the NS records of the zone containing domain
+// and returns their addresses. Parent labels are tried when domain itself
+// has no NS records.
+func authoritativeServers(ctx context.Context, domain string) ([]string, error) {
+	zone := strings.TrimSuffix(domain, ".")
+	for {
+		nsRecords, err := net.DefaultResolver.LookupNS(ctx, zone)
+		if err == nil && len(nsRecords) > 0 {
+			var servers []string
+			for _, ns := range nsRecords {
+				addrs, err := net.DefaultResolver.LookupHost(ctx, ns.Host)
+				if err != nil || len(addrs) == 0 {
+					continue
+				}
+				servers = append(servers, net.JoinHostPort(addrs[0], "53"))
+			}
+			if len(servers) == 0 {
+				return nil, fmt.Errorf("no addresses for nameservers of %s", zone)
+			}
+			return servers, nil
+		}
+
+		i := strings.Index(zone, ".")
+		if i < 0 || !strings.Contains(zone[i+1:], ".") {
+			return nil, fmt.Errorf("no NS records found for %s", domain)
+		}
+		zone = zone[i+1:]
+	}
+}
+
+// queryServer looks up fqdn directly against a single nameserver
+func queryServer(ctx context.Context, server, fqdn, recordType string) ([]string, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+
+	network := "ip4"
+	if recordType == "AAAA" {
+		network = "ip6"
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ips, err := resolver.LookupIP(queryCtx, network, fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+	return values, nil
+}
+
+// containsIP reports whether values contains the address ip, comparing
+// parsed addresses so differently formatted IPv6 values still match
+func containsIP(values []string, ip string) bool {
+	want := net.ParseIP(ip)
+	for _, v := range values {
+		if v == ip || (want != nil && want.Equal(net.ParseIP(v))) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameIPs reports whether values holds every address in ips and no others
+func sameIPs(values, ips []string) bool {
+	for _, ip := range ips {
+		if !containsIP(values, ip) {
+			return false
+		}
+	}
+	for _, v := range values {
+		if !containsIP(ips, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"context"
+	"ddnsd/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestContainsIP checks that addresses match however they are formatted
+func TestContainsIP(t *testing.T) {
+	tests := []struct {
+		values []string
+		ip     string
+		want   bool
+	}{
+		{[]string{"192.0.2.1", "192.0.2.2"}, "192.0.2.2", true},
+		{[]string{"192.0.2.1"}, "192.0.2.3", false},
+		{[]string{"2001:0db8:0:0:0:0:0:1"}, "2001:db8::1", true},
+		{[]string{"2001:db8::1"}, "2001:DB8::1", true},
+		{[]string{"2001:db8::1"}, "2001:db8::2", false},
+		{[]string{"::ffff:192.0.2.1"}, "192.0.2.1", true},
+		{[]string{"not-an-ip"}, "not-an-ip", true},
+		{[]string{"not-an-ip"}, "192.0.2.1", false},
+		{nil, "192.0.2.1", false},
+	}
+	for _, tt := range tests {
+		if got := containsIP(tt.values, tt.ip); got != tt.want {
+			t.Errorf("containsIP(%v, %s) = %v, want %v", tt.values, tt.ip, got, tt.want)
+		}
+	}
+}
+
+// TestSameIPs checks that served values must match the written set exactly
+func TestSameIPs(t *testing.T) {
+	tests := []struct {
+		values, ips []string
+		want        bool
+	}{
+		{[]string{"192.0.2.1"}, []string{"192.0.2.1"}, true},
+		{[]string{"192.0.2.2", "192.0.2.1"}, []string{"192.0.2.1", "192.0.2.2"}, true},
+		{[]string{"2001:db8:0:0:0:0:0:1"}, []string{"2001:db8::1"}, true},
+		{[]string{"192.0.2.1", "192.0.2.9"}, []string{"192.0.2.1"}, false},
+		{[]string{"192.0.2.1"}, []string{"192.0.2.1", "192.0.2.2"}, false},
+		{[]string{"192.0.2.9"}, []string{"192.0.2.1"}, false},
+		{nil, []string{"192.0.2.1"}, false},
+		{[]string{"192.0.2.1"}, nil, false},
+		{nil, nil, true},
+	}
+	for _, tt := range tests {
+		if got := sameIPs(tt.values, tt.ips); got != tt.want {
+			t.Errorf("sameIPs(%v, %v) = %v, want %v", tt.values, tt.ips, got, tt.want)
+		}
+	}
+}
+
+// TestVerify polls the in-process nameserver and checks that verification
+// succeeds once it serves exactly the values and times out otherwise
+func TestVerify(t *testing.T) {
+	server := newRFC2136StandIn(t, map[string][]string{
+		"example.com A":     {"192.0.2.100"},
+		"www.example.com A": {"192.0.2.1", "192.0.2.2"},
+	})
+	v := &propagationVerifier{
+		timeout:  300 * time.Millisecond,
+		interval: 20 * time.Millisecond,
+		servers: func(ctx context.Context, domain string) ([]string, error) {
+			return []string{server.conn.LocalAddr().String()}, nil
+		},
+	}
+	log := utils.NewLogger("")
+
+	tests := []struct {
+		subDomain string
+		values    []string
+		wantErr   string
+	}{
+		{"www", []string{"192.0.2.2", "192.0.2.1"}, ""},
+		{"@", []string{"192.0.2.100"}, ""},
+		{"www", []string{"192.0.2.1"}, "192.0.2.1 not served exactly by " + server.conn.LocalAddr().String()},
+		{"www", []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}, "not served exactly"},
+		{"mail", []string{"192.0.2.1"}, "not served exactly"},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		err := v.Verify(log, "example.com", tt.subDomain, "A", tt.values)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Verify(%s, %v) = %v", tt.subDomain, tt.values, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("Verify(%s, %v) = %v, want error containing %q", tt.subDomain, tt.values, err, tt.wantErr)
+		case tt.wantErr != "" && time.Since(start) < v.timeout:
+			t.Errorf("Verify(%s, %v) gave up after %v, before the %v timeout", tt.subDomain, tt.values, time.Since(start), v.timeout)
+		}
+	}
+
+	// The record changes on the nameserver while verification is polling
+	server.mu.Lock()
+	server.records["www.example.com A"] = []string{"192.0.2.1"}
+	server.mu.Unlock()
+	go func() {
+		time.Sleep(60 * time.Millisecond)
+		server.mu.Lock()
+		server.records["www.example.com A"] = []string{"192.0.2.5"}
+		server.mu.Unlock()
+	}()
+	if err := v.Verify(log, "example.com", "www", "A", []string{"192.0.2.5"}); err != nil {
+		t.Errorf("Verify after propagation = %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const volcengineAPIVersion = "2018-08-01"
+
+// VolcengineProvider implements DNSProvider for Volcengine TrafficRoute DNS
+type VolcengineProvider struct {
+	accessKey string
+	secretKey string
+	endpoint  string
+	ttl       int
+	client    *http.Client
+	signer    hmacSigner
+
+	mu      sync.Mutex
+	zoneIDs map[string]int64
+}
+
+// Volcengine API structures
+type volcengineResponse struct {
+	ResponseMetadata struct {
+		RequestID string `json:"RequestId"`
+		Error     *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+	} `json:"ResponseMetadata"`
+	Result json.RawMessage `json:"Result"`
+}
+
+type volcengineZonesResult struct {
+	Zones []struct {
+		ZID      int64  `json:"ZID"`
+		ZoneName string `json:"ZoneName"`
+	} `json:"Zones"`
+}
+
+type volcengineRecord struct {
+	RecordID string `json:"RecordID"`
+	Host     string `json:"Host"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	TTL      int    `json:"TTL"`
+	Line     string `json:"Line"`
+}
+
+type volcengineRecordsResult struct {
+	Records []volcengineRecord `json:"Records"`
+}
+
+type volcengineCreateRequest struct {
+	ZID   int64  `json:"ZID"`
+	Host  string `json:"Host"`
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+	TTL   int    `json:"TTL"`
+}
+
+type volcengineUpdateRequest struct {
+	RecordID string `json:"RecordID"`
+	Host     string `json:"Host"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	Line     string `json:"Line"`
+}
+
+// newVolcengineProvider creates a new Volcengine DNS provider instance.
+// region defaults to cn-north-1; endpoint overrides the API URL.
+func newVolcengineProvider(accessKey, secretKey, region, endpoint string, ttl int) (*VolcengineProvider, error) {
+	if region == "" {
+		region = "cn-north-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://open.volcengineapi.com"
+	}
+
+	return &VolcengineProvider{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		ttl:       ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		signer:  newVolcengineSigner(region, "DNS"),
+		zoneIDs: make(map[string]int64),
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (v *VolcengineProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	zoneID, err := v.zoneID(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("ZID", strconv.FormatInt(zoneID, 10))
+	query.Set("Host", subdomain)
+	query.Set("Type", recordType)
+	query.Set("SearchMode", "exact")
+
+	var result volcengineRecordsResult
+	if err := v.doRequest("GET", "ListRecords", query, nil, &result); err != nil {
+		return nil, err
+	}
+
+	var records []DNSRecord
+	for _, record := range result.Records {
+		if record.Host == subdomain && record.Type == recordType {
+			records = append(records, DNSRecord{
+				RecordID: record.RecordID,
+				Value:    record.Value,
+			})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord creates a new DNS record
+func (v *VolcengineProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	zoneID, err := v.zoneID(domain)
+	if err != nil {
+		return "", err
+	}
+
+	createReq := volcengineCreateRequest{
+		ZID:   zoneID,
+		Host:  subdomain,
+		Type:  recordType,
+		Value: value,
+		TTL:   v.ttl,
+	}
+
+	var result struct {
+		RecordID string `json:"RecordID"`
+	}
+	if err := v.doRequest("POST", "CreateRecord", nil, createReq, &result); err != nil {
+		return "", err
+	}
+
+	return result.RecordID, nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (v *VolcengineProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	updateReq := volcengineUpdateRequest{
+		RecordID: recordID,
+		Host:     subdomain,
+		Type:     recordType,
+		Value:    value,
+		Line:     "default",
+	}
+
+	return v.doRequest("POST", "UpdateRecord", nil, updateReq, nil)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (v *VolcengineProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	deleteReq := map[string]string{"RecordID": recordID}
+	return v.doRequest("POST", "DeleteRecord", nil, deleteReq, nil)
+}
+
+// zoneID resolves the zone ID (ZID) for domain and caches it
+func (v *VolcengineProvider) zoneID(domain string) (int64, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if id, ok := v.zoneIDs[domain]; ok {
+		return id, nil
+	}
+
+	query := url.Values{}
+	query.Set("Key", domain)
+	query.Set("SearchMode", "exact")
+
+	var result volcengineZonesResult
+	if err := v.doRequest("GET", "ListZones", query, nil, &result); err != nil {
+		return 0, err
+	}
+
+	for _, zone := range result.Zones {
+		if strings.EqualFold(zone.ZoneName, domain) {
+			v.zoneIDs[domain] = zone.ZID
+			return zone.ZID, nil
+		}
+	}
+	return 0, permanentError(fmt.Errorf("zone not found: %s", domain))
+}
+
+// doRequest sends a signed OpenAPI request for action and decodes the
+// result into out when it is not nil
+func (v *VolcengineProvider) doRequest(method, action string, query url.Values, payload interface{}, out interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return permanentError(fmt.Errorf("failed to marshal request: %v", err))
+		}
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("Action", action)
+	query.Set("Version", volcengineAPIVersion)
+
+	req, err := http.NewRequest(method, v.endpoint+"/?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	v.signer.sign(req, body, v.accessKey, v.secretKey, time.Now())
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+
+	statusErr := statusError(resp, respBody)
+
+	var apiResp volcengineResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		if statusErr != nil {
+			return statusErr
+		}
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+
+	if apiErr := apiResp.ResponseMetadata.Error; apiErr != nil && apiErr.Code != "" {
+		err := fmt.Errorf("API request failed: %s - %s", apiErr.Code, apiErr.Message)
+		if statusErr != nil && IsRetryable(statusErr) {
+			return retryableError(err, retryAfter(statusErr))
+		}
+		if isVolcengineRetryableCode(apiErr.Code) {
+			return retryableError(err, 0)
+		}
+		return permanentError(err)
+	}
+	if statusErr != nil {
+		return statusErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(apiResp.Result, out); err != nil {
+		return permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return nil
+}
+
+// isVolcengineRetryableCode reports whether an API error code indicates
+// throttling or a transient server-side failure
+func isVolcengineRetryableCode(code string) bool {
+	return strings.Contains(code, "LimitExceeded") ||
+		strings.HasPrefix(code, "Throttling") ||
+		strings.HasPrefix(code, "InternalError") ||
+		code == "ServiceUnavailable"
+}
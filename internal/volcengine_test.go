@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestVolcengineSignKnownAnswer checks Volcengine request signatures
+// against values computed independently from the OpenAPI signing
+// documentation
+func TestVolcengineSignKnownAnswer(t *testing.T) {
+	tests := []struct {
+		method, url, body string
+		signedHeaders     string
+		signature         string
+	}{
+		{
+			"GET", "https://open.volcengineapi.com/?Action=ListZones&Key=example.com&SearchMode=exact&Version=2018-08-01", "",
+			"host;x-content-sha256;x-date",
+			"d483c0ae3610bea23df3c8f7ab9f0e5f298e182fa6ab697b0fb1be2bd379aca6",
+		},
+		{
+			"POST", "https://open.volcengineapi.com/?Action=CreateRecord&Version=2018-08-01", `{"ZID":42,"Host":"www","Type":"A","Value":"192.0.2.1","TTL":300}`,
+			"content-type;host;x-content-sha256;x-date",
+			"0fbbd0eb3e8cdcb72615d8f19f3665d6b03bb1cc6a48ec890ef76c9faeae4e2c",
+		},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		var body []byte
+		if test.body != "" {
+			body = []byte(test.body)
+			req.Header.Set("Content-Type", "application/json")
+		}
+		newVolcengineSigner("cn-north-1", "DNS").sign(req, body, "AKLTEXAMPLE", "volcengine-secret-key", time.Unix(1700000000, 0))
+
+		want := "HMAC-SHA256 Credential=AKLTEXAMPLE/20231114/cn-north-1/DNS/request, SignedHeaders=" +
+			test.signedHeaders + ", Signature=" + test.signature
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s %s: Authorization is\n%s\nwant\n%s", test.method, test.url, got, want)
+		}
+	}
+}
+
+// volcengineStandIn is a minimal Volcengine DNS OpenAPI serving the
+// example.com zone as ZID 42. It re-signs every request to check the
+// signature.
+type volcengineStandIn struct {
+	mu      sync.Mutex
+	records []volcengineRecord
+	nextID  int
+}
+
+func (s *volcengineStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if !s.signatureValid(r, body) {
+		s.fail(w, http.StatusUnauthorized, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.")
+		return
+	}
+
+	query := r.URL.Query()
+	switch query.Get("Action") {
+	case "ListZones":
+		result := volcengineZonesResult{}
+		if query.Get("Key") == "example.com" {
+			result.Zones = append(result.Zones, struct {
+				ZID      int64  `json:"ZID"`
+				ZoneName string `json:"ZoneName"`
+			}{42, "example.com"})
+		}
+		s.reply(w, result)
+
+	case "ListRecords":
+		result := volcengineRecordsResult{Records: []volcengineRecord{}}
+		for _, record := range s.records {
+			if query.Get("ZID") == "42" && record.Host == query.Get("Host") && record.Type == query.Get("Type") {
+				result.Records = append(result.Records, record)
+			}
+		}
+		s.reply(w, result)
+
+	case "CreateRecord":
+		var create volcengineCreateRequest
+		json.Unmarshal(body, &create)
+		for _, record := range s.records {
+			if record.Host == create.Host && record.Type == create.Type && record.Value == create.Value {
+				s.fail(w, http.StatusBadRequest, "RecordDuplicated", "The record already exists.")
+				return
+			}
+		}
+		s.nextID++
+		record := volcengineRecord{
+			RecordID: fmt.Sprint(s.nextID), Host: create.Host, Type: create.Type,
+			Value: create.Value, TTL: create.TTL, Line: "default",
+		}
+		s.records = append(s.records, record)
+		s.reply(w, map[string]string{"RecordID": record.RecordID})
+
+	case "UpdateRecord", "DeleteRecord":
+		var change volcengineUpdateRequest
+		json.Unmarshal(body, &change)
+		for i := range s.records {
+			if s.records[i].RecordID != change.RecordID {
+				continue
+			}
+			if query.Get("Action") == "DeleteRecord" {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+			} else {
+				s.records[i].Host, s.records[i].Value, s.records[i].Line = change.Host, change.Value, change.Line
+			}
+			s.reply(w, map[string]string{})
+			return
+		}
+		s.fail(w, http.StatusNotFound, "RecordNotFound", "The record does not exist.")
+
+	default:
+		s.fail(w, http.StatusBadRequest, "InvalidActionOrVersion", "The action is not valid.")
+	}
+}
+
+// signatureValid signs a copy of the request again with the time it
+// claims and compares the Authorization headers
+func (s *volcengineStandIn) signatureValid(r *http.Request, body []byte) bool {
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Date"))
+	if err != nil {
+		return false
+	}
+	check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	check.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	newVolcengineSigner("cn-north-1", "DNS").sign(check, body, "AKLTEXAMPLE", "volcengine-secret-key", signedAt)
+	return check.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (s *volcengineStandIn) reply(w http.ResponseWriter, result interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ResponseMetadata": map[string]string{"RequestId": "request-1"},
+		"Result":           result,
+	})
+}
+
+func (s *volcengineStandIn) fail(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ResponseMetadata": map[string]interface{}{
+			"RequestId": "request-1",
+			"Error":     map[string]string{"Code": code, "Message": message},
+		},
+	})
+}
+
+// TestVolcengineErrors checks that API error codes are reported and
+// classified
+func TestVolcengineErrors(t *testing.T) {
+	standIn := &volcengineStandIn{records: []volcengineRecord{
+		{RecordID: "1", Host: "www", Type: "A", Value: "192.0.2.1", TTL: 600, Line: "default"},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+
+	p, _ := newVolcengineProvider("AKLTEXAMPLE", "volcengine-secret-key", "", server.URL, 300)
+	_, err := p.CreateRecord("example.com", "www", "A", "192.0.2.1")
+	if err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "RecordDuplicated - The record already exists.") {
+		t.Errorf("got error %v, want a permanent duplicate record error", err)
+	}
+
+	p, _ = newVolcengineProvider("AKLTEXAMPLE", "wrong-secret", "", server.URL, 300)
+	if _, err := p.GetRecords("example.com", "www", "A"); err == nil || IsRetryable(err) || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("got error %v, want a permanent signature error", err)
+	}
+}
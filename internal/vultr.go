@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// VultrProvider implements DNSProvider for Vultr DNS
+type VultrProvider struct {
+	api *restClient
+	ttl int
+}
+
+// Vultr API structures
+type vultrRecord struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type,omitempty"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+type vultrRecordsResponse struct {
+	Records []vultrRecord `json:"records"`
+	Meta    struct {
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	} `json:"meta"`
+}
+
+type vultrRecordResponse struct {
+	Record vultrRecord `json:"record"`
+}
+
+// newVultrProvider creates a new Vultr provider instance. endpoint
+// overrides the API URL.
+func newVultrProvider(token, endpoint string, ttl int) (*VultrProvider, error) {
+	if endpoint == "" {
+		endpoint = "https://api.vultr.com/v2"
+	}
+
+	return &VultrProvider{
+		api: newRESTClient(endpoint, bearerHeader(token), vultrErrorMessage),
+		ttl: ttl,
+	}, nil
+}
+
+// GetRecords retrieves the existing DNS records
+func (v *VultrProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	var records []DNSRecord
+	name := recordRelativeName(subdomain)
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("per_page", "500")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		var resp vultrRecordsResponse
+		path := fmt.Sprintf("/domains/%s/records?%s", url.PathEscape(domain), query.Encode())
+		if err := v.api.do("GET", path, nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, record := range resp.Records {
+			if strings.EqualFold(record.Name, name) && record.Type == recordType {
+				records = append(records, DNSRecord{
+					RecordID: record.ID,
+					Value:    record.Data,
+				})
+			}
+		}
+
+		cursor = resp.Meta.Links.Next
+		if cursor == "" {
+			return records, nil
+		}
+	}
+}
+
+// CreateRecord creates a new DNS record
+func (v *VultrProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	createReq := vultrRecord{
+		Type: recordType,
+		Name: recordRelativeName(subdomain),
+		Data: value,
+		TTL:  v.ttl,
+	}
+
+	var resp vultrRecordResponse
+	if err := v.api.do("POST", fmt.Sprintf("/domains/%s/records", url.PathEscape(domain)), createReq, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Record.ID, nil
+}
+
+// UpdateRecord updates an existing DNS record
+func (v *VultrProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	updateReq := vultrRecord{
+		Name: recordRelativeName(subdomain),
+		Data: value,
+	}
+
+	path := fmt.Sprintf("/domains/%s/records/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	return v.api.do("PATCH", path, updateReq, nil)
+}
+
+// DeleteRecord deletes an existing DNS record
+func (v *VultrProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	path := fmt.Sprintf("/domains/%s/records/%s", url.PathEscape(domain), url.PathEscape(recordID))
+	return v.api.do("DELETE", path, nil, nil)
+}
+
+// vultrErrorMessage extracts the message from an API error response
+func vultrErrorMessage(body []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+	return errResp.Error
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// vultrStandIn is a minimal Vultr DNS API for example.com that pages
+// listings two records at a time behind an opaque cursor
+type vultrStandIn struct {
+	mu      sync.Mutex
+	records []vultrRecord
+	nextID  int
+}
+
+func (s *vultrStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Invalid API token.", "status": 401})
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/domains/example.com/records")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Domain not found.", "status": 404})
+		return
+	}
+
+	switch {
+	case path == "" && r.Method == "GET":
+		start := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			start, _ = strconv.Atoi(strings.TrimPrefix(cursor, "after-"))
+		}
+		var resp vultrRecordsResponse
+		resp.Records = []vultrRecord{}
+		for i := start; i < start+2 && i < len(s.records); i++ {
+			resp.Records = append(resp.Records, s.records[i])
+		}
+		if start+2 < len(s.records) {
+			resp.Meta.Links.Next = fmt.Sprintf("after-%d", start+2)
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case path == "" && r.Method == "POST":
+		var record vultrRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		s.nextID++
+		record.ID = fmt.Sprintf("rec-%d", s.nextID)
+		s.records = append(s.records, record)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(vultrRecordResponse{Record: record})
+
+	default:
+		id := strings.TrimPrefix(path, "/")
+		for i := range s.records {
+			if s.records[i].ID != id {
+				continue
+			}
+			switch r.Method {
+			case "PATCH":
+				var update vultrRecord
+				json.NewDecoder(r.Body).Decode(&update)
+				s.records[i].Name, s.records[i].Data = update.Name, update.Data
+			case "DELETE":
+				s.records = append(s.records[:i], s.records[i+1:]...)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Record not found.", "status": 404})
+	}
+}
+
+// TestVultrCursorPaging checks that records are found across pages and
+// that changes touch only the record they are made to
+func TestVultrCursorPaging(t *testing.T) {
+	standIn := &vultrStandIn{nextID: 10, records: []vultrRecord{
+		{ID: "rec-1", Type: "NS", Name: "", Data: "ns1.vultr.com", TTL: 300},
+		{ID: "rec-2", Type: "AAAA", Name: "www", Data: "2001:db8::1", TTL: 300},
+		{ID: "rec-3", Type: "A", Name: "mail", Data: "192.0.2.50", TTL: 300},
+		{ID: "rec-4", Type: "A", Name: "www", Data: "192.0.2.1", TTL: 300},
+		{ID: "rec-5", Type: "A", Name: "WWW", Data: "192.0.2.9", TTL: 300},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	defer server.Close()
+
+	p, err := newVultrProvider("token", server.URL, 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := p.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	want := []DNSRecord{{RecordID: "rec-4", Value: "192.0.2.1"}, {RecordID: "rec-5", Value: "192.0.2.9"}}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("GetRecords returned %v, want %v", records, want)
+	}
+
+	if err := p.UpdateRecord("rec-4", "example.com", "www", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if err := p.DeleteRecord("rec-5", "example.com", "www", "A"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if id, err := p.CreateRecord("example.com", "@", "A", "192.0.2.3"); err != nil || id != "rec-11" {
+		t.Fatalf("CreateRecord: got ID %q and error %v, want rec-11", id, err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	wantRecords := []vultrRecord{
+		{ID: "rec-1", Type: "NS", Name: "", Data: "ns1.vultr.com", TTL: 300},
+		{ID: "rec-2", Type: "AAAA", Name: "www", Data: "2001:db8::1", TTL: 300},
+		{ID: "rec-3", Type: "A", Name: "mail", Data: "192.0.2.50", TTL: 300},
+		{ID: "rec-4", Type: "A", Name: "www", Data: "192.0.2.2", TTL: 300},
+		{ID: "rec-11", Type: "A", Name: "", Data: "192.0.2.3", TTL: 600},
+	}
+	if fmt.Sprint(standIn.records) != fmt.Sprint(wantRecords) {
+		t.Errorf("records are\n%v\nwant\n%v", standIn.records, wantRecords)
+	}
+}
@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// webhookEndpoint is the request template of one webhook operation
+type webhookEndpoint struct {
+	method string
+	url    string
+	body   string
+}
+
+// webhookTemplate is a parsed webhook request template
+type webhookTemplate struct {
+	method string
+	url    *template.Template
+	body   *template.Template
+}
+
+// webhookData is passed to the request and JSONPath templates
+type webhookData struct {
+	Domain    string
+	Subdomain string
+	Name      string
+	Type      string
+	Value     string
+	RecordID  string
+	TTL       int
+	SecretID  string
+	SecretKey string
+}
+
+// WebhookProvider implements DNSProvider for arbitrary HTTP APIs described
+// by request templates. Record IDs and values are extracted from JSON
+// responses with JSONPath.
+type WebhookProvider struct {
+	secretID  string
+	secretKey string
+	ttl       int
+	client    *http.Client
+
+	get, create, update, delete *webhookTemplate
+	header                      map[string]*template.Template
+
+	idPath, valuePath, createIDPath *template.Template
+}
+
+// newWebhookProvider creates a new webhook provider instance. headers holds
+// one "Name: value" template per line. idPath and valuePath pick the record
+// from the get response and createIDPath the new record's ID from the
+// create response; without an ID path the value doubles as the record ID.
+// The delete endpoint is optional.
+func newWebhookProvider(secretID, secretKey string, get, create, update, remove webhookEndpoint, headers, idPath, valuePath, createIDPath string, ttl int) (*WebhookProvider, error) {
+	if get.url == "" || create.url == "" || valuePath == "" {
+		return nil, fmt.Errorf("WEBHOOK_GET_URL, WEBHOOK_CREATE_URL and WEBHOOK_VALUE_PATH must be set for webhook")
+	}
+	if update.url == "" {
+		update.url = create.url
+	}
+
+	w := &WebhookProvider{
+		secretID:  secretID,
+		secretKey: secretKey,
+		ttl:       ttl,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		header: make(map[string]*template.Template),
+	}
+
+	var err error
+	if w.get, err = parseWebhookEndpoint("WEBHOOK_GET", get); err != nil {
+		return nil, err
+	}
+	if w.create, err = parseWebhookEndpoint("WEBHOOK_CREATE", create); err != nil {
+		return nil, err
+	}
+	if w.update, err = parseWebhookEndpoint("WEBHOOK_UPDATE", update); err != nil {
+		return nil, err
+	}
+	if remove.url != "" {
+		if w.delete, err = parseWebhookEndpoint("WEBHOOK_DELETE", remove); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, line := range strings.Split(headers, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid WEBHOOK_HEADERS line %q: must be Name: value", line)
+		}
+		if w.header[strings.TrimSpace(name)], err = parseWebhookTemplate("WEBHOOK_HEADERS", strings.TrimSpace(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	if w.idPath, err = parseWebhookTemplate("WEBHOOK_ID_PATH", idPath); err != nil {
+		return nil, err
+	}
+	if w.valuePath, err = parseWebhookTemplate("WEBHOOK_VALUE_PATH", valuePath); err != nil {
+		return nil, err
+	}
+	if w.createIDPath, err = parseWebhookTemplate("WEBHOOK_CREATE_ID_PATH", createIDPath); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// GetRecords sends the get request and extracts the records from the
+// response, pairing the nth ID with the nth value. A 404 response or a value
+// path matching nothing means there are no records.
+func (w *WebhookProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	data := w.data(domain, subdomain, recordType, "", "")
+	resp, err := w.send(w.get, data)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	values, err := w.extract(w.valuePath, resp, data)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+
+	recordIDs := values
+	if w.idPath != nil {
+		if recordIDs, err = w.extract(w.idPath, resp, data); err != nil {
+			return nil, err
+		}
+		if len(recordIDs) != len(values) {
+			return nil, permanentError(fmt.Errorf("WEBHOOK_ID_PATH matched %d IDs but WEBHOOK_VALUE_PATH matched %d values", len(recordIDs), len(values)))
+		}
+	}
+
+	records := make([]DNSRecord, len(values))
+	for i := range values {
+		records[i] = DNSRecord{RecordID: recordIDs[i], Value: values[i]}
+	}
+	return records, nil
+}
+
+// CreateRecord sends the create request
+func (w *WebhookProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	data := w.data(domain, subdomain, recordType, "", value)
+	resp, err := w.send(w.create, data)
+	if err != nil {
+		return "", err
+	}
+	if w.createIDPath == nil {
+		return value, nil
+	}
+
+	recordIDs, err := w.extract(w.createIDPath, resp, data)
+	if err != nil {
+		return "", err
+	}
+	if len(recordIDs) == 0 {
+		return "", permanentError(fmt.Errorf("record created but WEBHOOK_CREATE_ID_PATH matched nothing"))
+	}
+	return recordIDs[0], nil
+}
+
+// UpdateRecord sends the update request
+func (w *WebhookProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	_, err := w.send(w.update, w.data(domain, subdomain, recordType, recordID, value))
+	return err
+}
+
+// DeleteRecord sends the delete request
+func (w *WebhookProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	if w.delete == nil {
+		return permanentError(fmt.Errorf("WEBHOOK_DELETE_URL must be set to delete records"))
+	}
+	_, err := w.send(w.delete, w.data(domain, subdomain, recordType, recordID, ""))
+	return err
+}
+
+// data returns the template data for a request
+func (w *WebhookProvider) data(domain, subdomain, recordType, recordID, value string) webhookData {
+	return webhookData{
+		Domain:    domain,
+		Subdomain: subdomain,
+		Name:      recordFullName(domain, subdomain),
+		Type:      recordType,
+		Value:     value,
+		RecordID:  recordID,
+		TTL:       w.ttl,
+		SecretID:  w.secretID,
+		SecretKey: w.secretKey,
+	}
+}
+
+// send renders and sends a request, returning the decoded JSON response.
+// A 404 response returns nil without error.
+func (w *WebhookProvider) send(tmpl *webhookTemplate, data webhookData) (interface{}, error) {
+	requestURL, err := renderWebhookTemplate(tmpl.url, data)
+	if err != nil {
+		return nil, err
+	}
+	body := ""
+	if tmpl.body != nil {
+		if body, err = renderWebhookTemplate(tmpl.body, data); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(tmpl.method, requestURL, strings.NewReader(body))
+	if err != nil {
+		return nil, permanentError(fmt.Errorf("failed to create request: %v", err))
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range w.header {
+		rendered, err := renderWebhookTemplate(value, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, rendered)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, retryableError(fmt.Errorf("failed to read response body: %v", err), 0)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if statusErr := statusError(resp, respBody); statusErr != nil {
+		return nil, statusErr
+	}
+
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return nil, nil
+	}
+	var result interface{}
+	decoder := json.NewDecoder(bytes.NewReader(respBody))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, permanentError(fmt.Errorf("failed to parse response: %v", err))
+	}
+	return result, nil
+}
+
+// extract renders a JSONPath template and returns every scalar it selects
+// from resp
+func (w *WebhookProvider) extract(path *template.Template, resp interface{}, data webhookData) ([]string, error) {
+	rendered, err := renderWebhookTemplate(path, data)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := parseJSONPath(rendered)
+	if err != nil {
+		return nil, permanentError(err)
+	}
+
+	var values []string
+	for _, node := range evalJSONPath(resp, steps) {
+		value, err := jsonPathString(node)
+		if err != nil {
+			return nil, permanentError(fmt.Errorf("%s in response: %v", rendered, err))
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// webhookFuncs are the functions available in webhook templates
+var webhookFuncs = template.FuncMap{
+	// json quotes a value for use in a JSON body
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// parseWebhookEndpoint parses the templates of an endpoint
+func parseWebhookEndpoint(name string, endpoint webhookEndpoint) (*webhookTemplate, error) {
+	tmpl := &webhookTemplate{method: strings.ToUpper(endpoint.method)}
+
+	var err error
+	if tmpl.url, err = parseWebhookTemplate(name+"_URL", endpoint.url); err != nil {
+		return nil, err
+	}
+	if tmpl.body, err = parseWebhookTemplate(name+"_BODY", endpoint.body); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// parseWebhookTemplate parses the template text of a setting. Empty text
+// returns nil.
+func parseWebhookTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Funcs(webhookFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderWebhookTemplate executes a template with data
+func renderWebhookTemplate(tmpl *template.Template, data webhookData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", permanentError(fmt.Errorf("failed to render %s: %v", tmpl.Name(), err))
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"ddnsd/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// webhookRecord is a record of the API served by webhookStandIn
+type webhookRecord struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+// webhookStandIn serves the example API of the README's webhook section,
+// accepting the bearer token "token"
+type webhookStandIn struct {
+	mu      sync.Mutex
+	records []webhookRecord
+	nextID  int
+}
+
+func (s *webhookStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/api/records")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	id, _ := strconv.Atoi(strings.TrimPrefix(path, "/"))
+
+	switch {
+	case path == "" && r.Method == "GET":
+		records := []webhookRecord{}
+		for _, record := range s.records {
+			if record.Name == r.URL.Query().Get("name") && record.Type == r.URL.Query().Get("type") {
+				records = append(records, record)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"records": records})
+
+	case path == "" && r.Method == "POST":
+		var record webhookRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			http.Error(w, `{"error":"invalid body"}`, http.StatusBadRequest)
+			return
+		}
+		s.nextID++
+		record.ID = s.nextID
+		s.records = append(s.records, record)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(record)
+
+	case id != 0 && (r.Method == "PATCH" || r.Method == "DELETE"):
+		for i := range s.records {
+			if s.records[i].ID != id {
+				continue
+			}
+			if r.Method == "DELETE" {
+				s.records = append(s.records[:i], s.records[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&s.records[i])
+			json.NewEncoder(w).Encode(s.records[i])
+			return
+		}
+		http.NotFound(w, r)
+
+	default:
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// webhookTestConfig returns the README's example webhook configuration with
+// url in place of the example server
+func webhookTestConfig(url string) config.Config {
+	return config.Config{
+		Provider:            "webhook",
+		SecretKey:           "token",
+		WebhookHeaders:      "Authorization: Bearer {{.SecretKey}}",
+		WebhookGetURL:       url + "/api/records?name={{urlquery .Name}}&type={{.Type}}",
+		WebhookGetMethod:    "GET",
+		WebhookIDPath:       "$.records[*].id",
+		WebhookValuePath:    "$.records[*].content",
+		WebhookCreateURL:    url + "/api/records",
+		WebhookCreateMethod: "POST",
+		WebhookCreateBody:   `{"name":{{json .Name}},"type":"{{.Type}}","content":{{json .Value}},"ttl":{{.TTL}}}`,
+		WebhookCreateIDPath: "$.id",
+		WebhookUpdateURL:    url + "/api/records/{{.RecordID}}",
+		WebhookUpdateMethod: "PATCH",
+		WebhookUpdateBody:   `{"content":{{json .Value}}}`,
+		WebhookDeleteURL:    url + "/api/records/{{.RecordID}}",
+		WebhookDeleteMethod: "DELETE",
+	}
+}
+
+// TestWebhookExample checks the README's example configuration against the
+// API it describes
+func TestWebhookExample(t *testing.T) {
+	standIn := &webhookStandIn{}
+	server := httptest.NewServer(http.HandlerFunc(standIn.serve))
+	t.Cleanup(server.Close)
+	cfg := webhookTestConfig(server.URL)
+	cfg.RecordTTL = 300
+	p, err := NewDNSProvider(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := p.CreateRecord("example.com", "home", "A", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := p.UpdateRecord(id, "example.com", "home", "A", "192.0.2.2"); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+
+	standIn.mu.Lock()
+	defer standIn.mu.Unlock()
+	want := []webhookRecord{{ID: 1, Name: "home.example.com", Type: "A", Content: "192.0.2.2", TTL: 300}}
+	if id != "1" || fmt.Sprint(standIn.records) != fmt.Sprint(want) {
+		t.Errorf("CreateRecord returned ID %q and the API holds %v, want ID 1 and %v", id, standIn.records, want)
+	}
+}
@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ZoneFileProvider implements DNSProvider for a BIND-format zone file. Only
+// the rdata of managed records and the SOA serial are changed in place, so
+// the layout, comments and all other records are kept. New records are
+// appended with absolute owner names.
+type ZoneFileProvider struct {
+	file *localFile
+	ttl  int
+
+	// mu serialises read-modify-write cycles of the file
+	mu sync.Mutex
+}
+
+// zoneToken is a token of a zone file with its position
+type zoneToken struct {
+	text  string
+	line  int
+	start int
+	end   int
+}
+
+// zoneRecord is a resource record parsed from a zone file
+type zoneRecord struct {
+	owner     string
+	rrType    string
+	rdata     []zoneToken
+	multiLine bool
+}
+
+// newZoneFileProvider creates a new zone file provider instance. ttl is
+// used for appended records.
+func newZoneFileProvider(file *localFile, ttl int) (*ZoneFileProvider, error) {
+	return &ZoneFileProvider{file: file, ttl: ttl}, nil
+}
+
+// GetRecords returns the records for the name and type. The value doubles
+// as the record ID.
+func (z *ZoneFileProvider) GetRecords(domain, subdomain, recordType string) ([]DNSRecord, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	data, err := z.file.read()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(recordFQDN(domain, subdomain))
+	var records []DNSRecord
+	for _, record := range parseZoneFile(strings.Split(string(data), "\n"), strings.ToLower(recordFQDN(domain, "@"))) {
+		if record.owner == name && record.rrType == recordType && len(record.rdata) > 0 {
+			value := record.rdata[0].text
+			records = append(records, DNSRecord{RecordID: value, Value: value})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord appends a record for the name
+func (z *ZoneFileProvider) CreateRecord(domain, subdomain, recordType, value string) (string, error) {
+	if err := z.save(domain, subdomain, recordType, "", value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// UpdateRecord changes the record with value recordID
+func (z *ZoneFileProvider) UpdateRecord(recordID, domain, subdomain, recordType, value string) error {
+	return z.save(domain, subdomain, recordType, recordID, value)
+}
+
+// DeleteRecord removes the record with value recordID
+func (z *ZoneFileProvider) DeleteRecord(recordID, domain, subdomain, recordType string) error {
+	return z.save(domain, subdomain, recordType, recordID, "")
+}
+
+// save replaces the value oldValue of the name's record with newValue, or
+// appends a record when there is none, and bumps the SOA serial. An empty
+// newValue, or one another record for the name already has, removes the
+// oldValue record instead.
+func (z *ZoneFileProvider) save(domain, subdomain, recordType, oldValue, newValue string) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	data, err := z.file.read()
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	records := parseZoneFile(lines, strings.ToLower(recordFQDN(domain, "@")))
+	name := strings.ToLower(recordFQDN(domain, subdomain))
+
+	var soa, targetRecord *zoneRecord
+	var target *zoneToken
+	for i := range records {
+		record := &records[i]
+		if record.rrType == "SOA" && soa == nil {
+			soa = record
+		}
+		if record.owner != name || record.rrType != recordType || len(record.rdata) == 0 {
+			continue
+		}
+		if record.rdata[0].text == newValue {
+			newValue = ""
+		}
+		if oldValue != "" && target == nil && record.rdata[0].text == oldValue {
+			target, targetRecord = &record.rdata[0], record
+		}
+	}
+	if newValue == "" && target == nil {
+		return nil
+	}
+
+	if soa == nil || len(soa.rdata) < 3 {
+		return permanentError(fmt.Errorf("no SOA record found in %s", z.file.path))
+	}
+	serialToken := soa.rdata[2]
+	serial, err := strconv.ParseUint(serialToken.text, 10, 32)
+	if err != nil {
+		return permanentError(fmt.Errorf("invalid SOA serial %q in %s", serialToken.text, z.file.path))
+	}
+
+	if newValue == "" && targetRecord.multiLine {
+		return permanentError(fmt.Errorf("cannot remove the multi-line record %s %s %s in %s", name, recordType, target.text, z.file.path))
+	}
+
+	replaceZoneToken(lines, serialToken, strconv.FormatUint(uint64(nextSOASerial(uint32(serial), time.Now())), 10))
+	switch {
+	case newValue == "":
+		lines = removeZoneLine(lines, target.line)
+	case target != nil:
+		replaceZoneToken(lines, *target, newValue)
+	default:
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%d\tIN\t%s\t%s", name, z.ttl, recordType, newValue), "")
+	}
+
+	return z.file.write([]byte(strings.Join(lines, "\n")))
+}
+
+// replaceZoneToken replaces the text of token in lines
+func replaceZoneToken(lines []string, token zoneToken, text string) {
+	line := lines[token.line]
+	lines[token.line] = line[:token.start] + text + line[token.end:]
+}
+
+// removeZoneLine removes a single-line record from lines. When the next
+// line leaves its owner blank, the owner is carried over to it.
+func removeZoneLine(lines []string, i int) []string {
+	line := lines[i]
+	if line != "" && line[0] != ' ' && line[0] != '\t' && i+1 < len(lines) {
+		next, trimmed := lines[i+1], strings.TrimSpace(lines[i+1])
+		if trimmed != "" && trimmed[0] != ';' && (next[0] == ' ' || next[0] == '\t') {
+			lines[i+1] = strings.Fields(line)[0] + next
+		}
+	}
+	return append(lines[:i], lines[i+1:]...)
+}
+
+// nextSOASerial returns the serial following serial. Date-based serials
+// (YYYYMMDDnn) jump to today's first serial when they are behind.
+func nextSOASerial(serial uint32, now time.Time) uint32 {
+	today := uint32(now.Year()*1000000 + int(now.Month())*10000 + now.Day()*100)
+	if serial >= 1990000000 && serial < today {
+		return today
+	}
+	return serial + 1
+}
+
+// parseZoneFile parses the resource records of a zone file with the given
+// initial origin. It understands $ORIGIN, blank owners, parentheses and
+// comments; $INCLUDE and $GENERATE are ignored.
+func parseZoneFile(lines []string, origin string) []zoneRecord {
+	var records []zoneRecord
+	var tokens []zoneToken
+	owner := origin
+	depth := 0
+	multiLine := false
+
+	flush := func() {
+		if len(tokens) > 0 {
+			if record, ok := parseZoneRecord(tokens, &owner, &origin); ok {
+				record.multiLine = multiLine || tokens[0].line != tokens[len(tokens)-1].line
+				records = append(records, record)
+			}
+		}
+		tokens, multiLine = nil, false
+	}
+
+	for i, line := range lines {
+		if depth == 0 {
+			flush()
+			// A record starting with whitespace inherits the previous owner
+			if line != "" && (line[0] == ' ' || line[0] == '\t') {
+				tokens = append(tokens, zoneToken{text: "", line: i})
+			}
+		}
+		lineTokens, d := tokenizeZoneLine(line, i)
+		tokens = append(tokens, lineTokens...)
+		if d != 0 {
+			multiLine = true
+		}
+		depth += d
+		if depth < 0 {
+			depth = 0
+		}
+	}
+	flush()
+	return records
+}
+
+// parseZoneRecord parses the tokens of one entry. An empty first token
+// stands for the previous owner. Directives update origin.
+func parseZoneRecord(tokens []zoneToken, owner, origin *string) (zoneRecord, bool) {
+	first := tokens[0].text
+	if strings.HasPrefix(first, "$") {
+		if strings.EqualFold(first, "$ORIGIN") && len(tokens) > 1 {
+			*origin = zoneAbsoluteName(tokens[1].text, *origin)
+		}
+		return zoneRecord{}, false
+	}
+	if first != "" {
+		*owner = zoneAbsoluteName(first, *origin)
+	}
+
+	// TTL and class may appear in either order before the type
+	rest := tokens[1:]
+	for len(rest) > 0 {
+		text := strings.ToUpper(rest[0].text)
+		if text == "IN" || text == "CH" || text == "HS" || text == "CS" || (text != "" && text[0] >= '0' && text[0] <= '9') {
+			rest = rest[1:]
+			continue
+		}
+		break
+	}
+	if len(rest) == 0 {
+		return zoneRecord{}, false
+	}
+
+	return zoneRecord{
+		owner:  *owner,
+		rrType: strings.ToUpper(rest[0].text),
+		rdata:  rest[1:],
+	}, true
+}
+
+// tokenizeZoneLine splits a line into tokens, dropping comments and
+// parentheses. It returns the change in parenthesis depth.
+func tokenizeZoneLine(line string, lineNum int) ([]zoneToken, int) {
+	var tokens []zoneToken
+	depth := 0
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ';':
+			return tokens, depth
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == '"':
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				end = len(line) - i - 2
+			}
+			tokens = append(tokens, zoneToken{text: line[i : i+end+2], line: lineNum, start: i, end: i + end + 2})
+			i += end + 2
+		default:
+			start := i
+			for i < len(line) && !strings.ContainsRune(" \t\r;()\"", rune(line[i])) {
+				i++
+			}
+			tokens = append(tokens, zoneToken{text: line[start:i], line: lineNum, start: start, end: i})
+		}
+	}
+	return tokens, depth
+}
+
+// zoneAbsoluteName resolves a possibly relative owner name against origin
+func zoneAbsoluteName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(name)
+	default:
+		return strings.ToLower(name) + "." + origin
+	}
+}
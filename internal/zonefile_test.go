@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestZoneFileGolden applies a series of changes to a zone file and
+// compares the result with a golden file
+func TestZoneFileGolden(t *testing.T) {
+	file := copyTestdata(t, "zone.in")
+	z, err := newZoneFileProvider(file, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"delete a record with a parenthesis in its comment", func() error {
+			return z.DeleteRecord("192.0.2.20", "example.com", "www", "A")
+		}},
+		{"update a record with an inherited owner", func() error {
+			return z.UpdateRecord("192.0.2.21", "example.com", "www", "A", "198.51.100.21")
+		}},
+		{"update an IPv6 record", func() error {
+			return z.UpdateRecord("2001:db8::20", "example.com", "www", "AAAA", "2001:db8::21")
+		}},
+		{"delete a record with balanced parentheses", func() error {
+			return z.DeleteRecord("192.0.2.70", "example.com", "single", "A")
+		}},
+		{"update the apex", func() error {
+			return z.UpdateRecord("192.0.2.1", "example.com", "@", "A", "198.51.100.1")
+		}},
+		{"create a record", func() error {
+			_, err := z.CreateRecord("example.com", "new", "A", "198.51.100.40")
+			return err
+		}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+	checkGolden(t, file, "zone.golden")
+
+	records, err := z.GetRecords("example.com", "www", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Value != "198.51.100.21" {
+		t.Errorf("www.example.com A has %+v, want 198.51.100.21", records)
+	}
+}
+
+// TestZoneFileRefusesMultiLineDelete checks that a record spanning lines
+// is left alone rather than half removed
+func TestZoneFileRefusesMultiLineDelete(t *testing.T) {
+	file := copyTestdata(t, "zone.in")
+	z, err := newZoneFileProvider(file, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(file.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = z.DeleteRecord("192.0.2.60", "example.com", "multi", "A")
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("DeleteRecord of a multi-line record: got error %v, want a permanent error", err)
+	}
+	after, err := os.ReadFile(file.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("zone file changed by a refused delete")
+	}
+}
+
+// TestNextSOASerial checks serial increments, including date-based ones
+func TestNextSOASerial(t *testing.T) {
+	now, _ := time.Parse("2006-01-02", "2024-05-17")
+	tests := []struct {
+		serial, want uint32
+	}{
+		{42, 43},
+		{2024051703, 2024051704},
+		{2023120101, 2024051700},
+		{2024060100, 2024060101},
+	}
+	for _, tt := range tests {
+		if got := nextSOASerial(tt.serial, now); got != tt.want {
+			t.Errorf("nextSOASerial(%d) = %d, want %d", tt.serial, got, tt.want)
+		}
+	}
+}
@@ -14,6 +14,11 @@ import (
 )
 
 func main() {
+	// Query the history file instead of running the service
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistory(os.Args[2:]))
+	}
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		utils.LogWarning("Failed to load .env file: %v", err)
@@ -37,15 +42,16 @@ func main() {
 	config.PrintConfigSummary(cfg)
 
 	// Run initial update
+	updater := internal.NewUpdater(provider, cfg)
 	utils.LogInfo("Starting initial update...")
-	internal.RunSequentialUpdates(provider, cfg)
+	updater.Run()
 
 	// Set up scheduled updates
 	scheduler := cron.New(cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
 	spec := fmt.Sprintf("@every %ds", cfg.Interval)
 
 	_, err = scheduler.AddFunc(spec, func() {
-		internal.RunSequentialUpdates(provider, cfg)
+		updater.Run()
 	})
 	if err != nil {
 		utils.LogError("Failed to set up scheduler: %v", err)
@@ -6,53 +6,65 @@ import (
 	"time"
 )
 
-var (
-	logMutex  sync.Mutex
-	logPrefix string
-)
+// outputMutex serializes writes so concurrent log lines never interleave
+var outputMutex sync.Mutex
 
-// LogInfo prints an info level message
-func LogInfo(format string, v ...interface{}) {
-	logWithLevel("INFO", format, v...)
+// defaultLogger is used by the package-level logging functions
+var defaultLogger = NewLogger("")
+
+// Logger prints levelled log messages with a fixed prefix. A Logger is
+// immutable and safe for concurrent use.
+type Logger struct {
+	prefix string
 }
 
-// LogWarning prints a warning level message
-func LogWarning(format string, v ...interface{}) {
-	logWithLevel("WARN", format, v...)
+// NewLogger creates a logger that prepends prefix to every message
+func NewLogger(prefix string) *Logger {
+	return &Logger{prefix: prefix}
 }
 
-// LogError prints an error level message
-func LogError(format string, v ...interface{}) {
-	logWithLevel("ERROR", format, v...)
+// WithPrefix returns a new logger with prefix appended to the current one
+func (l *Logger) WithPrefix(prefix string) *Logger {
+	return &Logger{prefix: l.prefix + prefix}
+}
+
+// Info prints an info level message
+func (l *Logger) Info(format string, v ...interface{}) {
+	l.log("INFO", format, v...)
 }
 
-// logWithLevel prints a message with specified log level
-func logWithLevel(level, format string, v ...interface{}) {
-	logMutex.Lock()
-	defer logMutex.Unlock()
+// Warning prints a warning level message
+func (l *Logger) Warning(format string, v ...interface{}) {
+	l.log("WARN", format, v...)
+}
 
+// Error prints an error level message
+func (l *Logger) Error(format string, v ...interface{}) {
+	l.log("ERROR", format, v...)
+}
+
+// log prints a message with specified log level
+func (l *Logger) log(level, format string, v ...interface{}) {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	message := fmt.Sprintf(format, v...)
 
-	if logPrefix != "" {
-		fmt.Printf("[%s] %-5s %s%s\n", timestamp, level, logPrefix, message)
-	} else {
-		fmt.Printf("[%s] %-5s %s\n", timestamp, level, message)
-	}
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	fmt.Printf("[%s] %-5s %s%s\n", timestamp, level, l.prefix, message)
 }
 
-// WithLogPrefix sets a temporary prefix for log messages
-func WithLogPrefix(prefix string, fn func()) {
-	logMutex.Lock()
-	originalPrefix := logPrefix
-	logPrefix = prefix
-	logMutex.Unlock()
+// LogInfo prints an info level message
+func LogInfo(format string, v ...interface{}) {
+	defaultLogger.Info(format, v...)
+}
 
-	defer func() {
-		logMutex.Lock()
-		logPrefix = originalPrefix
-		logMutex.Unlock()
-	}()
+// LogWarning prints a warning level message
+func LogWarning(format string, v ...interface{}) {
+	defaultLogger.Warning(format, v...)
+}
 
-	fn()
-}
\ No newline at end of file
+// LogError prints an error level message
+func LogError(format string, v ...interface{}) {
+	defaultLogger.Error(format, v...)
+}